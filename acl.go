@@ -0,0 +1,216 @@
+package qingstor
+
+import (
+	"context"
+
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
+	typ "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// All available canned ACLs are listed here.
+const (
+	// CannedACLPrivate means only the owner has access to the bucket, it's the default ACL for new bucket.
+	CannedACLPrivate = "private"
+	// CannedACLPublicRead means all users could read the bucket.
+	CannedACLPublicRead = "public-read"
+	// CannedACLPublicReadWrite means all users could read and write the bucket.
+	CannedACLPublicReadWrite = "public-read-write"
+)
+
+// All available ACL grantee types are listed here.
+const (
+	ACLGranteeTypeUser  = "user"
+	ACLGranteeTypeGroup = "group"
+)
+
+// All available ACL group grantee names are listed here.
+const (
+	// ACLGroupAllUsers represents all users, including anonymous users.
+	ACLGroupAllUsers = "QS_ALL_USERS"
+)
+
+// All available ACL permissions are listed here.
+const (
+	ACLPermissionRead        = "READ"
+	ACLPermissionWrite       = "WRITE"
+	ACLPermissionFullControl = "FULL_CONTROL"
+)
+
+// ACLGrantee is the grantee of an ACL rule.
+type ACLGrantee struct {
+	// Type is the grantee type, could be ACLGranteeTypeUser or ACLGranteeTypeGroup.
+	Type string
+	// ID is the user id, only works for user grantee.
+	ID string
+	// Name is the user name for user grantee, or the group name for group grantee.
+	Name string
+}
+
+// ACLRule is an access control rule for a bucket.
+//
+// ref: https://docs.qingcloud.com/qingstor/api/bucket/acl/put_acl
+type ACLRule struct {
+	Grantee    ACLGrantee
+	Permission string
+}
+
+// GetACL will get the access control list of the bucket.
+func (s *Service) GetACL(name string, pairs ...typ.Pair) (rules []ACLRule, err error) {
+	ctx := context.Background()
+	return s.GetACLWithContext(ctx, name, pairs...)
+}
+
+// GetACLWithContext will get the access control list of the bucket.
+func (s *Service) GetACLWithContext(ctx context.Context, name string, pairs ...typ.Pair) (rules []ACLRule, err error) {
+	defer func() {
+		err = s.formatError("get_acl", err, name)
+	}()
+
+	store, err := s.newStorageWithPairs(name, pairs)
+	if err != nil {
+		return
+	}
+
+	output, err := store.bucket.GetACLWithContext(ctx)
+	if err != nil {
+		return
+	}
+
+	rules = make([]ACLRule, 0, len(output.ACL))
+	for _, v := range output.ACL {
+		rule := ACLRule{
+			Permission: service.StringValue(v.Permission),
+		}
+		if v.Grantee != nil {
+			rule.Grantee = ACLGrantee{
+				Type: service.StringValue(v.Grantee.Type),
+				ID:   service.StringValue(v.Grantee.ID),
+				Name: service.StringValue(v.Grantee.Name),
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// SetACL will replace the access control list of the bucket with given rules.
+func (s *Service) SetACL(name string, rules []ACLRule, pairs ...typ.Pair) (err error) {
+	ctx := context.Background()
+	return s.SetACLWithContext(ctx, name, rules, pairs...)
+}
+
+// SetACLWithContext will replace the access control list of the bucket with given rules.
+func (s *Service) SetACLWithContext(ctx context.Context, name string, rules []ACLRule, pairs ...typ.Pair) (err error) {
+	defer func() {
+		err = s.formatError("set_acl", err, name)
+	}()
+
+	store, err := s.newStorageWithPairs(name, pairs)
+	if err != nil {
+		return
+	}
+
+	_, err = store.bucket.PutACLWithContext(ctx, &service.PutBucketACLInput{
+		ACL: formatACLTypes(rules),
+	})
+	if err != nil {
+		return
+	}
+	return nil
+}
+
+// newStorageWithPairs will create a storage for bucket-level operations which are
+// not defined in go-storage, only location is supported in pairs.
+func (s *Service) newStorageWithPairs(name string, pairs []typ.Pair) (store *Storage, err error) {
+	pairs = append(pairs, s.defaultLocationPairs()...)
+
+	opt, err := parsePairServiceBucket(pairs)
+	if err != nil {
+		return
+	}
+	return s.newStorage(append(opt.pairs, ps.WithName(name))...)
+}
+
+// defaultLocationPairs will return location in default pairs of Get, which is the only default
+// pair applied to bucket-level operations not defined in go-storage.
+func (s *Service) defaultLocationPairs() []typ.Pair {
+	for _, v := range s.defaultPairs.Get {
+		if v.Key == "location" {
+			return []typ.Pair{v}
+		}
+	}
+	return nil
+}
+
+// pairServiceBucket is the parsed struct of pairs for bucket-level operations not defined in
+// go-storage.
+type pairServiceBucket struct {
+	pairs []typ.Pair
+
+	HasLocation bool
+	Location    string
+}
+
+// parsePairServiceBucket will parse Pair slice into pairServiceBucket.
+func parsePairServiceBucket(opts []typ.Pair) (pairServiceBucket, error) {
+	result := pairServiceBucket{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		case "location":
+			if result.HasLocation {
+				continue
+			}
+			result.HasLocation = true
+			result.Location = v.Value.(string)
+		default:
+			return pairServiceBucket{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+	return result, nil
+}
+
+// parseCannedACL will convert canned ACL into ACL rules.
+func parseCannedACL(acl string) (rules []ACLRule, err error) {
+	allUsers := ACLGrantee{Type: ACLGranteeTypeGroup, Name: ACLGroupAllUsers}
+
+	switch acl {
+	case CannedACLPrivate:
+		// New bucket is private already, no rules need to be added.
+		return nil, nil
+	case CannedACLPublicRead:
+		return []ACLRule{
+			{Grantee: allUsers, Permission: ACLPermissionRead},
+		}, nil
+	case CannedACLPublicReadWrite:
+		return []ACLRule{
+			{Grantee: allUsers, Permission: ACLPermissionRead},
+			{Grantee: allUsers, Permission: ACLPermissionWrite},
+		}, nil
+	default:
+		return nil, services.PairUnsupportedError{Pair: WithCannedACL(acl)}
+	}
+}
+
+func formatACLTypes(rules []ACLRule) []*service.ACLType {
+	acl := make([]*service.ACLType, 0, len(rules))
+	for _, v := range rules {
+		grantee := &service.GranteeType{
+			Type: service.String(v.Grantee.Type),
+		}
+		if v.Grantee.ID != "" {
+			grantee.ID = service.String(v.Grantee.ID)
+		}
+		if v.Grantee.Name != "" {
+			grantee.Name = service.String(v.Grantee.Name)
+		}
+		acl = append(acl, &service.ACLType{
+			Grantee:    grantee,
+			Permission: service.String(v.Permission),
+		})
+	}
+	return acl
+}
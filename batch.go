@@ -0,0 +1,114 @@
+package qingstor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// writeBatchConcurrencyDefault is the default number of objects written at the same time.
+const writeBatchConcurrencyDefault = 8
+
+// BatchItem is an object to be written by WriteBatch.
+type BatchItem struct {
+	Path   string
+	Reader io.Reader
+	Size   int64
+	// Pairs will be used while writing this object, as pairs of Write.
+	Pairs []Pair
+}
+
+// BatchOptions is the options for writing objects in batch.
+type BatchOptions struct {
+	// Concurrency is the number of objects written at the same time, default to 8.
+	Concurrency int
+	// ProgressFunc will be called after an object has been written, err is nil if succeeded.
+	//
+	// ProgressFunc could be called concurrently.
+	ProgressFunc func(path string, err error)
+}
+
+// BatchItemError is the error of an object failed in batch.
+type BatchItemError struct {
+	Path string
+	Err  error
+}
+
+// BatchError will be returned while some objects failed in batch.
+type BatchError struct {
+	// Total is the number of objects in batch.
+	Total int
+	// Failed is the objects failed in batch, in the same order as input.
+	Failed []BatchItemError
+}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, v := range e.Failed {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", v.Path, v.Err))
+	}
+	return fmt.Sprintf("%d of %d objects failed: %s", len(e.Failed), e.Total, strings.Join(msgs, "; "))
+}
+
+// IsInternalError implements InternalError
+func (e *BatchError) IsInternalError() {}
+
+// WriteBatch will write objects concurrently.
+func (s *Storage) WriteBatch(items []BatchItem, opt BatchOptions) (err error) {
+	ctx := context.Background()
+	return s.WriteBatchWithContext(ctx, items, opt)
+}
+
+// WriteBatchWithContext will write objects concurrently.
+//
+// Failure of an object will not stop others, all failed objects will be reported in a *BatchError
+// which could be retrieved via errors.As.
+func (s *Storage) WriteBatchWithContext(ctx context.Context, items []BatchItem, opt BatchOptions) (err error) {
+	defer func() {
+		err = s.formatError("write_batch", err)
+	}()
+
+	concurrency := opt.Concurrency
+	if concurrency <= 0 {
+		concurrency = writeBatchConcurrencyDefault
+	}
+
+	errs := make([]error, len(items))
+
+	var wg sync.WaitGroup
+	ch := make(chan int)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for idx := range ch {
+				item := items[idx]
+				_, errs[idx] = s.WriteWithContext(ctx, item.Path, item.Reader, item.Size, item.Pairs...)
+				if opt.ProgressFunc != nil {
+					opt.ProgressFunc(item.Path, errs[idx])
+				}
+			}
+		}()
+	}
+	for idx := range items {
+		ch <- idx
+	}
+	close(ch)
+	wg.Wait()
+
+	var failed []BatchItemError
+	for idx, e := range errs {
+		if e != nil {
+			failed = append(failed, BatchItemError{Path: items[idx].Path, Err: e})
+		}
+	}
+	if len(failed) > 0 {
+		return &BatchError{Total: len(items), Failed: failed}
+	}
+	return nil
+}
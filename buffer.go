@@ -0,0 +1,107 @@
+package qingstor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+const (
+	// copyBufferSizeDefault is the default size of buffers used while copying content, the same as io.Copy.
+	copyBufferSizeDefault = 32 * 1024
+	// copyBufferSizeMinimum is the size of the smallest pooled buffers.
+	copyBufferSizeMinimum = 4 * 1024
+	// copyBufferSizeMaximum is the size of the largest pooled buffers, larger copy_buffer_size will be capped.
+	copyBufferSizeMaximum = 16 * 1024 * 1024
+)
+
+// copyBufferPools holds pools of buffers whose sizes are powers of two between
+// copyBufferSizeMinimum and copyBufferSizeMaximum. copy_buffer_size set by operations will be
+// rounded up to them, so that pools are bounded and buffers could be reused across calls.
+var copyBufferPools = func() []*sync.Pool {
+	var pools []*sync.Pool
+	for size := copyBufferSizeMinimum; size <= copyBufferSizeMaximum; size *= 2 {
+		pools = append(pools, newCopyBufferPool(size))
+	}
+	return pools
+}()
+
+// defaultCopyBufferPool will be used while copy_buffer_size is not set.
+var defaultCopyBufferPool = getCopyBufferPool(copyBufferSizeDefault)
+
+func newCopyBufferPool(size int) *sync.Pool {
+	return &sync.Pool{
+		New: func() interface{} {
+			b := make([]byte, size)
+			return &b
+		},
+	}
+}
+
+// getCopyBufferPool returns the pool of the smallest buffers not less than size.
+func getCopyBufferPool(size int) *sync.Pool {
+	idx := 0
+	for v := copyBufferSizeMinimum; v < size && idx < len(copyBufferPools)-1; v *= 2 {
+		idx++
+	}
+	return copyBufferPools[idx]
+}
+
+// copyBuffer will copy from r to w with a pooled buffer, size is the copy_buffer_size of
+// operation and 0 means the one of storage.
+//
+// While copy_buffer_size is not set, if w implements io.ReaderFrom like *os.File or net.Conn,
+// the response body will be handed to it directly while content needs no decoding, and if r
+// implements io.WriterTo like *bytes.Reader, it will write into w directly, so that no
+// intermediate buffer is used.
+func (s *Storage) copyBuffer(w io.Writer, r io.Reader, size int) (n int64, err error) {
+	if size <= 0 {
+		size = s.copyBufferSize
+	}
+	if size <= 0 {
+		if rf, ok := w.(io.ReaderFrom); ok {
+			if or, ok := r.(*objectReader); ok && or.direct() {
+				r = or.r
+			}
+			return rf.ReadFrom(r)
+		}
+		if wt, ok := r.(io.WriterTo); ok {
+			return wt.WriteTo(w)
+		}
+	}
+
+	pool := defaultCopyBufferPool
+	if size > 0 {
+		pool = getCopyBufferPool(size)
+	}
+
+	bp := pool.Get().(*[]byte)
+	defer pool.Put(bp)
+	buf := *bp
+	// Buffers of the pool could be larger than size.
+	if size > 0 && size < len(buf) {
+		buf = buf[:size]
+	}
+	// io.CopyBuffer prefers io.WriterTo and io.ReaderFrom to the buffer, hide them so that
+	// copy_buffer_size is honored.
+	return io.CopyBuffer(struct{ io.Writer }{w}, struct{ io.Reader }{r}, buf)
+}
+
+// partBufferPool is the pool of buffers used to hold parts while uploading multipart.
+//
+// Buffers will keep their capacity after reset, so parts of the same size could be
+// buffered without allocating.
+var partBufferPool = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
+
+func getPartBuffer() *bytes.Buffer {
+	return partBufferPool.Get().(*bytes.Buffer)
+}
+
+func putPartBuffer(buf *bytes.Buffer) {
+	buf.Reset()
+	partBufferPool.Put(buf)
+}
@@ -0,0 +1,105 @@
+package qingstor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// MultipartCheckpoint is the state of an in-progress multipart upload, which could be saved as
+// JSON and resumed by ResumeMultipart after the process restarted.
+type MultipartCheckpoint struct {
+	// Key is the absolute path of the object.
+	Key         string `json:"key"`
+	MultipartID string `json:"multipart_id"`
+	// ClientEncryptionKey is the wrapped data key of client-side encryption, parts must be
+	// encrypted by the same key.
+	ClientEncryptionKey string `json:"client_encryption_key,omitempty"`
+	// Parts are the uploaded parts sorted by index.
+	Parts []CheckpointPart `json:"parts"`
+}
+
+// CheckpointPart is an uploaded part in MultipartCheckpoint.
+type CheckpointPart struct {
+	Index int    `json:"index"`
+	Size  int64  `json:"size"`
+	ETag  string `json:"etag"`
+}
+
+// NewMultipartCheckpoint will create the checkpoint of multipart object o, which is returned by
+// CreateMultipart, with parts that have been uploaded.
+func (s *Storage) NewMultipartCheckpoint(o *Object, parts []*Part) (c *MultipartCheckpoint, err error) {
+	defer func() {
+		err = s.formatError("new_multipart_checkpoint", err, o.Path)
+	}()
+
+	// Check mode first, getters of objects not done will send stat.
+	if !o.Mode.IsPart() {
+		return nil, services.ObjectModeInvalidError{Expected: ModePart, Actual: o.Mode}
+	}
+	id, ok := o.GetMultipartID()
+	if !ok {
+		return nil, services.ObjectModeInvalidError{Expected: ModePart, Actual: o.Mode}
+	}
+
+	c = &MultipartCheckpoint{
+		Key:                 o.ID,
+		MultipartID:         id,
+		ClientEncryptionKey: GetObjectSystemMetadata(o).ClientEncryptionKey,
+	}
+	for _, p := range parts {
+		c.AddPart(p)
+	}
+	return c, nil
+}
+
+// AddPart will add an uploaded part into checkpoint, the part with the same index will be
+// replaced.
+func (c *MultipartCheckpoint) AddPart(p *Part) {
+	cp := CheckpointPart{Index: p.Index, Size: p.Size, ETag: p.ETag}
+
+	idx := sort.Search(len(c.Parts), func(i int) bool {
+		return c.Parts[i].Index >= p.Index
+	})
+	if idx < len(c.Parts) && c.Parts[idx].Index == p.Index {
+		c.Parts[idx] = cp
+		return
+	}
+	c.Parts = append(c.Parts, CheckpointPart{})
+	copy(c.Parts[idx+1:], c.Parts[idx:])
+	c.Parts[idx] = cp
+}
+
+// ResumeMultipart will re-hydrate the multipart object and uploaded parts from checkpoint c.
+//
+// The object could be used in WriteMultipart and CompleteMultipart like the one returned by
+// CreateMultipart, parts are sorted by index. The upload is not checked by ResumeMultipart,
+// please Stat with multipart_id to make sure it is not completed or aborted.
+func (s *Storage) ResumeMultipart(c *MultipartCheckpoint) (o *Object, parts []*Part, err error) {
+	defer func() {
+		err = s.formatError("resume_multipart", err, c.Key)
+	}()
+
+	if c.Key == "" || c.MultipartID == "" {
+		return nil, nil, fmt.Errorf("checkpoint without key or multipart id: %w", services.ErrRestrictionDissatisfied)
+	}
+
+	o = s.newObject(true)
+	o.ID = c.Key
+	o.Path = s.getRelPath(c.Key)
+	o.Mode |= ModePart
+	o.SetMultipartID(c.MultipartID)
+	if c.ClientEncryptionKey != "" {
+		var sm ObjectSystemMetadata
+		sm.ClientEncryptionKey = c.ClientEncryptionKey
+		o.SetSystemMetadata(sm)
+	}
+
+	parts = make([]*Part, 0, len(c.Parts))
+	for _, p := range c.Parts {
+		parts = append(parts, &Part{Index: p.Index, Size: p.Size, ETag: p.ETag})
+	}
+	return o, parts, nil
+}
@@ -0,0 +1,340 @@
+package qingstor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+const (
+	// downloadPartSizeDefault is the default range size fetched by every request while downloading.
+	downloadPartSizeDefault = 8 * 1024 * 1024
+	// downloadConcurrencyDefault is the default number of ranges fetched at the same time.
+	downloadConcurrencyDefault = 4
+
+	// downloadCheckpointSuffix is the suffix of the checkpoint file used by DownloadFile to resume.
+	downloadCheckpointSuffix = ".bsdownload"
+)
+
+// Download will fetch the content of path by ranges concurrently, and write them into w.
+func (s *Storage) Download(path string, w io.WriterAt, pairs ...Pair) (n int64, err error) {
+	ctx := context.Background()
+	return s.DownloadWithContext(ctx, path, w, pairs...)
+}
+
+// DownloadWithContext will fetch the content of path by ranges concurrently, and write them into w.
+//
+// Pairs for Read are supported except offset, size and suffix_size, io_callback could be called
+// concurrently. The etag of object will be checked by every range request, so download fails with
+// ErrPreconditionFailed if the object is changed during downloading.
+//
+// Content encrypted by key provider, compressed or verified by sha256 or etag could not be fetched
+// by ranges, they will be read via a single request instead.
+func (s *Storage) DownloadWithContext(ctx context.Context, path string, w io.WriterAt, pairs ...Pair) (n int64, err error) {
+	defer func() {
+		err = s.formatError("download", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Read...)
+	opt, err := s.parsePairStorageRead(pairs)
+	if err != nil {
+		return
+	}
+
+	path = strings.ReplaceAll(path, "\\", "/")
+	o, err := s.downloadStat(ctx, path, opt)
+	if err != nil {
+		return
+	}
+	return s.download(ctx, path, w, o, opt, nil, nil)
+}
+
+// DownloadFile will fetch the content of path by ranges concurrently, and write them into local file.
+func (s *Storage) DownloadFile(path, localPath string, pairs ...Pair) (n int64, err error) {
+	ctx := context.Background()
+	return s.DownloadFileWithContext(ctx, path, localPath, pairs...)
+}
+
+// DownloadFileWithContext will fetch the content of path by ranges concurrently, and write them
+// into local file.
+//
+// Pairs for Download are supported. Finished ranges will be recorded in a checkpoint file next to
+// the local file, and a failed download could be resumed by calling DownloadFile again with the
+// same pairs, as long as the object is unchanged. The checkpoint file will be removed after
+// succeeded. n is the size of content fetched in this call.
+func (s *Storage) DownloadFileWithContext(ctx context.Context, path, localPath string, pairs ...Pair) (n int64, err error) {
+	defer func() {
+		err = s.formatError("download_file", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Read...)
+	opt, err := s.parsePairStorageRead(pairs)
+	if err != nil {
+		return
+	}
+
+	path = strings.ReplaceAll(path, "\\", "/")
+	o, err := s.downloadStat(ctx, path, opt)
+	if err != nil {
+		return
+	}
+
+	etag, _ := o.GetEtag()
+	cp := &downloadCheckpoint{
+		Etag:     etag,
+		Size:     o.MustGetContentLength(),
+		PartSize: downloadPartSize(opt),
+	}
+	cpPath := localPath + downloadCheckpointSuffix
+
+	flag := os.O_CREATE | os.O_WRONLY
+	if prev, ok := loadDownloadCheckpoint(cpPath); ok && prev.match(cp) {
+		cp = prev
+	} else {
+		// Content written by previous download is not reusable.
+		flag |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(localPath, flag, 0644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	n, err = s.download(ctx, path, f, o, opt, cp.completed(), func(index int) {
+		cp.add(index)
+		_ = cp.save(cpPath)
+	})
+	if err != nil {
+		return
+	}
+	if err = f.Truncate(o.MustGetContentLength()); err != nil {
+		return
+	}
+	if err = f.Sync(); err != nil {
+		return
+	}
+	if err = os.Remove(cpPath); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	return n, nil
+}
+
+// downloadStat will check pairs for download and stat the object to be downloaded.
+func (s *Storage) downloadStat(ctx context.Context, path string, opt pairStorageRead) (o *Object, err error) {
+	if opt.HasOffset {
+		return nil, services.PairUnsupportedError{Pair: ps.WithOffset(opt.Offset)}
+	}
+	if opt.HasSize {
+		return nil, services.PairUnsupportedError{Pair: ps.WithSize(opt.Size)}
+	}
+	if opt.HasSuffixSize {
+		return nil, services.PairUnsupportedError{Pair: WithSuffixSize(opt.SuffixSize)}
+	}
+	if opt.HasReadRateLimit && opt.ReadRateLimit <= 0 {
+		return nil, services.PairUnsupportedError{Pair: WithReadRateLimit(opt.ReadRateLimit)}
+	}
+	if downloadPartSize(opt) <= 0 {
+		return nil, services.PairUnsupportedError{Pair: WithDownloadPartSize(opt.DownloadPartSize)}
+	}
+
+	return s.stat(ctx, path, pairStorageStat{
+		HasEncryptionCustomerAlgorithm: opt.HasEncryptionCustomerAlgorithm,
+		EncryptionCustomerAlgorithm:    opt.EncryptionCustomerAlgorithm,
+		HasEncryptionCustomerKey:       opt.HasEncryptionCustomerKey,
+		EncryptionCustomerKey:          opt.EncryptionCustomerKey,
+	})
+}
+
+// download will fetch ranges of object concurrently, ranges in completed will be skipped and
+// onPart will be called after a range has been written.
+func (s *Storage) download(ctx context.Context, path string, w io.WriterAt, o *Object, opt pairStorageRead, completed map[int]bool, onPart func(index int)) (n int64, err error) {
+	// Encrypted chunks and compressed content could not be read by ranges, and checksum
+	// could only be verified with the whole content.
+	if s.keyProvider != nil || opt.HasCompression || (opt.HasVerifySha256 && opt.VerifySha256) || (opt.HasVerifyEtag && opt.VerifyEtag) {
+		return s.read(ctx, path, &offsetWriter{w: w}, opt)
+	}
+
+	size := o.MustGetContentLength()
+	etag, _ := o.GetEtag()
+	partSize := downloadPartSize(opt)
+	concurrency := downloadConcurrencyDefault
+	if opt.HasDownloadConcurrency && opt.DownloadConcurrency > 0 {
+		concurrency = opt.DownloadConcurrency
+	}
+
+	dctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// All ranges share the same limiter, so that the total rate is limited.
+	var limiter *rateLimiter
+	if opt.HasReadRateLimit {
+		limiter = newRateLimiter(opt.ReadRateLimit)
+		opt.HasReadRateLimit = false
+	}
+	// All ranges share the same meter, so that statistics cover the whole content.
+	var meter *transferMeter
+	if opt.HasTransferCallback {
+		meter = newTransferMeter(opt.TransferCallback, size)
+		opt.HasTransferCallback = false
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+	setErr := func(e error) {
+		once.Do(func() {
+			firstErr = e
+			cancel()
+		})
+	}
+
+	for index := 0; int64(index)*partSize < size; index++ {
+		if completed[index] {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-dctx.Done():
+		}
+		// The download context will be canceled before the semaphore released if any range failed.
+		if dctx.Err() != nil {
+			break
+		}
+
+		offset := int64(index) * partSize
+		partLen := partSize
+		if offset+partLen > size {
+			partLen = size - offset
+		}
+
+		wg.Add(1)
+		go func(index int, offset, partLen int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			popt := opt
+			popt.HasOffset, popt.Offset = true, offset
+			popt.HasSize, popt.Size = true, partLen
+			// Make sure all ranges come from the same object.
+			if etag != "" {
+				popt.HasIfMatch, popt.IfMatch = true, etag
+			}
+
+			var pw io.Writer = &offsetWriter{w: w, offset: offset}
+			if limiter != nil {
+				pw = &rateLimitWriter{ctx: dctx, w: pw, l: limiter}
+			}
+			if meter != nil {
+				pw = &transferWriter{w: pw, m: meter}
+			}
+			written, err := s.read(dctx, path, pw, popt)
+			if err == nil && written != partLen {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				setErr(err)
+				return
+			}
+
+			mu.Lock()
+			n += written
+			if onPart != nil {
+				onPart(index)
+			}
+			mu.Unlock()
+		}(index, offset, partLen)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return n, firstErr
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return n, nil
+}
+
+func downloadPartSize(opt pairStorageRead) int64 {
+	if opt.HasDownloadPartSize {
+		return opt.DownloadPartSize
+	}
+	return downloadPartSizeDefault
+}
+
+// offsetWriter will write into w sequentially from offset.
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (o *offsetWriter) Write(p []byte) (n int, err error) {
+	n, err = o.w.WriteAt(p, o.offset)
+	o.offset += int64(n)
+	return
+}
+
+// downloadCheckpoint records finished ranges of DownloadFile.
+type downloadCheckpoint struct {
+	Etag     string `json:"etag"`
+	Size     int64  `json:"size"`
+	PartSize int64  `json:"part_size"`
+	Parts    []int  `json:"parts"`
+}
+
+func loadDownloadCheckpoint(path string) (*downloadCheckpoint, bool) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	cp := &downloadCheckpoint{}
+	if err := json.Unmarshal(content, cp); err != nil {
+		return nil, false
+	}
+	return cp, true
+}
+
+// match will check whether the checkpoint is recorded for the same object and part size.
+func (cp *downloadCheckpoint) match(o *downloadCheckpoint) bool {
+	return cp.Etag == o.Etag && cp.Size == o.Size && cp.PartSize == o.PartSize
+}
+
+func (cp *downloadCheckpoint) completed() map[int]bool {
+	m := make(map[int]bool, len(cp.Parts))
+	for _, v := range cp.Parts {
+		m[v] = true
+	}
+	return m
+}
+
+func (cp *downloadCheckpoint) add(index int) {
+	cp.Parts = append(cp.Parts, index)
+}
+
+func (cp *downloadCheckpoint) save(path string) error {
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
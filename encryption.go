@@ -0,0 +1,384 @@
+package qingstor
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+// KeyProvider is used to wrap and unwrap data keys for client-side encryption.
+//
+// Every object is encrypted by its own data key, and the data key wrapped by KeyProvider
+// will be stored in object metadata. KeyProvider could be backed by a local master key
+// or an external key management service.
+type KeyProvider interface {
+	// WrapKey will encrypt the data key.
+	WrapKey(ctx context.Context, key []byte) (wrapped []byte, err error)
+	// UnwrapKey will decrypt the data key wrapped by WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte) (key []byte, err error)
+}
+
+// NewAESKeyProvider will create a KeyProvider which wraps data keys via AES-GCM with masterKey.
+//
+// masterKey must be a 32-byte AES-256 key.
+func NewAESKeyProvider(masterKey []byte) (KeyProvider, error) {
+	if len(masterKey) != 32 {
+		return nil, ErrClientEncryptionKeyInvalid
+	}
+	aead, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &aesKeyProvider{aead: aead}, nil
+}
+
+type aesKeyProvider struct {
+	aead cipher.AEAD
+}
+
+func (p *aesKeyProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return p.aead.Seal(nonce, nonce, key, nil), nil
+}
+
+func (p *aesKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < p.aead.NonceSize() {
+		return nil, ErrClientEncryptionKeyInvalid
+	}
+	nonce, ciphertext := wrapped[:p.aead.NonceSize()], wrapped[p.aead.NonceSize():]
+	key, err := p.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrClientEncryptionKeyInvalid
+	}
+	return key, nil
+}
+
+const (
+	// metadataClientEncryptionKey is the metadata name used to store the wrapped data key.
+	metadataClientEncryptionKey = "x-qs-meta-bs-cse-key"
+	// metadataClientEncryptionAlgorithm is the metadata name used to store the encryption algorithm.
+	metadataClientEncryptionAlgorithm = "x-qs-meta-bs-cse-alg"
+
+	// clientEncryptionAlgorithm means content is split into 64KB chunks like STREAM, and every
+	// chunk is sealed by AES-256-GCM with a random nonce, stored as header || nonce || ciphertext || tag.
+	//
+	// The header is made of the part number in big endian and the chunk flag, and the chunk
+	// counter in the part is appended to the header as additional data, so that chunks could
+	// not be reordered, dropped or moved to another part, and truncated content could be
+	// detected by the flag of the last chunk.
+	clientEncryptionAlgorithm = "AES256-GCM-64K-STREAM"
+	// clientEncryptionChunkSize is the plaintext size of every chunk, except the last one.
+	clientEncryptionChunkSize = 64 * 1024
+	// clientEncryptionHeaderSize is the size of chunk header, part number and flag included.
+	clientEncryptionHeaderSize = 4 + 1
+	// clientEncryptionOverhead is the extra size of every chunk, header, nonce and tag included.
+	clientEncryptionOverhead = clientEncryptionHeaderSize + 12 + 16
+)
+
+// Flags of chunks, the last chunk of every part is marked, so that truncated parts could
+// be detected.
+const (
+	chunkFlagMore byte = iota
+	// chunkFlagPartEnd means the chunk is the last one of a multipart part, the object ends
+	// after it or the next part starts.
+	chunkFlagPartEnd
+	// chunkFlagObjectEnd means the chunk is the last one of an object written by a single PUT.
+	chunkFlagObjectEnd
+)
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newDataKey will generate a new data key, and returns it with the metadata to be stored.
+func (s *Storage) newDataKey(ctx context.Context) (aead cipher.AEAD, metadata map[string]string, err error) {
+	key := make([]byte, 32)
+	if _, err = rand.Read(key); err != nil {
+		return
+	}
+	wrapped, err := s.keyProvider.WrapKey(ctx, key)
+	if err != nil {
+		return
+	}
+	aead, err = newGCM(key)
+	if err != nil {
+		return
+	}
+	metadata = map[string]string{
+		metadataClientEncryptionKey:       base64.StdEncoding.EncodeToString(wrapped),
+		metadataClientEncryptionAlgorithm: clientEncryptionAlgorithm,
+	}
+	return aead, metadata, nil
+}
+
+// openDataKey will unwrap the data key stored in metadata.
+func (s *Storage) openDataKey(ctx context.Context, wrappedKey string) (aead cipher.AEAD, err error) {
+	wrapped, err := base64.StdEncoding.DecodeString(wrappedKey)
+	if err != nil {
+		return nil, ErrClientEncryptionKeyInvalid
+	}
+	key, err := s.keyProvider.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return
+	}
+	return newGCM(key)
+}
+
+// getClientEncryptionKey will return the wrapped data key in object metadata.
+func getClientEncryptionKey(m map[string]string) (string, bool) {
+	for k, v := range m {
+		if strings.ToLower(k) == metadataClientEncryptionKey {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// encryptedSize will return the size of content after encrypted.
+//
+// Empty content will still be encrypted into an empty chunk, so that it could be verified.
+func encryptedSize(size int64) int64 {
+	chunks := (size + clientEncryptionChunkSize - 1) / clientEncryptionChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size + chunks*clientEncryptionOverhead
+}
+
+// decryptedSize will return the size of content before encrypted.
+//
+// Content encrypted by multipart is supported as long as every part except the last one is
+// aligned to clientEncryptionChunkSize, which is checked while completing multipart.
+func decryptedSize(size int64) int64 {
+	full := size / (clientEncryptionChunkSize + clientEncryptionOverhead)
+	rem := size % (clientEncryptionChunkSize + clientEncryptionOverhead)
+	if rem > clientEncryptionOverhead {
+		rem -= clientEncryptionOverhead
+	} else {
+		rem = 0
+	}
+	return full*clientEncryptionChunkSize + rem
+}
+
+// chunkAdditionalData will return the additional data of a chunk, which is the header
+// followed by the chunk counter in the part.
+func chunkAdditionalData(header []byte, counter uint64) []byte {
+	ad := make([]byte, len(header)+8)
+	copy(ad, header)
+	binary.BigEndian.PutUint64(ad[len(header):], counter)
+	return ad
+}
+
+// encryptReader will encrypt content read from r chunk by chunk.
+type encryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	part    uint32
+	lastEnd byte
+
+	// cur is the chunk to be sealed, and next is read ahead to know whether cur is the last.
+	cur, next []byte
+	started   bool
+	eof       bool
+	counter   uint64
+
+	out []byte
+	err error
+}
+
+// newEncryptReader will encrypt content of part read from r, the last chunk will be marked
+// as the end of object if last is true, otherwise the end of part.
+//
+// Content written by single PUT should be encrypted as part 0 with last set.
+func newEncryptReader(r io.Reader, aead cipher.AEAD, part int, last bool) io.Reader {
+	e := &encryptReader{
+		r:       r,
+		aead:    aead,
+		part:    uint32(part),
+		lastEnd: chunkFlagPartEnd,
+		cur:     make([]byte, clientEncryptionChunkSize),
+		next:    make([]byte, clientEncryptionChunkSize),
+	}
+	if last {
+		e.lastEnd = chunkFlagObjectEnd
+	}
+	return e
+}
+
+// readChunk will read a full chunk into buf, eof is true if r has been drained.
+func (e *encryptReader) readChunk(buf []byte) (n int, eof bool, err error) {
+	n, err = io.ReadFull(e.r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, true, nil
+	}
+	return n, false, err
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.out) == 0 {
+		if e.err != nil {
+			return 0, e.err
+		}
+		if e.out, e.err = e.seal(); e.err != nil && e.err != io.EOF {
+			return 0, e.err
+		}
+	}
+
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+// seal will seal the next chunk, and returns io.EOF with the last chunk.
+func (e *encryptReader) seal() (out []byte, err error) {
+	if !e.started {
+		e.started = true
+		n, eof, err := e.readChunk(e.cur)
+		if err != nil {
+			return nil, err
+		}
+		e.cur, e.eof = e.cur[:n], eof
+	}
+
+	flag := chunkFlagMore
+	var n int
+	if e.eof {
+		flag = e.lastEnd
+	} else {
+		// Read ahead to avoid sealing an empty chunk after aligned content.
+		var eof bool
+		n, eof, err = e.readChunk(e.next[:cap(e.next)])
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 && eof {
+			flag = e.lastEnd
+		}
+		e.eof = eof
+	}
+
+	header := make([]byte, clientEncryptionHeaderSize)
+	binary.BigEndian.PutUint32(header, e.part)
+	header[4] = flag
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = make([]byte, 0, len(header)+len(nonce)+len(e.cur)+e.aead.Overhead())
+	out = append(append(out, header...), nonce...)
+	out = e.aead.Seal(out, nonce, e.cur, chunkAdditionalData(header, e.counter))
+	e.counter++
+
+	if flag != chunkFlagMore {
+		return out, io.EOF
+	}
+	e.cur, e.next = e.next[:n], e.cur
+	return out, nil
+}
+
+// decryptReader will decrypt content encrypted by encryptReader.
+//
+// Parts must start from 0 and be contiguous, and content must end with the last chunk of
+// a part. Parts are encrypted separately, so dropping whole parts at the end could not be
+// detected.
+type decryptReader struct {
+	r    io.Reader
+	aead cipher.AEAD
+	buf  []byte
+
+	// started is true after the first chunk read.
+	started bool
+	part    uint32
+	flag    byte
+	counter uint64
+
+	out []byte
+	err error
+}
+
+func newDecryptReader(r io.Reader, aead cipher.AEAD) io.Reader {
+	return &decryptReader{
+		r:    r,
+		aead: aead,
+		buf:  make([]byte, clientEncryptionChunkSize+clientEncryptionOverhead),
+	}
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.out, d.err = d.open()
+	}
+
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// open will read and open the next chunk, and returns io.EOF after the last chunk.
+func (d *decryptReader) open() ([]byte, error) {
+	n, err := io.ReadFull(d.r, d.buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		if n == 0 {
+			// Content must end with the last chunk of a part.
+			if !d.started || d.flag == chunkFlagMore {
+				return nil, ErrContentCorrupted
+			}
+			return nil, io.EOF
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n < clientEncryptionOverhead {
+		return nil, ErrContentCorrupted
+	}
+
+	header := d.buf[:clientEncryptionHeaderSize]
+	part, flag := binary.BigEndian.Uint32(header), header[4]
+	switch {
+	case flag > chunkFlagObjectEnd:
+		return nil, ErrContentCorrupted
+	case !d.started:
+		if part != multipartNumberMinimum {
+			return nil, ErrContentCorrupted
+		}
+	case d.flag == chunkFlagObjectEnd:
+		// Nothing should be appended after the end of object.
+		return nil, ErrContentCorrupted
+	case d.flag == chunkFlagPartEnd:
+		if part != d.part+1 {
+			return nil, ErrContentCorrupted
+		}
+		d.counter = 0
+	default:
+		if part != d.part {
+			return nil, ErrContentCorrupted
+		}
+	}
+
+	nonceEnd := clientEncryptionHeaderSize + d.aead.NonceSize()
+	nonce, ciphertext := d.buf[clientEncryptionHeaderSize:nonceEnd], d.buf[nonceEnd:n]
+	out, err := d.aead.Open(ciphertext[:0], nonce, ciphertext, chunkAdditionalData(header, d.counter))
+	if err != nil {
+		return nil, ErrContentCorrupted
+	}
+	d.started, d.part, d.flag = true, part, flag
+	d.counter++
+	return out, nil
+}
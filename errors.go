@@ -6,6 +6,25 @@ var (
 	// ErrBucketNameInvalid will be returned while bucket name is invalid.
 	ErrBucketNameInvalid = services.NewErrorCode("invalid bucket name")
 
+	// ErrBucketNotExist will be returned while bucket is not exist.
+	ErrBucketNotExist = services.NewErrorCode("bucket not exist")
+
+	// ErrObjectAlreadyExist will be returned while object already exists when writing with if_none_match.
+	ErrObjectAlreadyExist = services.NewErrorCode("object already exist")
+
+	// ErrPreconditionFailed will be returned while the object doesn't match the if_match pair when reading.
+	ErrPreconditionFailed = services.NewErrorCode("precondition failed")
+
+	// ErrObjectNotModified will be returned while the object is unchanged as if_none_match or if_modified_since specified when reading.
+	ErrObjectNotModified = services.NewErrorCode("object not modified")
+
+	// ErrStorageClassInvalid will be returned while storage class could not be parsed.
+	ErrStorageClassInvalid = services.NewErrorCode("invalid storage class")
+
+	// ErrContentCorrupted will be returned while the etag returned by server doesn't match the content's md5,
+	// or the content read doesn't match the etag or sha256 checksum of the object.
+	ErrContentCorrupted = services.NewErrorCode("content corrupted")
+
 	// ErrWorkDirInvalid will be returned while work dir is invalid.
 	// Work dir must start and end with only one '/'
 	ErrWorkDirInvalid = services.NewErrorCode("invalid work dir")
@@ -14,9 +33,55 @@ var (
 	// Encryption key must be a 32-byte AES-256 key.
 	ErrEncryptionCustomerKeyInvalid = services.NewErrorCode("invalid encryption customer key")
 
+	// ErrClientEncryptionKeyInvalid will be returned while the key for client-side encryption is invalid or not found.
+	ErrClientEncryptionKeyInvalid = services.NewErrorCode("invalid client encryption key")
+
 	// ErrAppendNextPositionEmpty will be returned while next append position is empty.
 	ErrAppendNextPositionEmpty = services.NewErrorCode("next append position is empty")
 
 	// ErrPartNumberInvalid will be returned while part number is out of range [0, 10000] when uploading multipart.
 	ErrPartNumberInvalid = services.NewErrorCode("part number is out of range [0, 10000]")
+
+	// ErrWriteSizeExceeded will be returned while size exceeds the 5GB limit of a single PUT.
+	// Use multipart_threshold to switch to multipart upload for large content.
+	//
+	// It's services.ErrRestrictionDissatisfied as well.
+	ErrWriteSizeExceeded = newRestrictionErrorCode("size exceeds the limit of single PUT")
+
+	// ErrSeekOffsetInvalid will be returned while seeking to a negative position or with an invalid whence.
+	ErrSeekOffsetInvalid = services.NewErrorCode("invalid seek offset")
+
+	// ErrPartSizeInvalid will be returned while part size is out of range [4MB, 5GB] when writing with multipart_part_size,
+	// or the size of part exceeds 5GB when writing multipart.
+	//
+	// It's services.ErrRestrictionDissatisfied as well.
+	ErrPartSizeInvalid = newRestrictionErrorCode("part size is out of range [4MB, 5GB]")
+
+	// ErrPartsInvalid will be returned while parts are not contiguous, duplicated, out of range or empty except the last one
+	// when completing multipart.
+	ErrPartsInvalid = services.NewErrorCode("invalid parts")
+
+	// ErrMultipartUploadInProgress will be returned while renaming a bucket with multipart uploads in progress.
+	ErrMultipartUploadInProgress = services.NewErrorCode("multipart upload in progress")
+
+	// ErrListOrderViolated will be returned while keys are out of order across pages when listing with list_sorted.
+	ErrListOrderViolated = services.NewErrorCode("listed keys out of order")
 )
+
+// restrictionErrorCode is an error code which unwraps to services.ErrRestrictionDissatisfied,
+// so that callers checking the generic error keep working after a specific one is introduced.
+type restrictionErrorCode struct {
+	error
+}
+
+func newRestrictionErrorCode(text string) error {
+	return restrictionErrorCode{services.NewErrorCode(text)}
+}
+
+// Unwrap implements xerrors.Wrapper
+func (e restrictionErrorCode) Unwrap() error {
+	return services.ErrRestrictionDissatisfied
+}
+
+// IsInternalError implements InternalError
+func (e restrictionErrorCode) IsInternalError() {}
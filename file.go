@@ -0,0 +1,156 @@
+package qingstor
+
+import (
+	"bufio"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+const (
+	// fileBufferSize is the buffer size used while writing into local file.
+	fileBufferSize = 1024 * 1024
+
+	// readFileTempSuffix is the suffix of the temporary file used by ReadFile.
+	readFileTempSuffix = ".bstmp"
+)
+
+// WriteFile will write the content of local file into path.
+func (s *Storage) WriteFile(path, localPath string, pairs ...Pair) (n int64, err error) {
+	ctx := context.Background()
+	return s.WriteFileWithContext(ctx, path, localPath, pairs...)
+}
+
+// WriteFileWithContext will write the content of local file into path.
+//
+// Pairs for Write are supported, size of the local file will be used as the size of content.
+func (s *Storage) WriteFileWithContext(ctx context.Context, path, localPath string, pairs ...Pair) (n int64, err error) {
+	defer func() {
+		err = s.formatError("write_file", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Write...)
+	opt, err := s.parsePairStorageWrite(pairs)
+	if err != nil {
+		return
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+
+	return s.write(ctx, strings.ReplaceAll(path, "\\", "/"), f, fi.Size(), opt)
+}
+
+// ReadFile will read the content of path into local file.
+func (s *Storage) ReadFile(path, localPath string, pairs ...Pair) (n int64, err error) {
+	ctx := context.Background()
+	return s.ReadFileWithContext(ctx, path, localPath, pairs...)
+}
+
+// ReadFileWithContext will read the content of path into local file.
+//
+// Pairs for Read are supported. Content will be written into a temporary file in the same
+// directory, and renamed to local file after synced to disk, so that partially read content
+// will never be seen at local file. Existing local file will be replaced.
+//
+// While reading the whole object, verify_etag will be enabled unless it's set, so that
+// corrupted content fails with ErrContentCorrupted instead of being renamed. Processed image
+// could not be verified, so verify_etag is not enabled with image_process.
+//
+// Local file is created with mode 0666 before umask like os.Create, and the directory will be
+// synced after renamed, so that the local file will not be lost after a crash.
+func (s *Storage) ReadFileWithContext(ctx context.Context, path, localPath string, pairs ...Pair) (n int64, err error) {
+	defer func() {
+		err = s.formatError("read_file", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Read...)
+	opt, err := s.parsePairStorageRead(pairs)
+	if err != nil {
+		return
+	}
+	if !opt.HasVerifyEtag && !opt.HasOffset && !opt.HasSize && !opt.HasSuffixSize && !opt.HasImageProcess {
+		opt.HasVerifyEtag, opt.VerifyEtag = true, true
+	}
+
+	f, err := createReadFileTemp(localPath)
+	if err != nil {
+		return
+	}
+	defer func() {
+		// Temporary file is closed and renamed if succeeded.
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	w := bufio.NewWriterSize(f, fileBufferSize)
+	n, err = s.read(ctx, strings.ReplaceAll(path, "\\", "/"), w, opt)
+	if err != nil {
+		return
+	}
+	if err = w.Flush(); err != nil {
+		return
+	}
+	if err = f.Sync(); err != nil {
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(f.Name(), localPath); err != nil {
+		return
+	}
+	if err = syncDir(filepath.Dir(localPath)); err != nil {
+		return
+	}
+	return n, nil
+}
+
+// createReadFileTemp will create the temporary file for ReadFile in the same directory of
+// localPath.
+//
+// ioutil.TempFile creates files with mode 0600, so the file is created with 0666 here like
+// os.Create, and the umask will be applied.
+func createReadFileTemp(localPath string) (f *os.File, err error) {
+	prefix := filepath.Join(filepath.Dir(localPath), "."+filepath.Base(localPath)+".")
+	for i := 0; i < 10000; i++ {
+		name := prefix + strconv.FormatUint(uint64(rand.Uint32()), 10) + readFileTempSuffix
+		f, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) {
+			continue
+		}
+		return
+	}
+	return
+}
+
+// syncDir will sync the directory, so that entries renamed into it are persisted.
+func syncDir(dir string) error {
+	// Directories could not be synced on windows.
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
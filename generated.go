@@ -28,8 +28,19 @@ const Type = "qingstor"
 
 // ObjectSystemMetadata stores system metadata for object.
 type ObjectSystemMetadata struct {
+	ClientEncryptionKey         string
+	ContentSha256               string
+	Count                       int64
+	Created                     time.Time
+	Encrypted                   bool
 	EncryptionCustomerAlgorithm string
+	Initiated                   time.Time
+	OwnerID                     string
+	OwnerName                   string
+	Size                        int64
+	Status                      string
 	StorageClass                string
+	URL                         string
 }
 
 // GetObjectSystemMetadata will get ObjectSystemMetadata from Object.
@@ -53,8 +64,19 @@ func setObjectSystemMetadata(o *Object, sm ObjectSystemMetadata) {
 
 // StorageSystemMetadata stores system metadata for object.
 type StorageSystemMetadata struct {
+	ClientEncryptionKey         string
+	ContentSha256               string
+	Count                       int64
+	Created                     time.Time
+	Encrypted                   bool
 	EncryptionCustomerAlgorithm string
+	Initiated                   time.Time
+	OwnerID                     string
+	OwnerName                   string
+	Size                        int64
+	Status                      string
 	StorageClass                string
+	URL                         string
 }
 
 // GetStorageSystemMetadata will get StorageSystemMetadata from Storage.
@@ -76,6 +98,75 @@ func setStorageSystemMetadata(s *StorageMeta, sm StorageSystemMetadata) {
 	s.SetSystemMetadata(sm)
 }
 
+// WithAutoContentMd5 will apply auto_content_md5 value to Options.
+//
+// will calculate Content-MD5 of the content automatically, the content will be buffered in memory
+// if the reader is not an io.ReadSeeker.
+func WithAutoContentMd5() Pair {
+	return Pair{Key: "auto_content_md5", Value: true}
+}
+
+// WithAutoContentSha256 will apply auto_content_sha256 value to Options.
+//
+// will calculate SHA-256 checksum of the content automatically, the content will be buffered in
+// memory if the reader is not an io.ReadSeeker, not supported while writing via multipart upload.
+func WithAutoContentSha256() Pair {
+	return Pair{Key: "auto_content_sha256", Value: true}
+}
+
+// WithCacheControl will apply cache_control value to Options.
+//
+// specifies the Cache-Control header of the object.
+func WithCacheControl(v string) Pair {
+	return Pair{Key: "cache_control", Value: v}
+}
+
+// WithCannedACL will apply canned_acl value to Options.
+//
+// specifies the canned ACL applied to the bucket after creation, could be private, public-read or
+// public-read-write.
+func WithCannedACL(v string) Pair {
+	return Pair{Key: "canned_acl", Value: v}
+}
+
+// WithCompression will apply compression value to Options.
+//
+// specifies the compression algorithm applied on content while writing and removed while reading,
+// only gzip is supported.
+func WithCompression(v string) Pair {
+	return Pair{Key: "compression", Value: v}
+}
+
+// WithContentDisposition will apply content_disposition value to Options.
+//
+// specifies the Content-Disposition header of the object.
+func WithContentDisposition(v string) Pair {
+	return Pair{Key: "content_disposition", Value: v}
+}
+
+// WithContentEncoding will apply content_encoding value to Options.
+//
+// specifies the Content-Encoding header of the object, like gzip.
+func WithContentEncoding(v string) Pair {
+	return Pair{Key: "content_encoding", Value: v}
+}
+
+// WithContentSha256 will apply content_sha256 value to Options.
+//
+// specifies the hex encoded SHA-256 checksum of the content, which will be stored in object metadata,
+// content written via multipart upload by Write will be verified with it before completed.
+func WithContentSha256(v string) Pair {
+	return Pair{Key: "content_sha256", Value: v}
+}
+
+// WithCopyBufferSize will apply copy_buffer_size value to Options.
+//
+// specifies the size of pooled buffers used while copying content, default to 32KB and capped to 16MB.
+// It could be set for storage and overridden by read, and by write only with compression.
+func WithCopyBufferSize(v int) Pair {
+	return Pair{Key: "copy_buffer_size", Value: v}
+}
+
 // WithCopySourceEncryptionCustomerAlgorithm will apply copy_source_encryption_customer_algorithm
 // value to Options.
 //
@@ -100,18 +191,63 @@ func WithDefaultServicePairs(v DefaultServicePairs) Pair {
 	return Pair{Key: "default_service_pairs", Value: v}
 }
 
+// WithDefaultStorageClass will apply default_storage_class value to Options.
+func WithDefaultStorageClass(v string) Pair {
+	return Pair{Key: "default_storage_class", Value: v}
+}
+
 // WithDefaultStoragePairs will apply default_storage_pairs value to Options.
 //
-// set default pairs for storager actions
+// set default pairs for storager actions, multiple default_storage_pairs will be merged and the
+// former take precedence
 func WithDefaultStoragePairs(v DefaultStoragePairs) Pair {
 	return Pair{Key: "default_storage_pairs", Value: v}
 }
 
+// WithDetectContentType will apply detect_content_type value to Options.
+//
+// will detect content type from the extension of the path while content_type is not set, content buffered
+// by streamed or multipart writes will be sniffed if the extension is unknown.
+func WithDetectContentType() Pair {
+	return Pair{Key: "detect_content_type", Value: true}
+}
+
 // WithDisableURICleaning will apply disable_uri_cleaning value to Options.
 func WithDisableURICleaning() Pair {
 	return Pair{Key: "disable_uri_cleaning", Value: true}
 }
 
+// WithDownloadConcurrency will apply download_concurrency value to Options.
+//
+// specifies the number of ranges fetched at the same time while downloading, default to 4, only works
+// with Download and DownloadFile.
+func WithDownloadConcurrency(v int) Pair {
+	return Pair{Key: "download_concurrency", Value: v}
+}
+
+// WithDownloadPartSize will apply download_part_size value to Options.
+//
+// specifies the range size fetched by every request while downloading, default to 8MB, only works
+// with Download and DownloadFile.
+func WithDownloadPartSize(v int64) Pair {
+	return Pair{Key: "download_part_size", Value: v}
+}
+
+// WithDryRun will apply dry_run value to Options.
+//
+// will only report what would be done without making any changes. It's only supported by Rename.
+func WithDryRun() Pair {
+	return Pair{Key: "dry_run", Value: true}
+}
+
+// WithDstLocation will apply dst_location value to Options.
+//
+// specifies the location of the destination bucket, will be detected automatically if not set. It's
+// only supported by Replicate.
+func WithDstLocation(v string) Pair {
+	return Pair{Key: "dst_location", Value: v}
+}
+
 // WithEnableVirtualDir will apply enable_virtual_dir value to Options.
 //
 // virtual_dir feature is designed for a service that doesn't have native dir support but wants to
@@ -154,6 +290,259 @@ func WithEncryptionCustomerKey(v []byte) Pair {
 	return Pair{Key: "encryption_customer_key", Value: v}
 }
 
+// WithExpires will apply expires value to Options.
+//
+// specifies the Expires header of the object.
+func WithExpires(v time.Time) Pair {
+	return Pair{Key: "expires", Value: v}
+}
+
+// WithForce will apply force value to Options.
+//
+// will delete all objects and abort all multipart uploads in the bucket before deleting it.
+func WithForce() Pair {
+	return Pair{Key: "force", Value: true}
+}
+
+// WithIfMatch will apply if_match value to Options.
+//
+// specifies the If-Match header, make read fail with ErrPreconditionFailed if the etag of object
+// doesn't match.
+func WithIfMatch(v string) Pair {
+	return Pair{Key: "if_match", Value: v}
+}
+
+// WithIfModifiedSince will apply if_modified_since value to Options.
+//
+// specifies the If-Modified-Since header, make read fail with ErrObjectNotModified if the object
+// is not modified since then.
+func WithIfModifiedSince(v time.Time) Pair {
+	return Pair{Key: "if_modified_since", Value: v}
+}
+
+// WithIfNoneMatch will apply if_none_match value to Options.
+//
+// specifies the If-None-Match header, use * to make write fail if the object already exists, or etag
+// to make read fail with ErrObjectNotModified if the object is unchanged.
+func WithIfNoneMatch(v string) Pair {
+	return Pair{Key: "if_none_match", Value: v}
+}
+
+// WithImageProcess will apply image_process value to Options.
+//
+// specifies the image processing actions applied on the object, the processed image will be returned
+// instead.
+func WithImageProcess(v []ImageAction) Pair {
+	return Pair{Key: "image_process", Value: v}
+}
+
+// WithImplicitDir will apply implicit_dir value to Options.
+//
+// will return a directory object from Stat for path with trailing slash if keys exist under it, even
+// if the directory object doesn't exist.
+func WithImplicitDir() Pair {
+	return Pair{Key: "implicit_dir", Value: true}
+}
+
+// WithIncludeMetadata will apply include_metadata value to Options.
+//
+// specifies to stat every listed object to get full metadata like user metadata and link target, which
+// costs a HEAD request per object. Listed objects are always stated while key_provider is set, since
+// the size of content encrypted by client is only known after stated.
+func WithIncludeMetadata() Pair {
+	return Pair{Key: "include_metadata", Value: true}
+}
+
+// WithKeyProvider will apply key_provider value to Options.
+//
+// will enable client-side encryption, data keys will be wrapped by the provider and stored in object
+// metadata. Multipart parts except the last one must be aligned to 64KB.
+func WithKeyProvider(v KeyProvider) Pair {
+	return Pair{Key: "key_provider", Value: v}
+}
+
+// WithListConcurrency will apply list_concurrency value to Options.
+//
+// specifies the number of sub-prefixes listed by ListConcurrent or objects stated for include_metadata
+// at the same time, default to 8.
+func WithListConcurrency(v int) Pair {
+	return Pair{Key: "list_concurrency", Value: v}
+}
+
+// WithListGlob will apply list_glob value to Options.
+//
+// specifies the pattern like "logs/*/*.gz" to filter objects by path while listing, see path.Match
+// for the syntax.
+func WithListGlob(v string) Pair {
+	return Pair{Key: "list_glob", Value: v}
+}
+
+// WithListRegexp will apply list_regexp value to Options.
+//
+// specifies the regular expression to filter objects by path while listing.
+func WithListRegexp(v string) Pair {
+	return Pair{Key: "list_regexp", Value: v}
+}
+
+// WithListRetry will apply list_retry value to Options.
+//
+// specifies the max retry times with backoff for transient failures while fetching every page of
+// listing.
+func WithListRetry(v int) Pair {
+	return Pair{Key: "list_retry", Value: v}
+}
+
+// WithListSorted will apply list_sorted value to Options.
+//
+// specifies to return objects in lexicographic order of keys without duplicated keys, listing fails
+// with ErrListOrderViolated if keys returned by server are out of order.
+func WithListSorted() Pair {
+	return Pair{Key: "list_sorted", Value: true}
+}
+
+// WithLocations will apply locations value to Options.
+//
+// specifies the locations to list buckets from concurrently, buckets in all locations will be merged.
+func WithLocations(v []string) Pair {
+	return Pair{Key: "locations", Value: v}
+}
+
+// WithMaxSize will apply max_size value to Options.
+//
+// specifies to list only objects not larger than the size in bytes.
+func WithMaxSize(v int64) Pair {
+	return Pair{Key: "max_size", Value: v}
+}
+
+// WithMinSize will apply min_size value to Options.
+//
+// specifies to list only objects not smaller than the size in bytes.
+func WithMinSize(v int64) Pair {
+	return Pair{Key: "min_size", Value: v}
+}
+
+// WithModifiedAfter will apply modified_after value to Options.
+//
+// specifies to list only objects modified after the time.
+func WithModifiedAfter(v time.Time) Pair {
+	return Pair{Key: "modified_after", Value: v}
+}
+
+// WithModifiedBefore will apply modified_before value to Options.
+//
+// specifies to list only objects modified before the time.
+func WithModifiedBefore(v time.Time) Pair {
+	return Pair{Key: "modified_before", Value: v}
+}
+
+// WithMultipartConcurrency will apply multipart_concurrency value to Options.
+//
+// specifies the number of parts uploaded at the same time while write switches to multipart upload,
+// default to 1.
+func WithMultipartConcurrency(v int) Pair {
+	return Pair{Key: "multipart_concurrency", Value: v}
+}
+
+// WithMultipartPartSize will apply multipart_part_size value to Options.
+//
+// specifies the part size used while write switches to multipart upload, default to 64MB.
+func WithMultipartPartSize(v int64) Pair {
+	return Pair{Key: "multipart_part_size", Value: v}
+}
+
+// WithMultipartProgress will apply multipart_progress value to Options.
+//
+// specifies the callback receiving the overall progress every time a part has been uploaded while
+// write switches to multipart upload.
+func WithMultipartProgress(v MultipartProgressCallback) Pair {
+	return Pair{Key: "multipart_progress", Value: v}
+}
+
+// WithMultipartRetry will apply multipart_retry value to Options.
+//
+// specifies the max retry times for transient failures of every part while write switches to multipart
+// upload.
+func WithMultipartRetry(v int) Pair {
+	return Pair{Key: "multipart_retry", Value: v}
+}
+
+// WithMultipartThreshold will apply multipart_threshold value to Options.
+//
+// will make write switch to multipart upload while size exceeds the threshold.
+func WithMultipartThreshold(v int64) Pair {
+	return Pair{Key: "multipart_threshold", Value: v}
+}
+
+// WithPageSize will apply page_size value to Options.
+//
+// specifies the max number of items returned in each page while listing.
+func WithPageSize(v int) Pair {
+	return Pair{Key: "page_size", Value: v}
+}
+
+// WithPrefixesOnly will apply prefixes_only value to Options.
+//
+// specifies to return only the immediate sub-directories (common prefixes) of the path, objects
+// will be skipped. It works with ListModeDir, which is the default list mode while it is set.
+func WithPrefixesOnly() Pair {
+	return Pair{Key: "prefixes_only", Value: true}
+}
+
+// WithReadRateLimit will apply read_rate_limit value to Options.
+//
+// specifies the max bytes per second while downloading content.
+func WithReadRateLimit(v int64) Pair {
+	return Pair{Key: "read_rate_limit", Value: v}
+}
+
+// WithReadRetry will apply read_retry value to Options.
+//
+// specifies the max retry times to resume reading from the broken position while the response stream
+// fails, content is guaranteed unchanged by etag.
+func WithReadRetry(v int) Pair {
+	return Pair{Key: "read_retry", Value: v}
+}
+
+// WithReaderBlockCache will apply reader_block_cache value to Options.
+//
+// specifies the max number of blocks cached by RangeReader, blocks will not be cached by default,
+// only works with ReaderAt.
+func WithReaderBlockCache(v int) Pair {
+	return Pair{Key: "reader_block_cache", Value: v}
+}
+
+// WithReaderBlockSize will apply reader_block_size value to Options.
+//
+// specifies the size of blocks fetched and cached by RangeReader, default to 1MB, only works with
+// ReaderAt.
+func WithReaderBlockSize(v int64) Pair {
+	return Pair{Key: "reader_block_size", Value: v}
+}
+
+// WithReaderReadAhead will apply reader_read_ahead value to Options.
+//
+// specifies the number of blocks prefetched after the current position while reading RangeReader
+// sequentially, only works with ReaderAt.
+func WithReaderReadAhead(v int) Pair {
+	return Pair{Key: "reader_read_ahead", Value: v}
+}
+
+// WithReplicateConcurrency will apply replicate_concurrency value to Options.
+//
+// specifies the number of objects replicated at the same time, default to 4. It's only supported by
+// Replicate.
+func WithReplicateConcurrency(v int) Pair {
+	return Pair{Key: "replicate_concurrency", Value: v}
+}
+
+// WithReplicateProgress will apply replicate_progress value to Options.
+//
+// specifies the callback called after an object has been replicated or skipped, it could be called
+// concurrently. It's only supported by Replicate.
+func WithReplicateProgress(v ReplicateProgressCallback) Pair {
+	return Pair{Key: "replicate_progress", Value: v}
+}
+
 // WithServiceFeatures will apply service_features value to Options.
 //
 // set service features
@@ -161,6 +550,36 @@ func WithServiceFeatures(v ServiceFeatures) Pair {
 	return Pair{Key: "service_features", Value: v}
 }
 
+// WithStatCacheNegativeTTL will apply stat_cache_negative_ttl value to Options.
+//
+// specifies how long results of Stat for objects not exist will be cached, which should be shorter
+// than stat_cache_ttl.
+func WithStatCacheNegativeTTL(v time.Duration) Pair {
+	return Pair{Key: "stat_cache_negative_ttl", Value: v}
+}
+
+// WithStatCacheSize will apply stat_cache_size value to Options.
+//
+// specifies the max number of cached results of Stat, default to 1024.
+func WithStatCacheSize(v int) Pair {
+	return Pair{Key: "stat_cache_size", Value: v}
+}
+
+// WithStatCacheTTL will apply stat_cache_ttl value to Options.
+//
+// specifies how long results of Stat will be cached, cache is disabled while not set.
+func WithStatCacheTTL(v time.Duration) Pair {
+	return Pair{Key: "stat_cache_ttl", Value: v}
+}
+
+// WithStatistics will apply statistics value to Options.
+//
+// will fetch bucket statistics like size, count and status while getting storage metadata. Metadata
+// leaves them empty if failed, use MetadataWithContext to get the error.
+func WithStatistics() Pair {
+	return Pair{Key: "statistics", Value: true}
+}
+
 // WithStorageClass will apply storage_class value to Options.
 func WithStorageClass(v string) Pair {
 	return Pair{Key: "storage_class", Value: v}
@@ -173,7 +592,76 @@ func WithStorageFeatures(v StorageFeatures) Pair {
 	return Pair{Key: "storage_features", Value: v}
 }
 
-var pairMap = map[string]string{"content_md5": "string", "content_type": "string", "context": "context.Context", "continuation_token": "string", "copy_source_encryption_customer_algorithm": "string", "copy_source_encryption_customer_key": "[]byte", "credential": "string", "default_content_type": "string", "default_io_callback": "func([]byte)", "default_service_pairs": "DefaultServicePairs", "default_storage_pairs": "DefaultStoragePairs", "disable_uri_cleaning": "bool", "enable_virtual_dir": "bool", "enable_virtual_link": "bool", "encryption_customer_algorithm": "string", "encryption_customer_key": "[]byte", "endpoint": "string", "expire": "time.Duration", "http_client_options": "*httpclient.Options", "interceptor": "Interceptor", "io_callback": "func([]byte)", "list_mode": "ListMode", "location": "string", "multipart_id": "string", "name": "string", "object_mode": "ObjectMode", "offset": "int64", "service_features": "ServiceFeatures", "size": "int64", "storage_class": "string", "storage_features": "StorageFeatures", "work_dir": "string"}
+// WithSuffixSize will apply suffix_size value to Options.
+//
+// specifies to read the last N bytes of the object, could not be used with offset or size.
+func WithSuffixSize(v int64) Pair {
+	return Pair{Key: "suffix_size", Value: v}
+}
+
+// WithTransferCallback will apply transfer_callback value to Options.
+//
+// specifies the callback receiving cumulative bytes, elapsed time and instantaneous rate while
+// transferring content.
+func WithTransferCallback(v TransferCallback) Pair {
+	return Pair{Key: "transfer_callback", Value: v}
+}
+
+// WithUploadConcurrency will apply upload_concurrency value to Options.
+//
+// specifies the max number of parts uploaded at the same time by every multipart upload of the storage,
+// multipart_concurrency larger than it will be capped. It could also be passed to write, which overrides
+// the one of the storage for this call.
+func WithUploadConcurrency(v int) Pair {
+	return Pair{Key: "upload_concurrency", Value: v}
+}
+
+// WithUserMetadata will apply user_metadata value to Options.
+//
+// specifies the user-defined metadata of the object, keys should not contain the x-qs-meta- prefix.
+func WithUserMetadata(v map[string]string) Pair {
+	return Pair{Key: "user_metadata", Value: v}
+}
+
+// WithValidateBucket will apply validate_bucket value to Options.
+//
+// will send a HEAD request to make sure the bucket exists and is reachable while getting it.
+func WithValidateBucket() Pair {
+	return Pair{Key: "validate_bucket", Value: true}
+}
+
+// WithVerifyEtag will apply verify_etag value to Options.
+//
+// will verify the etag returned by server with the md5 of the content while writing, and verify the
+// etag of object with the md5 of content read while reading. It doesn't work with server-side encryption
+// or objects uploaded by multipart.
+func WithVerifyEtag() Pair {
+	return Pair{Key: "verify_etag", Value: true}
+}
+
+// WithVerifySha256 will apply verify_sha256 value to Options.
+//
+// will verify the content with the SHA-256 checksum stored in object metadata while reading.
+func WithVerifySha256() Pair {
+	return Pair{Key: "verify_sha256", Value: true}
+}
+
+// WithWriteRateLimit will apply write_rate_limit value to Options.
+//
+// specifies the max bytes per second while uploading content.
+func WithWriteRateLimit(v int64) Pair {
+	return Pair{Key: "write_rate_limit", Value: v}
+}
+
+// WithWriteRetry will apply write_retry value to Options.
+//
+// specifies the max retry times for transient failures while writing, only works while the reader
+// is an io.Seeker or io.ReaderAt.
+func WithWriteRetry(v int) Pair {
+	return Pair{Key: "write_retry", Value: v}
+}
+
+var pairMap = map[string]string{"auto_content_md5": "bool", "auto_content_sha256": "bool", "cache_control": "string", "canned_acl": "string", "compression": "string", "content_disposition": "string", "content_encoding": "string", "content_md5": "string", "content_sha256": "string", "content_type": "string", "context": "context.Context", "continuation_token": "string", "copy_buffer_size": "int", "copy_source_encryption_customer_algorithm": "string", "copy_source_encryption_customer_key": "[]byte", "credential": "string", "default_content_type": "string", "default_io_callback": "func([]byte)", "default_service_pairs": "DefaultServicePairs", "default_storage_class": "string", "default_storage_pairs": "DefaultStoragePairs", "detect_content_type": "bool", "disable_uri_cleaning": "bool", "download_concurrency": "int", "download_part_size": "int64", "dry_run": "bool", "dst_location": "string", "enable_virtual_dir": "bool", "enable_virtual_link": "bool", "encryption_customer_algorithm": "string", "encryption_customer_key": "[]byte", "endpoint": "string", "expire": "time.Duration", "expires": "time.Time", "force": "bool", "http_client_options": "*httpclient.Options", "if_match": "string", "if_modified_since": "time.Time", "if_none_match": "string", "image_process": "[]ImageAction", "implicit_dir": "bool", "include_metadata": "bool", "interceptor": "Interceptor", "io_callback": "func([]byte)", "key_provider": "KeyProvider", "list_concurrency": "int", "list_glob": "string", "list_mode": "ListMode", "list_regexp": "string", "list_retry": "int", "list_sorted": "bool", "location": "string", "locations": "[]string", "max_size": "int64", "min_size": "int64", "modified_after": "time.Time", "modified_before": "time.Time", "multipart_concurrency": "int", "multipart_id": "string", "multipart_part_size": "int64", "multipart_progress": "MultipartProgressCallback", "multipart_retry": "int", "multipart_threshold": "int64", "name": "string", "object_mode": "ObjectMode", "offset": "int64", "page_size": "int", "prefixes_only": "bool", "read_rate_limit": "int64", "read_retry": "int", "reader_block_cache": "int", "reader_block_size": "int64", "reader_read_ahead": "int", "replicate_concurrency": "int", "replicate_progress": "ReplicateProgressCallback", "service_features": "ServiceFeatures", "size": "int64", "stat_cache_negative_ttl": "time.Duration", "stat_cache_size": "int", "stat_cache_ttl": "time.Duration", "statistics": "bool", "storage_class": "string", "storage_features": "StorageFeatures", "suffix_size": "int64", "transfer_callback": "TransferCallback", "upload_concurrency": "int", "user_metadata": "map[string]string", "validate_bucket": "bool", "verify_etag": "bool", "verify_sha256": "bool", "work_dir": "string", "write_rate_limit": "int64", "write_retry": "int"}
 var _ Servicer = &Service{}
 
 type ServiceFeatures struct {
@@ -189,6 +677,8 @@ type pairServiceNew struct {
 	// Optional pairs
 	HasDefaultServicePairs bool
 	DefaultServicePairs    DefaultServicePairs
+	HasDefaultStorageClass bool
+	DefaultStorageClass    string
 	HasEndpoint            bool
 	Endpoint               string
 	HasHTTPClientOptions   bool
@@ -217,6 +707,12 @@ func parsePairServiceNew(opts []Pair) (pairServiceNew, error) {
 			}
 			result.HasDefaultServicePairs = true
 			result.DefaultServicePairs = v.Value.(DefaultServicePairs)
+		case "default_storage_class":
+			if result.HasDefaultStorageClass {
+				continue
+			}
+			result.HasDefaultStorageClass = true
+			result.DefaultStorageClass = v.Value.(string)
 		case "endpoint":
 			if result.HasEndpoint {
 				continue
@@ -260,6 +756,10 @@ type pairServiceCreate struct {
 	HasLocation bool
 	Location    string
 	// Optional pairs
+	HasCannedACL           bool
+	CannedACL              string
+	HasDefaultStorageClass bool
+	DefaultStorageClass    string
 }
 
 func (s *Service) parsePairServiceCreate(opts []Pair) (pairServiceCreate, error) {
@@ -274,6 +774,18 @@ func (s *Service) parsePairServiceCreate(opts []Pair) (pairServiceCreate, error)
 			}
 			result.HasLocation = true
 			result.Location = v.Value.(string)
+		case "canned_acl":
+			if result.HasCannedACL {
+				continue
+			}
+			result.HasCannedACL = true
+			result.CannedACL = v.Value.(string)
+		case "default_storage_class":
+			if result.HasDefaultStorageClass {
+				continue
+			}
+			result.HasDefaultStorageClass = true
+			result.DefaultStorageClass = v.Value.(string)
 		default:
 			return pairServiceCreate{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -288,6 +800,8 @@ type pairServiceDelete struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
+	HasForce    bool
+	Force       bool
 	HasLocation bool
 	Location    string
 }
@@ -298,6 +812,12 @@ func (s *Service) parsePairServiceDelete(opts []Pair) (pairServiceDelete, error)
 
 	for _, v := range opts {
 		switch v.Key {
+		case "force":
+			if result.HasForce {
+				continue
+			}
+			result.HasForce = true
+			result.Force = v.Value.(bool)
 		case "location":
 			if result.HasLocation {
 				continue
@@ -316,8 +836,10 @@ type pairServiceGet struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasLocation bool
-	Location    string
+	HasLocation       bool
+	Location          string
+	HasValidateBucket bool
+	ValidateBucket    bool
 }
 
 func (s *Service) parsePairServiceGet(opts []Pair) (pairServiceGet, error) {
@@ -332,6 +854,12 @@ func (s *Service) parsePairServiceGet(opts []Pair) (pairServiceGet, error) {
 			}
 			result.HasLocation = true
 			result.Location = v.Value.(string)
+		case "validate_bucket":
+			if result.HasValidateBucket {
+				continue
+			}
+			result.HasValidateBucket = true
+			result.ValidateBucket = v.Value.(bool)
 		default:
 			return pairServiceGet{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -344,8 +872,14 @@ type pairServiceList struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasLocation bool
-	Location    string
+	HasContinuationToken bool
+	ContinuationToken    string
+	HasLocation          bool
+	Location             string
+	HasLocations         bool
+	Locations            []string
+	HasPageSize          bool
+	PageSize             int
 }
 
 func (s *Service) parsePairServiceList(opts []Pair) (pairServiceList, error) {
@@ -354,12 +888,30 @@ func (s *Service) parsePairServiceList(opts []Pair) (pairServiceList, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "continuation_token":
+			if result.HasContinuationToken {
+				continue
+			}
+			result.HasContinuationToken = true
+			result.ContinuationToken = v.Value.(string)
 		case "location":
 			if result.HasLocation {
 				continue
 			}
 			result.HasLocation = true
 			result.Location = v.Value.(string)
+		case "locations":
+			if result.HasLocations {
+				continue
+			}
+			result.HasLocations = true
+			result.Locations = v.Value.([]string)
+		case "page_size":
+			if result.HasPageSize {
+				continue
+			}
+			result.HasPageSize = true
+			result.PageSize = v.Value.(int)
 		default:
 			return pairServiceList{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -485,22 +1037,36 @@ type pairStorageNew struct {
 	HasName bool
 	Name    string
 	// Optional pairs
-	HasDefaultContentType  bool
-	DefaultContentType     string
-	HasDefaultIoCallback   bool
-	DefaultIoCallback      func([]byte)
-	HasDefaultStoragePairs bool
-	DefaultStoragePairs    DefaultStoragePairs
-	HasDisableURICleaning  bool
-	DisableURICleaning     bool
-	HasHTTPClientOptions   bool
-	HTTPClientOptions      *httpclient.Options
-	HasLocation            bool
-	Location               string
-	HasStorageFeatures     bool
-	StorageFeatures        StorageFeatures
-	HasWorkDir             bool
-	WorkDir                string
+	HasCopyBufferSize       bool
+	CopyBufferSize          int
+	HasDefaultContentType   bool
+	DefaultContentType      string
+	HasDefaultIoCallback    bool
+	DefaultIoCallback       func([]byte)
+	HasDefaultStorageClass  bool
+	DefaultStorageClass     string
+	HasDefaultStoragePairs  bool
+	DefaultStoragePairs     DefaultStoragePairs
+	HasDisableURICleaning   bool
+	DisableURICleaning      bool
+	HasHTTPClientOptions    bool
+	HTTPClientOptions       *httpclient.Options
+	HasKeyProvider          bool
+	KeyProvider             KeyProvider
+	HasLocation             bool
+	Location                string
+	HasStatCacheNegativeTTL bool
+	StatCacheNegativeTTL    time.Duration
+	HasStatCacheSize        bool
+	StatCacheSize           int
+	HasStatCacheTTL         bool
+	StatCacheTTL            time.Duration
+	HasStorageFeatures      bool
+	StorageFeatures         StorageFeatures
+	HasUploadConcurrency    bool
+	UploadConcurrency       int
+	HasWorkDir              bool
+	WorkDir                 string
 	// Enable features
 	hasEnableVirtualDir  bool
 	EnableVirtualDir     bool
@@ -521,6 +1087,12 @@ func parsePairStorageNew(opts []Pair) (pairStorageNew, error) {
 			}
 			result.HasName = true
 			result.Name = v.Value.(string)
+		case "copy_buffer_size":
+			if result.HasCopyBufferSize {
+				continue
+			}
+			result.HasCopyBufferSize = true
+			result.CopyBufferSize = v.Value.(int)
 		case "default_content_type":
 			if result.HasDefaultContentType {
 				continue
@@ -533,6 +1105,12 @@ func parsePairStorageNew(opts []Pair) (pairStorageNew, error) {
 			}
 			result.HasDefaultIoCallback = true
 			result.DefaultIoCallback = v.Value.(func([]byte))
+		case "default_storage_class":
+			if result.HasDefaultStorageClass {
+				continue
+			}
+			result.HasDefaultStorageClass = true
+			result.DefaultStorageClass = v.Value.(string)
 		case "default_storage_pairs":
 			if result.HasDefaultStoragePairs {
 				continue
@@ -551,18 +1129,48 @@ func parsePairStorageNew(opts []Pair) (pairStorageNew, error) {
 			}
 			result.HasHTTPClientOptions = true
 			result.HTTPClientOptions = v.Value.(*httpclient.Options)
+		case "key_provider":
+			if result.HasKeyProvider {
+				continue
+			}
+			result.HasKeyProvider = true
+			result.KeyProvider = v.Value.(KeyProvider)
 		case "location":
 			if result.HasLocation {
 				continue
 			}
 			result.HasLocation = true
 			result.Location = v.Value.(string)
+		case "stat_cache_negative_ttl":
+			if result.HasStatCacheNegativeTTL {
+				continue
+			}
+			result.HasStatCacheNegativeTTL = true
+			result.StatCacheNegativeTTL = v.Value.(time.Duration)
+		case "stat_cache_size":
+			if result.HasStatCacheSize {
+				continue
+			}
+			result.HasStatCacheSize = true
+			result.StatCacheSize = v.Value.(int)
+		case "stat_cache_ttl":
+			if result.HasStatCacheTTL {
+				continue
+			}
+			result.HasStatCacheTTL = true
+			result.StatCacheTTL = v.Value.(time.Duration)
 		case "storage_features":
 			if result.HasStorageFeatures {
 				continue
 			}
 			result.HasStorageFeatures = true
 			result.StorageFeatures = v.Value.(StorageFeatures)
+		case "upload_concurrency":
+			if result.HasUploadConcurrency {
+				continue
+			}
+			result.HasUploadConcurrency = true
+			result.UploadConcurrency = v.Value.(int)
 		case "work_dir":
 			if result.HasWorkDir {
 				continue
@@ -596,6 +1204,7 @@ func parsePairStorageNew(opts []Pair) (pairStorageNew, error) {
 	if result.HasDefaultContentType {
 		result.HasDefaultStoragePairs = true
 		result.DefaultStoragePairs.CreateAppend = append(result.DefaultStoragePairs.CreateAppend, WithContentType(result.DefaultContentType))
+		result.DefaultStoragePairs.CreateMultipart = append(result.DefaultStoragePairs.CreateMultipart, WithContentType(result.DefaultContentType))
 		result.DefaultStoragePairs.QuerySignHTTPWrite = append(result.DefaultStoragePairs.QuerySignHTTPWrite, WithContentType(result.DefaultContentType))
 		result.DefaultStoragePairs.Write = append(result.DefaultStoragePairs.Write, WithContentType(result.DefaultContentType))
 	}
@@ -605,6 +1214,15 @@ func parsePairStorageNew(opts []Pair) (pairStorageNew, error) {
 		result.DefaultStoragePairs.Write = append(result.DefaultStoragePairs.Write, WithIoCallback(result.DefaultIoCallback))
 		result.DefaultStoragePairs.WriteMultipart = append(result.DefaultStoragePairs.WriteMultipart, WithIoCallback(result.DefaultIoCallback))
 	}
+	if result.HasDefaultStorageClass {
+		result.HasDefaultStoragePairs = true
+		result.DefaultStoragePairs.Copy = append(result.DefaultStoragePairs.Copy, WithStorageClass(result.DefaultStorageClass))
+		result.DefaultStoragePairs.CreateAppend = append(result.DefaultStoragePairs.CreateAppend, WithStorageClass(result.DefaultStorageClass))
+		result.DefaultStoragePairs.CreateDir = append(result.DefaultStoragePairs.CreateDir, WithStorageClass(result.DefaultStorageClass))
+		result.DefaultStoragePairs.CreateMultipart = append(result.DefaultStoragePairs.CreateMultipart, WithStorageClass(result.DefaultStorageClass))
+		result.DefaultStoragePairs.QuerySignHTTPWrite = append(result.DefaultStoragePairs.QuerySignHTTPWrite, WithStorageClass(result.DefaultStorageClass))
+		result.DefaultStoragePairs.Write = append(result.DefaultStoragePairs.Write, WithStorageClass(result.DefaultStorageClass))
+	}
 	if !result.HasName {
 		return pairStorageNew{}, services.PairRequiredError{Keys: []string{"name"}}
 	}
@@ -681,6 +1299,8 @@ type pairStorageCopy struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
+	HasContentDisposition                    bool
+	ContentDisposition                       string
 	HasCopySourceEncryptionCustomerAlgorithm bool
 	CopySourceEncryptionCustomerAlgorithm    string
 	HasCopySourceEncryptionCustomerKey       bool
@@ -689,6 +1309,8 @@ type pairStorageCopy struct {
 	EncryptionCustomerAlgorithm              string
 	HasEncryptionCustomerKey                 bool
 	EncryptionCustomerKey                    []byte
+	HasStorageClass                          bool
+	StorageClass                             string
 }
 
 func (s *Storage) parsePairStorageCopy(opts []Pair) (pairStorageCopy, error) {
@@ -697,6 +1319,12 @@ func (s *Storage) parsePairStorageCopy(opts []Pair) (pairStorageCopy, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "content_disposition":
+			if result.HasContentDisposition {
+				continue
+			}
+			result.HasContentDisposition = true
+			result.ContentDisposition = v.Value.(string)
 		case "copy_source_encryption_customer_algorithm":
 			if result.HasCopySourceEncryptionCustomerAlgorithm {
 				continue
@@ -721,6 +1349,12 @@ func (s *Storage) parsePairStorageCopy(opts []Pair) (pairStorageCopy, error) {
 			}
 			result.HasEncryptionCustomerKey = true
 			result.EncryptionCustomerKey = v.Value.([]byte)
+		case "storage_class":
+			if result.HasStorageClass {
+				continue
+			}
+			result.HasStorageClass = true
+			result.StorageClass = v.Value.(string)
 		default:
 			return pairStorageCopy{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -853,10 +1487,26 @@ type pairStorageCreateMultipart struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
+	HasCacheControl                bool
+	CacheControl                   string
+	HasContentDisposition          bool
+	ContentDisposition             string
+	HasContentEncoding             bool
+	ContentEncoding                string
+	HasContentSha256               bool
+	ContentSha256                  string
+	HasContentType                 bool
+	ContentType                    string
 	HasEncryptionCustomerAlgorithm bool
 	EncryptionCustomerAlgorithm    string
 	HasEncryptionCustomerKey       bool
 	EncryptionCustomerKey          []byte
+	HasExpires                     bool
+	Expires                        time.Time
+	HasStorageClass                bool
+	StorageClass                   string
+	HasUserMetadata                bool
+	UserMetadata                   map[string]string
 }
 
 func (s *Storage) parsePairStorageCreateMultipart(opts []Pair) (pairStorageCreateMultipart, error) {
@@ -865,6 +1515,36 @@ func (s *Storage) parsePairStorageCreateMultipart(opts []Pair) (pairStorageCreat
 
 	for _, v := range opts {
 		switch v.Key {
+		case "cache_control":
+			if result.HasCacheControl {
+				continue
+			}
+			result.HasCacheControl = true
+			result.CacheControl = v.Value.(string)
+		case "content_disposition":
+			if result.HasContentDisposition {
+				continue
+			}
+			result.HasContentDisposition = true
+			result.ContentDisposition = v.Value.(string)
+		case "content_encoding":
+			if result.HasContentEncoding {
+				continue
+			}
+			result.HasContentEncoding = true
+			result.ContentEncoding = v.Value.(string)
+		case "content_sha256":
+			if result.HasContentSha256 {
+				continue
+			}
+			result.HasContentSha256 = true
+			result.ContentSha256 = v.Value.(string)
+		case "content_type":
+			if result.HasContentType {
+				continue
+			}
+			result.HasContentType = true
+			result.ContentType = v.Value.(string)
 		case "encryption_customer_algorithm":
 			if result.HasEncryptionCustomerAlgorithm {
 				continue
@@ -877,6 +1557,24 @@ func (s *Storage) parsePairStorageCreateMultipart(opts []Pair) (pairStorageCreat
 			}
 			result.HasEncryptionCustomerKey = true
 			result.EncryptionCustomerKey = v.Value.([]byte)
+		case "expires":
+			if result.HasExpires {
+				continue
+			}
+			result.HasExpires = true
+			result.Expires = v.Value.(time.Time)
+		case "storage_class":
+			if result.HasStorageClass {
+				continue
+			}
+			result.HasStorageClass = true
+			result.StorageClass = v.Value.(string)
+		case "user_metadata":
+			if result.HasUserMetadata {
+				continue
+			}
+			result.HasUserMetadata = true
+			result.UserMetadata = v.Value.(map[string]string)
 		default:
 			return pairStorageCreateMultipart{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -945,8 +1643,34 @@ type pairStorageList struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasListMode bool
-	ListMode    ListMode
+	HasContinuationToken bool
+	ContinuationToken    string
+	HasIncludeMetadata   bool
+	IncludeMetadata      bool
+	HasListConcurrency   bool
+	ListConcurrency      int
+	HasListGlob          bool
+	ListGlob             string
+	HasListMode          bool
+	ListMode             ListMode
+	HasListRegexp        bool
+	ListRegexp           string
+	HasListRetry         bool
+	ListRetry            int
+	HasListSorted        bool
+	ListSorted           bool
+	HasMaxSize           bool
+	MaxSize              int64
+	HasMinSize           bool
+	MinSize              int64
+	HasModifiedAfter     bool
+	ModifiedAfter        time.Time
+	HasModifiedBefore    bool
+	ModifiedBefore       time.Time
+	HasPageSize          bool
+	PageSize             int
+	HasPrefixesOnly      bool
+	PrefixesOnly         bool
 }
 
 func (s *Storage) parsePairStorageList(opts []Pair) (pairStorageList, error) {
@@ -955,12 +1679,90 @@ func (s *Storage) parsePairStorageList(opts []Pair) (pairStorageList, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "continuation_token":
+			if result.HasContinuationToken {
+				continue
+			}
+			result.HasContinuationToken = true
+			result.ContinuationToken = v.Value.(string)
+		case "include_metadata":
+			if result.HasIncludeMetadata {
+				continue
+			}
+			result.HasIncludeMetadata = true
+			result.IncludeMetadata = v.Value.(bool)
+		case "list_concurrency":
+			if result.HasListConcurrency {
+				continue
+			}
+			result.HasListConcurrency = true
+			result.ListConcurrency = v.Value.(int)
+		case "list_glob":
+			if result.HasListGlob {
+				continue
+			}
+			result.HasListGlob = true
+			result.ListGlob = v.Value.(string)
 		case "list_mode":
 			if result.HasListMode {
 				continue
 			}
 			result.HasListMode = true
 			result.ListMode = v.Value.(ListMode)
+		case "list_regexp":
+			if result.HasListRegexp {
+				continue
+			}
+			result.HasListRegexp = true
+			result.ListRegexp = v.Value.(string)
+		case "list_retry":
+			if result.HasListRetry {
+				continue
+			}
+			result.HasListRetry = true
+			result.ListRetry = v.Value.(int)
+		case "list_sorted":
+			if result.HasListSorted {
+				continue
+			}
+			result.HasListSorted = true
+			result.ListSorted = v.Value.(bool)
+		case "max_size":
+			if result.HasMaxSize {
+				continue
+			}
+			result.HasMaxSize = true
+			result.MaxSize = v.Value.(int64)
+		case "min_size":
+			if result.HasMinSize {
+				continue
+			}
+			result.HasMinSize = true
+			result.MinSize = v.Value.(int64)
+		case "modified_after":
+			if result.HasModifiedAfter {
+				continue
+			}
+			result.HasModifiedAfter = true
+			result.ModifiedAfter = v.Value.(time.Time)
+		case "modified_before":
+			if result.HasModifiedBefore {
+				continue
+			}
+			result.HasModifiedBefore = true
+			result.ModifiedBefore = v.Value.(time.Time)
+		case "page_size":
+			if result.HasPageSize {
+				continue
+			}
+			result.HasPageSize = true
+			result.PageSize = v.Value.(int)
+		case "prefixes_only":
+			if result.HasPrefixesOnly {
+				continue
+			}
+			result.HasPrefixesOnly = true
+			result.PrefixesOnly = v.Value.(bool)
 		default:
 			return pairStorageList{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -973,6 +1775,10 @@ type pairStorageListMultipart struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
+	HasContinuationToken bool
+	ContinuationToken    string
+	HasPageSize          bool
+	PageSize             int
 }
 
 func (s *Storage) parsePairStorageListMultipart(opts []Pair) (pairStorageListMultipart, error) {
@@ -981,6 +1787,18 @@ func (s *Storage) parsePairStorageListMultipart(opts []Pair) (pairStorageListMul
 
 	for _, v := range opts {
 		switch v.Key {
+		case "continuation_token":
+			if result.HasContinuationToken {
+				continue
+			}
+			result.HasContinuationToken = true
+			result.ContinuationToken = v.Value.(string)
+		case "page_size":
+			if result.HasPageSize {
+				continue
+			}
+			result.HasPageSize = true
+			result.PageSize = v.Value.(int)
 		default:
 			return pairStorageListMultipart{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -993,6 +1811,8 @@ type pairStorageMetadata struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
+	HasStatistics bool
+	Statistics    bool
 }
 
 func (s *Storage) parsePairStorageMetadata(opts []Pair) (pairStorageMetadata, error) {
@@ -1001,6 +1821,12 @@ func (s *Storage) parsePairStorageMetadata(opts []Pair) (pairStorageMetadata, er
 
 	for _, v := range opts {
 		switch v.Key {
+		case "statistics":
+			if result.HasStatistics {
+				continue
+			}
+			result.HasStatistics = true
+			result.Statistics = v.Value.(bool)
 		default:
 			return pairStorageMetadata{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -1167,6 +1993,8 @@ type pairStorageReach struct {
 	HasExpire bool
 	Expire    time.Duration
 	// Optional pairs
+	HasImageProcess bool
+	ImageProcess    []ImageAction
 }
 
 func (s *Storage) parsePairStorageReach(opts []Pair) (pairStorageReach, error) {
@@ -1181,6 +2009,12 @@ func (s *Storage) parsePairStorageReach(opts []Pair) (pairStorageReach, error) {
 			}
 			result.HasExpire = true
 			result.Expire = v.Value.(time.Duration)
+		case "image_process":
+			if result.HasImageProcess {
+				continue
+			}
+			result.HasImageProcess = true
+			result.ImageProcess = v.Value.([]ImageAction)
 		default:
 			return pairStorageReach{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -1195,16 +2029,50 @@ type pairStorageRead struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
+	HasCompression                 bool
+	Compression                    string
+	HasCopyBufferSize              bool
+	CopyBufferSize                 int
+	HasDownloadConcurrency         bool
+	DownloadConcurrency            int
+	HasDownloadPartSize            bool
+	DownloadPartSize               int64
 	HasEncryptionCustomerAlgorithm bool
 	EncryptionCustomerAlgorithm    string
 	HasEncryptionCustomerKey       bool
 	EncryptionCustomerKey          []byte
+	HasIfMatch                     bool
+	IfMatch                        string
+	HasIfModifiedSince             bool
+	IfModifiedSince                time.Time
+	HasIfNoneMatch                 bool
+	IfNoneMatch                    string
+	HasImageProcess                bool
+	ImageProcess                   []ImageAction
 	HasIoCallback                  bool
 	IoCallback                     func([]byte)
 	HasOffset                      bool
 	Offset                         int64
+	HasReadRateLimit               bool
+	ReadRateLimit                  int64
+	HasReadRetry                   bool
+	ReadRetry                      int
+	HasReaderBlockCache            bool
+	ReaderBlockCache               int
+	HasReaderBlockSize             bool
+	ReaderBlockSize                int64
+	HasReaderReadAhead             bool
+	ReaderReadAhead                int
 	HasSize                        bool
 	Size                           int64
+	HasSuffixSize                  bool
+	SuffixSize                     int64
+	HasTransferCallback            bool
+	TransferCallback               TransferCallback
+	HasVerifyEtag                  bool
+	VerifyEtag                     bool
+	HasVerifySha256                bool
+	VerifySha256                   bool
 }
 
 func (s *Storage) parsePairStorageRead(opts []Pair) (pairStorageRead, error) {
@@ -1213,6 +2081,30 @@ func (s *Storage) parsePairStorageRead(opts []Pair) (pairStorageRead, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "compression":
+			if result.HasCompression {
+				continue
+			}
+			result.HasCompression = true
+			result.Compression = v.Value.(string)
+		case "copy_buffer_size":
+			if result.HasCopyBufferSize {
+				continue
+			}
+			result.HasCopyBufferSize = true
+			result.CopyBufferSize = v.Value.(int)
+		case "download_concurrency":
+			if result.HasDownloadConcurrency {
+				continue
+			}
+			result.HasDownloadConcurrency = true
+			result.DownloadConcurrency = v.Value.(int)
+		case "download_part_size":
+			if result.HasDownloadPartSize {
+				continue
+			}
+			result.HasDownloadPartSize = true
+			result.DownloadPartSize = v.Value.(int64)
 		case "encryption_customer_algorithm":
 			if result.HasEncryptionCustomerAlgorithm {
 				continue
@@ -1225,6 +2117,30 @@ func (s *Storage) parsePairStorageRead(opts []Pair) (pairStorageRead, error) {
 			}
 			result.HasEncryptionCustomerKey = true
 			result.EncryptionCustomerKey = v.Value.([]byte)
+		case "if_match":
+			if result.HasIfMatch {
+				continue
+			}
+			result.HasIfMatch = true
+			result.IfMatch = v.Value.(string)
+		case "if_modified_since":
+			if result.HasIfModifiedSince {
+				continue
+			}
+			result.HasIfModifiedSince = true
+			result.IfModifiedSince = v.Value.(time.Time)
+		case "if_none_match":
+			if result.HasIfNoneMatch {
+				continue
+			}
+			result.HasIfNoneMatch = true
+			result.IfNoneMatch = v.Value.(string)
+		case "image_process":
+			if result.HasImageProcess {
+				continue
+			}
+			result.HasImageProcess = true
+			result.ImageProcess = v.Value.([]ImageAction)
 		case "io_callback":
 			if result.HasIoCallback {
 				continue
@@ -1237,12 +2153,66 @@ func (s *Storage) parsePairStorageRead(opts []Pair) (pairStorageRead, error) {
 			}
 			result.HasOffset = true
 			result.Offset = v.Value.(int64)
+		case "read_rate_limit":
+			if result.HasReadRateLimit {
+				continue
+			}
+			result.HasReadRateLimit = true
+			result.ReadRateLimit = v.Value.(int64)
+		case "read_retry":
+			if result.HasReadRetry {
+				continue
+			}
+			result.HasReadRetry = true
+			result.ReadRetry = v.Value.(int)
+		case "reader_block_cache":
+			if result.HasReaderBlockCache {
+				continue
+			}
+			result.HasReaderBlockCache = true
+			result.ReaderBlockCache = v.Value.(int)
+		case "reader_block_size":
+			if result.HasReaderBlockSize {
+				continue
+			}
+			result.HasReaderBlockSize = true
+			result.ReaderBlockSize = v.Value.(int64)
+		case "reader_read_ahead":
+			if result.HasReaderReadAhead {
+				continue
+			}
+			result.HasReaderReadAhead = true
+			result.ReaderReadAhead = v.Value.(int)
 		case "size":
 			if result.HasSize {
 				continue
 			}
 			result.HasSize = true
 			result.Size = v.Value.(int64)
+		case "suffix_size":
+			if result.HasSuffixSize {
+				continue
+			}
+			result.HasSuffixSize = true
+			result.SuffixSize = v.Value.(int64)
+		case "transfer_callback":
+			if result.HasTransferCallback {
+				continue
+			}
+			result.HasTransferCallback = true
+			result.TransferCallback = v.Value.(TransferCallback)
+		case "verify_etag":
+			if result.HasVerifyEtag {
+				continue
+			}
+			result.HasVerifyEtag = true
+			result.VerifyEtag = v.Value.(bool)
+		case "verify_sha256":
+			if result.HasVerifySha256 {
+				continue
+			}
+			result.HasVerifySha256 = true
+			result.VerifySha256 = v.Value.(bool)
 		default:
 			return pairStorageRead{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -1255,10 +2225,16 @@ type pairStorageStat struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
-	HasMultipartID bool
-	MultipartID    string
-	HasObjectMode  bool
-	ObjectMode     ObjectMode
+	HasEncryptionCustomerAlgorithm bool
+	EncryptionCustomerAlgorithm    string
+	HasEncryptionCustomerKey       bool
+	EncryptionCustomerKey          []byte
+	HasImplicitDir                 bool
+	ImplicitDir                    bool
+	HasMultipartID                 bool
+	MultipartID                    string
+	HasObjectMode                  bool
+	ObjectMode                     ObjectMode
 }
 
 func (s *Storage) parsePairStorageStat(opts []Pair) (pairStorageStat, error) {
@@ -1267,6 +2243,24 @@ func (s *Storage) parsePairStorageStat(opts []Pair) (pairStorageStat, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "encryption_customer_algorithm":
+			if result.HasEncryptionCustomerAlgorithm {
+				continue
+			}
+			result.HasEncryptionCustomerAlgorithm = true
+			result.EncryptionCustomerAlgorithm = v.Value.(string)
+		case "encryption_customer_key":
+			if result.HasEncryptionCustomerKey {
+				continue
+			}
+			result.HasEncryptionCustomerKey = true
+			result.EncryptionCustomerKey = v.Value.([]byte)
+		case "implicit_dir":
+			if result.HasImplicitDir {
+				continue
+			}
+			result.HasImplicitDir = true
+			result.ImplicitDir = v.Value.(bool)
 		case "multipart_id":
 			if result.HasMultipartID {
 				continue
@@ -1291,18 +2285,64 @@ type pairStorageWrite struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
+	HasAutoContentMd5              bool
+	AutoContentMd5                 bool
+	HasAutoContentSha256           bool
+	AutoContentSha256              bool
+	HasCacheControl                bool
+	CacheControl                   string
+	HasCompression                 bool
+	Compression                    string
+	HasContentDisposition          bool
+	ContentDisposition             string
+	HasContentEncoding             bool
+	ContentEncoding                string
 	HasContentMd5                  bool
 	ContentMd5                     string
+	HasContentSha256               bool
+	ContentSha256                  string
 	HasContentType                 bool
 	ContentType                    string
+	HasCopyBufferSize              bool
+	CopyBufferSize                 int
+	HasDetectContentType           bool
+	DetectContentType              bool
 	HasEncryptionCustomerAlgorithm bool
 	EncryptionCustomerAlgorithm    string
 	HasEncryptionCustomerKey       bool
 	EncryptionCustomerKey          []byte
+	HasExpires                     bool
+	Expires                        time.Time
+	HasIfNoneMatch                 bool
+	IfNoneMatch                    string
 	HasIoCallback                  bool
 	IoCallback                     func([]byte)
+	HasMultipartConcurrency        bool
+	MultipartConcurrency           int
+	HasMultipartPartSize           bool
+	MultipartPartSize              int64
+	HasMultipartProgress           bool
+	MultipartProgress              MultipartProgressCallback
+	HasMultipartRetry              bool
+	MultipartRetry                 int
+	HasMultipartThreshold          bool
+	MultipartThreshold             int64
+	HasObjectMode                  bool
+	ObjectMode                     ObjectMode
 	HasStorageClass                bool
 	StorageClass                   string
+	HasTransferCallback            bool
+	TransferCallback               TransferCallback
+	HasUploadConcurrency           bool
+	UploadConcurrency              int
+	HasUserMetadata                bool
+	UserMetadata                   map[string]string
+	HasVerifyEtag                  bool
+	VerifyEtag                     bool
+	HasWriteRateLimit              bool
+	WriteRateLimit                 int64
+	HasWriteRetry                  bool
+	WriteRetry                     int
 }
 
 func (s *Storage) parsePairStorageWrite(opts []Pair) (pairStorageWrite, error) {
@@ -1311,18 +2351,72 @@ func (s *Storage) parsePairStorageWrite(opts []Pair) (pairStorageWrite, error) {
 
 	for _, v := range opts {
 		switch v.Key {
+		case "auto_content_md5":
+			if result.HasAutoContentMd5 {
+				continue
+			}
+			result.HasAutoContentMd5 = true
+			result.AutoContentMd5 = v.Value.(bool)
+		case "auto_content_sha256":
+			if result.HasAutoContentSha256 {
+				continue
+			}
+			result.HasAutoContentSha256 = true
+			result.AutoContentSha256 = v.Value.(bool)
+		case "cache_control":
+			if result.HasCacheControl {
+				continue
+			}
+			result.HasCacheControl = true
+			result.CacheControl = v.Value.(string)
+		case "compression":
+			if result.HasCompression {
+				continue
+			}
+			result.HasCompression = true
+			result.Compression = v.Value.(string)
+		case "content_disposition":
+			if result.HasContentDisposition {
+				continue
+			}
+			result.HasContentDisposition = true
+			result.ContentDisposition = v.Value.(string)
+		case "content_encoding":
+			if result.HasContentEncoding {
+				continue
+			}
+			result.HasContentEncoding = true
+			result.ContentEncoding = v.Value.(string)
 		case "content_md5":
 			if result.HasContentMd5 {
 				continue
 			}
 			result.HasContentMd5 = true
 			result.ContentMd5 = v.Value.(string)
+		case "content_sha256":
+			if result.HasContentSha256 {
+				continue
+			}
+			result.HasContentSha256 = true
+			result.ContentSha256 = v.Value.(string)
 		case "content_type":
 			if result.HasContentType {
 				continue
 			}
 			result.HasContentType = true
 			result.ContentType = v.Value.(string)
+		case "copy_buffer_size":
+			if result.HasCopyBufferSize {
+				continue
+			}
+			result.HasCopyBufferSize = true
+			result.CopyBufferSize = v.Value.(int)
+		case "detect_content_type":
+			if result.HasDetectContentType {
+				continue
+			}
+			result.HasDetectContentType = true
+			result.DetectContentType = v.Value.(bool)
 		case "encryption_customer_algorithm":
 			if result.HasEncryptionCustomerAlgorithm {
 				continue
@@ -1335,18 +2429,102 @@ func (s *Storage) parsePairStorageWrite(opts []Pair) (pairStorageWrite, error) {
 			}
 			result.HasEncryptionCustomerKey = true
 			result.EncryptionCustomerKey = v.Value.([]byte)
+		case "expires":
+			if result.HasExpires {
+				continue
+			}
+			result.HasExpires = true
+			result.Expires = v.Value.(time.Time)
+		case "if_none_match":
+			if result.HasIfNoneMatch {
+				continue
+			}
+			result.HasIfNoneMatch = true
+			result.IfNoneMatch = v.Value.(string)
 		case "io_callback":
 			if result.HasIoCallback {
 				continue
 			}
 			result.HasIoCallback = true
 			result.IoCallback = v.Value.(func([]byte))
+		case "multipart_concurrency":
+			if result.HasMultipartConcurrency {
+				continue
+			}
+			result.HasMultipartConcurrency = true
+			result.MultipartConcurrency = v.Value.(int)
+		case "multipart_part_size":
+			if result.HasMultipartPartSize {
+				continue
+			}
+			result.HasMultipartPartSize = true
+			result.MultipartPartSize = v.Value.(int64)
+		case "multipart_progress":
+			if result.HasMultipartProgress {
+				continue
+			}
+			result.HasMultipartProgress = true
+			result.MultipartProgress = v.Value.(MultipartProgressCallback)
+		case "multipart_retry":
+			if result.HasMultipartRetry {
+				continue
+			}
+			result.HasMultipartRetry = true
+			result.MultipartRetry = v.Value.(int)
+		case "multipart_threshold":
+			if result.HasMultipartThreshold {
+				continue
+			}
+			result.HasMultipartThreshold = true
+			result.MultipartThreshold = v.Value.(int64)
+		case "object_mode":
+			if result.HasObjectMode {
+				continue
+			}
+			result.HasObjectMode = true
+			result.ObjectMode = v.Value.(ObjectMode)
 		case "storage_class":
 			if result.HasStorageClass {
 				continue
 			}
 			result.HasStorageClass = true
 			result.StorageClass = v.Value.(string)
+		case "transfer_callback":
+			if result.HasTransferCallback {
+				continue
+			}
+			result.HasTransferCallback = true
+			result.TransferCallback = v.Value.(TransferCallback)
+		case "upload_concurrency":
+			if result.HasUploadConcurrency {
+				continue
+			}
+			result.HasUploadConcurrency = true
+			result.UploadConcurrency = v.Value.(int)
+		case "user_metadata":
+			if result.HasUserMetadata {
+				continue
+			}
+			result.HasUserMetadata = true
+			result.UserMetadata = v.Value.(map[string]string)
+		case "verify_etag":
+			if result.HasVerifyEtag {
+				continue
+			}
+			result.HasVerifyEtag = true
+			result.VerifyEtag = v.Value.(bool)
+		case "write_rate_limit":
+			if result.HasWriteRateLimit {
+				continue
+			}
+			result.HasWriteRateLimit = true
+			result.WriteRateLimit = v.Value.(int64)
+		case "write_retry":
+			if result.HasWriteRetry {
+				continue
+			}
+			result.HasWriteRetry = true
+			result.WriteRetry = v.Value.(int)
 		default:
 			return pairStorageWrite{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -1387,12 +2565,18 @@ type pairStorageWriteMultipart struct {
 	pairs []Pair
 	// Required pairs
 	// Optional pairs
+	HasAutoContentMd5              bool
+	AutoContentMd5                 bool
+	HasContentMd5                  bool
+	ContentMd5                     string
 	HasEncryptionCustomerAlgorithm bool
 	EncryptionCustomerAlgorithm    string
 	HasEncryptionCustomerKey       bool
 	EncryptionCustomerKey          []byte
 	HasIoCallback                  bool
 	IoCallback                     func([]byte)
+	HasWriteRateLimit              bool
+	WriteRateLimit                 int64
 }
 
 func (s *Storage) parsePairStorageWriteMultipart(opts []Pair) (pairStorageWriteMultipart, error) {
@@ -1401,6 +2585,18 @@ func (s *Storage) parsePairStorageWriteMultipart(opts []Pair) (pairStorageWriteM
 
 	for _, v := range opts {
 		switch v.Key {
+		case "auto_content_md5":
+			if result.HasAutoContentMd5 {
+				continue
+			}
+			result.HasAutoContentMd5 = true
+			result.AutoContentMd5 = v.Value.(bool)
+		case "content_md5":
+			if result.HasContentMd5 {
+				continue
+			}
+			result.HasContentMd5 = true
+			result.ContentMd5 = v.Value.(string)
 		case "encryption_customer_algorithm":
 			if result.HasEncryptionCustomerAlgorithm {
 				continue
@@ -1419,6 +2615,12 @@ func (s *Storage) parsePairStorageWriteMultipart(opts []Pair) (pairStorageWriteM
 			}
 			result.HasIoCallback = true
 			result.IoCallback = v.Value.(func([]byte))
+		case "write_rate_limit":
+			if result.HasWriteRateLimit {
+				continue
+			}
+			result.HasWriteRateLimit = true
+			result.WriteRateLimit = v.Value.(int64)
 		default:
 			return pairStorageWriteMultipart{}, services.PairUnsupportedError{Pair: v}
 		}
@@ -0,0 +1,194 @@
+package qingstor
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/pkg/iowrap"
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// ImageAction is an action of QingStor image processing, actions will be applied in order.
+//
+// Refer to the image processing API of QingStor for the details of every action.
+type ImageAction struct {
+	name   string
+	params []string
+}
+
+// String will format the action like "resize:w_100,h_100".
+func (a ImageAction) String() string {
+	if len(a.params) == 0 {
+		return a.name
+	}
+	return a.name + ":" + strings.Join(a.params, ",")
+}
+
+// ImageInfo will return the info of image in JSON, like width, height and type.
+func ImageInfo() ImageAction {
+	return ImageAction{name: "info"}
+}
+
+// ImageCrop will crop the image to width and height, gravity specifies the position
+// to crop from, like 0 for center, 1 for north and 5 for auto.
+func ImageCrop(width, height, gravity int) ImageAction {
+	return ImageAction{name: "crop", params: []string{
+		fmt.Sprintf("w_%d", width),
+		fmt.Sprintf("h_%d", height),
+		fmt.Sprintf("g_%d", gravity),
+	}}
+}
+
+// ImageRotate will rotate the image clockwise by angle.
+func ImageRotate(angle int) ImageAction {
+	return ImageAction{name: "rotate", params: []string{fmt.Sprintf("a_%d", angle)}}
+}
+
+// ImageResize will resize the image to width and height, mode 0 resizes to the fixed size,
+// 1 keeps the aspect ratio and resizes to the larger side, 2 keeps the aspect ratio and
+// resizes to the smaller side. Zero width or height will be computed from the aspect ratio.
+func ImageResize(width, height, mode int) ImageAction {
+	var params []string
+	if width > 0 {
+		params = append(params, fmt.Sprintf("w_%d", width))
+	}
+	if height > 0 {
+		params = append(params, fmt.Sprintf("h_%d", height))
+	}
+	params = append(params, fmt.Sprintf("m_%d", mode))
+	return ImageAction{name: "resize", params: params}
+}
+
+// ImageWatermark will add text watermark with dpi, opacity in [0, 1] and color like "#ff0000".
+func ImageWatermark(text string, dpi int, opacity float64, color string) ImageAction {
+	return ImageAction{name: "watermark", params: []string{
+		fmt.Sprintf("d_%d", dpi),
+		fmt.Sprintf("p_%.2f", opacity),
+		"t_" + base64.URLEncoding.EncodeToString([]byte(text)),
+		"c_" + base64.URLEncoding.EncodeToString([]byte(color)),
+	}}
+}
+
+// ImageFormat will convert the image into format, like "jpeg", "png" or "webp".
+func ImageFormat(format string) ImageAction {
+	return ImageAction{name: "format", params: []string{"t_" + format}}
+}
+
+// formatImageAction will join actions into the action query of image processing.
+func formatImageAction(actions []ImageAction) string {
+	v := make([]string, 0, len(actions))
+	for _, a := range actions {
+		v = append(v, a.String())
+	}
+	return strings.Join(v, "|")
+}
+
+// openImageReader will send the image processing request, and returns the processed image.
+func (s *Storage) openImageReader(ctx context.Context, path string, opt pairStorageRead) (rc io.ReadCloser, o *Object, err error) {
+	// Processed image is generated on the fly, so it could not be read partially, decrypted
+	// or verified.
+	switch {
+	case len(opt.ImageProcess) == 0:
+		err = services.PairUnsupportedError{Pair: WithImageProcess(opt.ImageProcess)}
+	case opt.HasOffset:
+		err = services.PairUnsupportedError{Pair: ps.WithOffset(opt.Offset)}
+	case opt.HasSize:
+		err = services.PairUnsupportedError{Pair: ps.WithSize(opt.Size)}
+	case opt.HasSuffixSize:
+		err = services.PairUnsupportedError{Pair: WithSuffixSize(opt.SuffixSize)}
+	case opt.HasIfMatch:
+		err = services.PairUnsupportedError{Pair: WithIfMatch(opt.IfMatch)}
+	case opt.HasIfNoneMatch:
+		err = services.PairUnsupportedError{Pair: WithIfNoneMatch(opt.IfNoneMatch)}
+	case opt.HasEncryptionCustomerAlgorithm:
+		err = services.PairUnsupportedError{Pair: WithEncryptionCustomerAlgorithm(opt.EncryptionCustomerAlgorithm)}
+	case opt.HasCompression:
+		err = services.PairUnsupportedError{Pair: WithCompression(opt.Compression)}
+	case opt.HasVerifySha256:
+		err = services.PairUnsupportedError{Pair: WithVerifySha256()}
+	case opt.HasVerifyEtag:
+		err = services.PairUnsupportedError{Pair: WithVerifyEtag()}
+	case s.keyProvider != nil:
+		err = services.ErrCapabilityInsufficient
+	}
+	if err != nil {
+		return
+	}
+
+	input := &service.ImageProcessInput{
+		Action: service.String(formatImageAction(opt.ImageProcess)),
+	}
+	if opt.HasIfModifiedSince {
+		input.IfModifiedSince = service.Time(opt.IfModifiedSince)
+	}
+
+	rp := s.getAbsPath(path)
+
+	output, err := s.bucket.ImageProcessWithContext(ctx, rp, input)
+	if err != nil {
+		return
+	}
+	// Conditional requests are treated as succeeded by sdk, so we need to check status code here.
+	if service.IntValue(output.StatusCode) == http.StatusNotModified {
+		if output.Body != nil {
+			_ = output.Body.Close()
+		}
+		return nil, nil, ErrObjectNotModified
+	}
+
+	or := &objectReader{
+		r:       output.Body,
+		closers: []io.Closer{output.Body},
+	}
+	if opt.HasReadRateLimit {
+		or.wrap(newRateLimitReader(ctx, or.r, opt.ReadRateLimit))
+	}
+	if opt.HasIoCallback {
+		or.wrap(iowrap.CallbackReader(or.r, opt.IoCallback))
+	}
+	if opt.HasTransferCallback {
+		or.wrap(&transferReader{r: or.r, m: newTransferMeter(opt.TransferCallback, service.Int64Value(output.ContentLength))})
+	}
+
+	o = s.newObject(true)
+	o.ID = rp
+	o.Path = path
+	o.Mode |= ModeRead
+	o.SetContentLength(service.Int64Value(output.ContentLength))
+	return or, o, nil
+}
+
+// reachImage will generate a signed url of the processed image.
+func (s *Storage) reachImage(ctx context.Context, path string, opt pairStorageReach) (url string, err error) {
+	if len(opt.ImageProcess) == 0 {
+		err = services.PairUnsupportedError{Pair: WithImageProcess(opt.ImageProcess)}
+		return
+	}
+
+	bucket := s.bucket.(*service.Bucket)
+
+	rp := s.getAbsPath(path)
+
+	r, _, err := bucket.ImageProcessRequest(rp, &service.ImageProcessInput{
+		Action: service.String(formatImageAction(opt.ImageProcess)),
+	})
+	if err != nil {
+		return
+	}
+	if err = r.BuildWithContext(ctx); err != nil {
+		return
+	}
+
+	if err = r.SignQuery(int(opt.Expire.Seconds())); err != nil {
+		return
+	}
+	return r.HTTPRequest.URL.String(), nil
+}
@@ -10,6 +10,8 @@ type objectPageStatus struct {
 	marker       string
 	prefix       string
 	partIdMarker string
+	// prefixesOnly will skip keys and only return common prefixes.
+	prefixesOnly bool
 }
 
 func (i *objectPageStatus) ContinuationToken() string {
@@ -20,9 +22,10 @@ func (i *objectPageStatus) ContinuationToken() string {
 }
 
 type storagePageStatus struct {
-	limit    int
-	offset   int
-	location string
+	limit     int
+	offset    int
+	location  string
+	locations []string
 }
 
 func (i *storagePageStatus) ContinuationToken() string {
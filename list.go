@@ -0,0 +1,554 @@
+package qingstor
+
+import (
+	"context"
+	"errors"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// listConcurrencyDefault is the default number of sub-prefixes listed at the same time.
+const listConcurrencyDefault = 8
+
+// ListAll will list objects under path and return at most limit objects.
+func (s *Storage) ListAll(path string, limit int, pairs ...Pair) (objects []*Object, err error) {
+	ctx := context.Background()
+	return s.ListAllWithContext(ctx, path, limit, pairs...)
+}
+
+// ListAllWithContext will list objects under path and return at most limit objects.
+//
+// Pairs for List are supported, and limit <= 0 means all objects will be returned. Pages will
+// be fetched until limit reached, so page_size larger than limit is not necessary.
+func (s *Storage) ListAllWithContext(ctx context.Context, path string, limit int, pairs ...Pair) (objects []*Object, err error) {
+	it, err := s.ListWithContext(ctx, path, pairs...)
+	if err != nil {
+		return
+	}
+
+	for limit <= 0 || len(objects) < limit {
+		o, err := it.Next()
+		if err != nil {
+			if errors.Is(err, IterateDone) {
+				break
+			}
+			return nil, s.formatError("list_all", err, path)
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+// ListChan will list objects under path and send them to the returned channel.
+func (s *Storage) ListChan(path string, pairs ...Pair) (<-chan *Object, <-chan error) {
+	ctx := context.Background()
+	return s.ListChanWithContext(ctx, path, pairs...)
+}
+
+// ListChanWithContext will list objects under path and send them to the returned channel.
+//
+// Pages will be fetched in background with at most one page buffered in the object channel,
+// which will be closed after all objects sent or listing failed. The error channel will be
+// closed after the object channel, and receives the error if listing failed. Pairs for List
+// are supported, and listing will be stopped after ctx canceled, please cancel ctx if the
+// object channel is not drained.
+func (s *Storage) ListChanWithContext(ctx context.Context, path string, pairs ...Pair) (<-chan *Object, <-chan error) {
+	// Invalid pairs will be reported by List in background.
+	size := 200
+	if opt, err := s.parsePairStorageList(append(pairs, s.defaultPairs.List...)); err == nil &&
+		opt.HasPageSize && opt.PageSize > 0 {
+		size = opt.PageSize
+	}
+
+	objects := make(chan *Object, size)
+	errc := make(chan error, 1)
+
+	go func() {
+		var err error
+		defer func() {
+			close(objects)
+			if err != nil {
+				errc <- err
+			}
+			close(errc)
+		}()
+
+		it, err := s.ListWithContext(ctx, path, pairs...)
+		if err != nil {
+			return
+		}
+		defer func() {
+			err = s.formatError("list_chan", err, path)
+		}()
+		for {
+			var o *Object
+			o, err = it.Next()
+			if err != nil {
+				if errors.Is(err, IterateDone) {
+					err = nil
+				}
+				return
+			}
+
+			select {
+			case objects <- o:
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return objects, errc
+}
+
+// ListConcurrentWithContext will list all objects under path like ListModePrefix, with sub-prefixes
+// listed concurrently.
+//
+// Keys directly under path will be listed with delimiter "/" first, and every common prefix found
+// will be listed by prefix in list_concurrency goroutines. Objects will be returned in the order
+// they are fetched instead of the key order, and the listing could not be resumed by continuation
+// token.
+//
+// Pairs for List are supported except list_mode, continuation_token, list_sorted and
+// prefixes_only.
+//
+// Prefixes are listed in background goroutines which are blocked until pages consumed, so
+// caller must cancel ctx after done with the iterator, or these goroutines will be leaked if
+// the iterator is not consumed till IterateDone. There is no variant without context for the
+// same reason.
+func (s *Storage) ListConcurrentWithContext(ctx context.Context, path string, pairs ...Pair) (oi *ObjectIterator, err error) {
+	// Size of content encrypted by client is only known after stated.
+	return s.listConcurrentWithContext(ctx, path, s.keyProvider != nil, pairs...)
+}
+
+// listConcurrentWithContext will list objects like ListConcurrentWithContext, listed objects will
+// be stated if stat is true or include_metadata is set.
+func (s *Storage) listConcurrentWithContext(ctx context.Context, path string, stat bool, pairs ...Pair) (oi *ObjectIterator, err error) {
+	defer func() {
+		err = s.formatError("list_concurrent", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.List...)
+	opt, err := s.parsePairStorageList(pairs)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case opt.HasListMode && !opt.ListMode.IsPrefix():
+		return nil, services.PairUnsupportedError{Pair: ps.WithListMode(opt.ListMode)}
+	case opt.HasContinuationToken:
+		return nil, services.PairUnsupportedError{Pair: ps.WithContinuationToken(opt.ContinuationToken)}
+	case opt.HasListSorted && opt.ListSorted:
+		return nil, services.PairUnsupportedError{Pair: WithListSorted()}
+	case opt.HasPrefixesOnly && opt.PrefixesOnly:
+		return nil, services.PairUnsupportedError{Pair: WithPrefixesOnly()}
+	case opt.HasPageSize && opt.PageSize <= 0:
+		return nil, services.PairUnsupportedError{Pair: WithPageSize(opt.PageSize)}
+	case opt.HasListConcurrency && opt.ListConcurrency <= 0:
+		return nil, services.PairUnsupportedError{Pair: WithListConcurrency(opt.ListConcurrency)}
+	}
+
+	keep, err := listFilter(opt)
+	if err != nil {
+		return
+	}
+
+	limit := 200
+	if opt.HasPageSize {
+		limit = opt.PageSize
+	}
+	concurrency := listConcurrencyDefault
+	if opt.HasListConcurrency {
+		concurrency = opt.ListConcurrency
+	}
+	retry := 0
+	if opt.HasListRetry {
+		retry = opt.ListRetry
+	}
+
+	input := &concurrentPageStatus{
+		pages: make(chan []*Object, concurrency),
+	}
+	go s.listConcurrent(ctx, s.getAbsPath(strings.ReplaceAll(path, "\\", "/")), limit, concurrency, retry, input)
+
+	nextFn := filterObjectPage(s.nextConcurrentPage, keep)
+	if stat || (opt.HasIncludeMetadata && opt.IncludeMetadata) {
+		nextFn = s.statObjectPage(nextFn, concurrency)
+	}
+	return NewObjectIterator(ctx, nextFn, input), nil
+}
+
+// concurrentPageStatus is the status of ListConcurrent, pages will be closed after all prefixes
+// have been listed.
+type concurrentPageStatus struct {
+	pages chan []*Object
+	// err will be set before pages closed.
+	err error
+}
+
+// ContinuationToken is always empty, because objects are not returned in order.
+func (i *concurrentPageStatus) ContinuationToken() string {
+	return ""
+}
+
+func (s *Storage) nextConcurrentPage(ctx context.Context, page *ObjectPage) error {
+	input := page.Status.(*concurrentPageStatus)
+
+	select {
+	case objects, ok := <-input.pages:
+		if !ok {
+			if input.err != nil {
+				return input.err
+			}
+			return IterateDone
+		}
+		page.Data = append(page.Data, objects...)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// listConcurrent will list keys under prefix by dir, and list common prefixes concurrently.
+func (s *Storage) listConcurrent(ctx context.Context, prefix string, limit, concurrency, retry int, input *concurrentPageStatus) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+	setErr := func(e error) {
+		once.Do(func() {
+			firstErr = e
+			cancel()
+		})
+	}
+	nextByDir := retryObjectPage(s.nextObjectPageByDir, retry)
+	nextByPrefix := retryObjectPage(s.nextObjectPageByPrefix, retry)
+
+	// send returns false if listing has been stopped.
+	send := func(objects []*Object) bool {
+		// Empty page will be treated as the end by iterator.
+		if len(objects) == 0 {
+			return true
+		}
+		select {
+		case input.pages <- objects:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	list := func(prefix string) {
+		page := &ObjectPage{Status: &objectPageStatus{limit: limit, prefix: prefix}}
+		for {
+			page.Data = nil
+			err := nextByPrefix(ctx, page)
+			if err != nil && err != IterateDone {
+				setErr(err)
+				return
+			}
+			if !send(page.Data) || err == IterateDone {
+				return
+			}
+		}
+	}
+
+	page := &ObjectPage{Status: &objectPageStatus{delimiter: "/", limit: limit, prefix: prefix}}
+	for done := false; !done && ctx.Err() == nil; {
+		page.Data = nil
+		err := nextByDir(ctx, page)
+		if err != nil && err != IterateDone {
+			setErr(err)
+			break
+		}
+		done = err == IterateDone
+
+		var files []*Object
+		for _, o := range page.Data {
+			// Common prefixes always end with the delimiter.
+			if !o.Mode.IsDir() || !strings.HasSuffix(o.ID, "/") {
+				files = append(files, o)
+				continue
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+
+			wg.Add(1)
+			go func(prefix string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				list(prefix)
+			}(o.ID)
+		}
+		if !send(files) {
+			break
+		}
+	}
+	wg.Wait()
+
+	// Listing is stopped by the caller if ctx is canceled without errors.
+	input.err = firstErr
+	close(input.pages)
+}
+
+// listFilter will build the filter of listed objects from pairs, nil means all objects are kept.
+//
+// Filters by mtime and size are not applied to directories, so that they could still be
+// walked into.
+func listFilter(opt pairStorageList) (keep func(o *Object) bool, err error) {
+	var filters []func(o *Object) bool
+
+	if opt.HasListGlob {
+		// Check the pattern here, path.Match only reports ErrBadPattern while matching.
+		if _, err = path.Match(opt.ListGlob, ""); err != nil {
+			return nil, services.PairUnsupportedError{Pair: WithListGlob(opt.ListGlob)}
+		}
+		filters = append(filters, func(o *Object) bool {
+			ok, _ := path.Match(opt.ListGlob, o.Path)
+			return ok
+		})
+	}
+	if opt.HasListRegexp {
+		re, err := regexp.Compile(opt.ListRegexp)
+		if err != nil {
+			return nil, services.PairUnsupportedError{Pair: WithListRegexp(opt.ListRegexp)}
+		}
+		filters = append(filters, func(o *Object) bool {
+			return re.MatchString(o.Path)
+		})
+	}
+	if opt.HasModifiedAfter {
+		filters = append(filters, func(o *Object) bool {
+			if o.Mode.IsDir() {
+				return true
+			}
+			t, ok := o.GetLastModified()
+			return ok && t.After(opt.ModifiedAfter)
+		})
+	}
+	if opt.HasModifiedBefore {
+		filters = append(filters, func(o *Object) bool {
+			if o.Mode.IsDir() {
+				return true
+			}
+			t, ok := o.GetLastModified()
+			return ok && t.Before(opt.ModifiedBefore)
+		})
+	}
+	if opt.HasMinSize {
+		if opt.MinSize < 0 {
+			return nil, services.PairUnsupportedError{Pair: WithMinSize(opt.MinSize)}
+		}
+		filters = append(filters, func(o *Object) bool {
+			if o.Mode.IsDir() {
+				return true
+			}
+			n, ok := o.GetContentLength()
+			return ok && n >= opt.MinSize
+		})
+	}
+	if opt.HasMaxSize {
+		if opt.MaxSize < 0 {
+			return nil, services.PairUnsupportedError{Pair: WithMaxSize(opt.MaxSize)}
+		}
+		filters = append(filters, func(o *Object) bool {
+			if o.Mode.IsDir() {
+				return true
+			}
+			n, ok := o.GetContentLength()
+			return ok && n <= opt.MaxSize
+		})
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return func(o *Object) bool {
+		for _, fn := range filters {
+			if !fn(o) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// contextObjectPage will wrap next to stop fetching pages after ctx of the iterator is done.
+//
+// Every page request is sent with ctx, this check makes sure no more request will be built
+// after ctx canceled or deadline exceeded, even if pages are skipped by filters.
+func contextObjectPage(next NextObjectFunc) NextObjectFunc {
+	return func(ctx context.Context, page *ObjectPage) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return next(ctx, page)
+	}
+}
+
+// sortedObjectPage will wrap next to return objects sorted by ID across pages.
+//
+// Objects in every page will be sorted, since common prefixes and keys are returned
+// separately in dir mode. Keys that have been returned in previous pages will be dropped, and
+// keys before them means the order is broken by server, ErrListOrderViolated will be returned.
+func sortedObjectPage(next NextObjectFunc) NextObjectFunc {
+	// last is the ID of the last returned object.
+	var last string
+	return func(ctx context.Context, page *ObjectPage) error {
+		n := len(page.Data)
+		err := next(ctx, page)
+		if err != nil && err != IterateDone {
+			return err
+		}
+
+		data := page.Data[n:]
+		sort.SliceStable(data, func(i, j int) bool {
+			return data[i].ID < data[j].ID
+		})
+
+		m := n
+		for _, o := range data {
+			if last != "" {
+				if o.ID == last {
+					continue
+				}
+				if o.ID < last {
+					return ErrListOrderViolated
+				}
+			}
+			page.Data[m] = o
+			m++
+			last = o.ID
+		}
+		page.Data = page.Data[:m]
+		return err
+	}
+}
+
+// filterObjectPage will wrap next to drop objects not kept from every page.
+//
+// Pages with all objects dropped will be skipped, because an empty page means the end of
+// iterating.
+func filterObjectPage(next NextObjectFunc, keep func(o *Object) bool) NextObjectFunc {
+	if keep == nil {
+		return next
+	}
+	return func(ctx context.Context, page *ObjectPage) error {
+		for {
+			err := next(ctx, page)
+
+			n := 0
+			for _, o := range page.Data {
+				if keep(o) {
+					page.Data[n] = o
+					n++
+				}
+			}
+			page.Data = page.Data[:n]
+
+			if err != nil || n > 0 {
+				return err
+			}
+		}
+	}
+}
+
+// statObjectPage will wrap next to replace objects of every page with the result of stat, so
+// that full metadata like user metadata will be returned. At most concurrency objects will be
+// stated at the same time.
+//
+// Directories will not be stated, and objects deleted after listed will be kept as listed.
+func (s *Storage) statObjectPage(next NextObjectFunc, concurrency int) NextObjectFunc {
+	return func(ctx context.Context, page *ObjectPage) error {
+		n := len(page.Data)
+		err := next(ctx, page)
+		if err != nil && err != IterateDone {
+			return err
+		}
+
+		if serr := s.statObjects(ctx, page.Data[n:], concurrency); serr != nil {
+			return serr
+		}
+		return err
+	}
+}
+
+// statObjects will stat objects concurrently, and replace them with the stated ones in place.
+func (s *Storage) statObjects(ctx context.Context, objects []*Object, concurrency int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+	setErr := func(e error) {
+		once.Do(func() {
+			firstErr = e
+			cancel()
+		})
+	}
+
+	for i, o := range objects {
+		if o.Mode.IsDir() {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, o *Object) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			so, err := s.stat(ctx, o.Path, pairStorageStat{})
+			if err != nil {
+				if !errors.Is(formatError(err), services.ErrObjectNotExist) {
+					setErr(err)
+				}
+				return
+			}
+			objects[i] = so
+		}(i, o)
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		// ctx of the caller is canceled.
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
@@ -0,0 +1,463 @@
+package qingstor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+const (
+	// uploadMultipartConcurrencyDefault is the default multipart_concurrency of Upload.
+	uploadMultipartConcurrencyDefault = 4
+	// uploadMultipartRetryDefault is the default multipart_retry of Upload.
+	uploadMultipartRetryDefault = 3
+)
+
+// Upload will upload size bytes from r into path, large content will be uploaded via
+// multipart upload concurrently.
+func (s *Storage) Upload(path string, r io.Reader, size int64, pairs ...Pair) (n int64, err error) {
+	ctx := context.Background()
+	return s.UploadWithContext(ctx, path, r, size, pairs...)
+}
+
+// UploadWithContext will upload size bytes from r into path, large content will be uploaded via
+// multipart upload concurrently.
+//
+// Upload is Write with different defaults, it has no concurrency or retry knobs of its own.
+// Content larger than multipart_threshold (default to 64MB) will be sliced into parts of
+// multipart_part_size, which is enlarged to fit the part count limit. Parts are buffered in
+// memory and uploaded by multipart_concurrency (default to 4, capped by upload_concurrency of this
+// call or the storage) workers, every failed part will be retried multipart_retry (default to 3) times, and
+// the upload will be aborted if any part failed at last. Content not larger than
+// multipart_threshold will be written via a single PUT, which is retried as a whole
+// multipart_retry times unless write_retry is set, and it will be buffered in memory if r is
+// neither io.Seeker nor io.ReaderAt. Other pairs for Write are supported, but verify_etag, content_md5 and
+// if_none_match will be rejected for content uploaded via multipart upload.
+func (s *Storage) UploadWithContext(ctx context.Context, path string, r io.Reader, size int64, pairs ...Pair) (n int64, err error) {
+	defer func() {
+		err = s.formatError("upload", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Write...)
+	opt, err := s.parsePairStorageWrite(pairs)
+	if err != nil {
+		return
+	}
+
+	if size < 0 {
+		return 0, fmt.Errorf("size must not be negative: %w", services.ErrRestrictionDissatisfied)
+	}
+	if !opt.HasMultipartThreshold {
+		opt.HasMultipartThreshold, opt.MultipartThreshold = true, streamPartSize
+	}
+	if !opt.HasMultipartConcurrency {
+		opt.HasMultipartConcurrency, opt.MultipartConcurrency = true, uploadMultipartConcurrencyDefault
+	}
+	if !opt.HasMultipartRetry {
+		opt.HasMultipartRetry, opt.MultipartRetry = true, uploadMultipartRetryDefault
+	}
+
+	// Content written via a single PUT is retried as a whole, so it should be rewindable.
+	if size <= opt.MultipartThreshold && !opt.HasWriteRetry {
+		opt.HasWriteRetry, opt.WriteRetry = true, opt.MultipartRetry
+		if !isRewindable(r) && size <= streamPartSize {
+			buf := make([]byte, size)
+			if _, err = io.ReadFull(r, buf); err != nil {
+				return
+			}
+			r = bytes.NewReader(buf)
+		}
+	}
+	return s.write(ctx, path, r, size, opt)
+}
+
+// WriteMultipartAt will write size bytes from offset of r into the part index of multipart
+// object o.
+func (s *Storage) WriteMultipartAt(o *Object, r io.ReaderAt, offset, size int64, index int, pairs ...Pair) (n int64, part *Part, err error) {
+	ctx := context.Background()
+	return s.WriteMultipartAtWithContext(ctx, o, r, offset, size, index, pairs...)
+}
+
+// WriteMultipartAtWithContext will write size bytes from offset of r into the part index of
+// multipart object o.
+//
+// Content is read by ReadAt without changing the offset of r, so parts could be uploaded
+// concurrently from one opened file, and a failed part could be retried by calling again
+// without seeking. Pairs for WriteMultipart are supported.
+func (s *Storage) WriteMultipartAtWithContext(ctx context.Context, o *Object, r io.ReaderAt, offset, size int64, index int, pairs ...Pair) (n int64, part *Part, err error) {
+	defer func() {
+		err = s.formatError("write_multipart_at", err, o.Path)
+	}()
+
+	if !o.Mode.IsPart() {
+		return 0, nil, services.ObjectModeInvalidError{Expected: ModePart, Actual: o.Mode}
+	}
+	if offset < 0 {
+		return 0, nil, fmt.Errorf("offset must not be negative: %w", services.ErrRestrictionDissatisfied)
+	}
+
+	pairs = append(pairs, s.defaultPairs.WriteMultipart...)
+	opt, err := s.parsePairStorageWriteMultipart(pairs)
+	if err != nil {
+		return
+	}
+	return s.writeMultipart(ctx, o, io.NewSectionReader(r, offset, size), size, index, opt)
+}
+
+// RepairMultipart will re-upload parts of multipart object o which are missing or mismatched
+// in size on server side.
+func (s *Storage) RepairMultipart(o *Object, r io.ReaderAt, parts []*Part, pairs ...Pair) (result []*Part, err error) {
+	ctx := context.Background()
+	return s.RepairMultipartWithContext(ctx, o, r, parts, pairs...)
+}
+
+// RepairMultipartWithContext will re-upload parts of multipart object o which are missing or
+// mismatched in size on server side.
+//
+// parts are the locally known parts of the whole content in r, like the ones saved in
+// MultipartCheckpoint, the content of every part starts right after the former part, so they
+// should be numbered contiguously from the first part number without gaps. Parts uploaded
+// correctly will not be uploaded again, and the returned parts sorted by index could be used in
+// CompleteMultipart directly. Pairs for WriteMultipart are supported, every part will be retried
+// for transient failures like Upload.
+func (s *Storage) RepairMultipartWithContext(ctx context.Context, o *Object, r io.ReaderAt, parts []*Part, pairs ...Pair) (result []*Part, err error) {
+	defer func() {
+		err = s.formatError("repair_multipart", err, o.Path)
+	}()
+
+	if !o.Mode.IsPart() {
+		return nil, services.ObjectModeInvalidError{Expected: ModePart, Actual: o.Mode}
+	}
+
+	pairs = append(pairs, s.defaultPairs.WriteMultipart...)
+	opt, err := s.parsePairStorageWriteMultipart(pairs)
+	if err != nil {
+		return
+	}
+	// Content MD5 given by user could only match one part.
+	if opt.HasContentMd5 {
+		return nil, services.PairUnsupportedError{Pair: ps.WithContentMd5(opt.ContentMd5)}
+	}
+
+	local := make([]*Part, len(parts))
+	copy(local, parts)
+	sort.Slice(local, func(i, j int) bool {
+		return local[i].Index < local[j].Index
+	})
+	// Offset of parts after a gap could not be known, so repair will be refused.
+	for i, p := range local {
+		if i > 0 && p.Index == local[i-1].Index {
+			return nil, fmt.Errorf("part %d is duplicated: %w", p.Index, services.ErrRestrictionDissatisfied)
+		}
+		if p.Index != multipartNumberMinimum+i {
+			return nil, fmt.Errorf("part %d is missing: %w", multipartNumberMinimum+i, services.ErrRestrictionDissatisfied)
+		}
+	}
+
+	uploaded, err := s.ListPartsWithContext(ctx, o)
+	if err != nil {
+		return
+	}
+	remote := make(map[int]*Part, len(uploaded))
+	for _, p := range uploaded {
+		p := p.Part
+		remote[p.Index] = &p
+	}
+
+	var offset int64
+	result = make([]*Part, 0, len(local))
+	for _, p := range local {
+		// Size of client-side encrypted parts is the size after encrypted.
+		size := p.Size
+		if s.keyProvider != nil {
+			size = decryptedSize(p.Size)
+		}
+
+		if rp, ok := remote[p.Index]; ok && rp.Size == p.Size {
+			result = append(result, rp)
+			offset += size
+			continue
+		}
+
+		buf := make([]byte, size)
+		if _, err = io.ReadFull(io.NewSectionReader(r, offset, size), buf); err != nil {
+			return nil, err
+		}
+		part, err := s.writeMultipartWithRetry(ctx, o, buf, p.Index, uploadMultipartRetryDefault, opt)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, part)
+		offset += size
+	}
+	return result, nil
+}
+
+// WriteMultipartCopy will copy size bytes from offset of src into the part index of multipart
+// object o on server side.
+func (s *Storage) WriteMultipartCopy(o *Object, src string, offset, size int64, index int, pairs ...Pair) (part *Part, err error) {
+	ctx := context.Background()
+	return s.WriteMultipartCopyWithContext(ctx, o, src, offset, size, index, pairs...)
+}
+
+// WriteMultipartCopyWithContext will copy size bytes from offset of src into the part index of
+// multipart object o on server side.
+//
+// Content will not be downloaded and uploaded again, so objects larger than the limit of a
+// single copy could be copied part by part. Encryption pairs for Copy are supported, and the
+// part should be encrypted by the same customer key of the multipart upload. Client-side
+// encrypted objects could not be copied, since parts would be encrypted by the data key of src.
+func (s *Storage) WriteMultipartCopyWithContext(ctx context.Context, o *Object, src string, offset, size int64, index int, pairs ...Pair) (part *Part, err error) {
+	defer func() {
+		err = s.formatError("write_multipart_copy", err, o.Path, src)
+	}()
+
+	if !o.Mode.IsPart() {
+		return nil, services.ObjectModeInvalidError{Expected: ModePart, Actual: o.Mode}
+	}
+	if s.keyProvider != nil {
+		return nil, services.ErrCapabilityInsufficient
+	}
+
+	// Metadata and storage class are specified while creating the multipart upload, the storage
+	// class from default pairs of Copy is ignored, but the one passed in is rejected.
+	for _, v := range pairs {
+		if v.Key == "storage_class" {
+			return nil, services.PairUnsupportedError{Pair: v}
+		}
+	}
+	pairs = append(pairs, s.defaultPairs.Copy...)
+	opt, err := s.parsePairStorageCopy(pairs)
+	if err != nil {
+		return
+	}
+	if opt.HasContentDisposition {
+		return nil, services.PairUnsupportedError{Pair: WithContentDisposition(opt.ContentDisposition)}
+	}
+	opt.HasStorageClass = false
+
+	switch {
+	case index < multipartNumberMinimum || index > multipartNumberMaximum:
+		return nil, ErrPartNumberInvalid
+	case size <= 0 || size > multipartSizeMaximum:
+		return nil, ErrPartSizeInvalid
+	case offset < 0:
+		return nil, fmt.Errorf("offset must not be negative: %w", services.ErrRestrictionDissatisfied)
+	}
+
+	return s.writeMultipartCopy(ctx, o, s.copySourcePath(s.getAbsPath(src)), offset, size, index, opt)
+}
+
+// writeMultipartCopy will copy size bytes from offset of the copy source srcPath into the part
+// index of o.
+func (s *Storage) writeMultipartCopy(ctx context.Context, o *Object, srcPath string, offset, size int64, index int, opt pairStorageCopy) (part *Part, err error) {
+	input := &service.UploadMultipartInput{
+		PartNumber:    service.Int(index),
+		UploadID:      service.String(o.MustGetMultipartID()),
+		XQSCopySource: &srcPath,
+		XQSCopyRange:  service.String(fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)),
+	}
+	if opt.HasEncryptionCustomerAlgorithm {
+		input.XQSEncryptionCustomerAlgorithm, input.XQSEncryptionCustomerKey, input.XQSEncryptionCustomerKeyMD5, err = calculateEncryptionHeaders(opt.EncryptionCustomerAlgorithm, opt.EncryptionCustomerKey)
+		if err != nil {
+			return
+		}
+	}
+	if opt.HasCopySourceEncryptionCustomerAlgorithm {
+		input.XQSCopySourceEncryptionCustomerAlgorithm, input.XQSCopySourceEncryptionCustomerKey, input.XQSCopySourceEncryptionCustomerKeyMD5, err = calculateEncryptionHeaders(opt.CopySourceEncryptionCustomerAlgorithm, opt.CopySourceEncryptionCustomerKey)
+		if err != nil {
+			return
+		}
+	}
+
+	output, err := s.bucket.UploadMultipartWithContext(ctx, o.ID, input)
+	if err != nil {
+		return
+	}
+	return &Part{
+		Index: index,
+		Size:  size,
+		ETag:  service.StringValue(output.ETag),
+	}, nil
+}
+
+// copyPartSize is the part size used while copying objects larger than copySizeMaximum.
+const copyPartSize = 1024 * 1024 * 1024
+
+// copyConcurrency is the number of parts copied at the same time while copying objects larger
+// than copySizeMaximum.
+const copyConcurrency = 4
+
+// copyMultipart will copy the object of copy source srcPath with size larger than
+// copySizeMaximum into dst part by part, and complete the upload.
+//
+// Headers and metadata of source read by getObjectHeaders will be kept, which are copied by
+// server in a single copy.
+func (s *Storage) copyMultipart(ctx context.Context, srcPath string, dst string, src *service.GetObjectOutput, size int64, opt pairStorageCopy) (err error) {
+	// Parts of client-side encrypted objects could not be decrypted continuously after concatenated.
+	if s.keyProvider != nil {
+		return services.ErrCapabilityInsufficient
+	}
+
+	pairs, err := formatHeaderPairs(src)
+	if err != nil {
+		return
+	}
+	cmOpt, err := s.parsePairStorageCreateMultipart(pairs)
+	if err != nil {
+		return
+	}
+	if opt.HasStorageClass {
+		cmOpt.HasStorageClass, cmOpt.StorageClass = true, opt.StorageClass
+	}
+	if opt.HasEncryptionCustomerAlgorithm {
+		cmOpt.HasEncryptionCustomerAlgorithm, cmOpt.EncryptionCustomerAlgorithm = true, opt.EncryptionCustomerAlgorithm
+		cmOpt.HasEncryptionCustomerKey, cmOpt.EncryptionCustomerKey = true, opt.EncryptionCustomerKey
+	}
+	if opt.HasContentDisposition {
+		cmOpt.HasContentDisposition, cmOpt.ContentDisposition = true, opt.ContentDisposition
+	}
+
+	partSize, err := CalculatePartSize(size)
+	if err != nil {
+		return
+	}
+	if partSize < copyPartSize {
+		partSize = copyPartSize
+	}
+
+	o, err := s.createMultipart(ctx, dst, cmOpt)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			// Abort the multipart upload so that no parts will be left.
+			_ = s.delete(ctx, dst, pairStorageDelete{HasMultipartID: true, MultipartID: o.MustGetMultipartID()})
+		}
+	}()
+
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		parts    = make([]*Part, 0, (size+partSize-1)/partSize)
+		sem      = make(chan struct{}, copyConcurrency)
+	)
+	setErr := func(e error) {
+		once.Do(func() {
+			firstErr = e
+			cancel()
+		})
+	}
+
+	for index, offset := multipartNumberMinimum, int64(0); offset < size; index, offset = index+1, offset+partSize {
+		n := partSize
+		if offset+n > size {
+			n = size - offset
+		}
+		parts = append(parts, nil)
+
+		select {
+		case sem <- struct{}{}:
+		case <-cctx.Done():
+		}
+		if cctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i, index int, offset, n int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			part, err := s.writeMultipartCopy(cctx, o, srcPath, offset, n, index, opt)
+			if err != nil {
+				setErr(err)
+				return
+			}
+			// Every goroutine writes its own slot, so no lock is needed.
+			parts[i] = part
+		}(len(parts)-1, index, offset, n)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return s.completeMultipartUpload(ctx, o, parts)
+}
+
+// MultipartPart is an uploaded part of multipart object with the time it was uploaded.
+type MultipartPart struct {
+	Part
+	Created time.Time
+}
+
+// ListParts will list all uploaded parts of multipart object o.
+func (s *Storage) ListParts(o *Object) (parts []*MultipartPart, err error) {
+	ctx := context.Background()
+	return s.ListPartsWithContext(ctx, o)
+}
+
+// ListPartsWithContext will list all uploaded parts of multipart object o.
+//
+// Parts are returned with etag, size and created time, which could be used to rebuild the
+// parts for CompleteMultipart while resuming an upload started elsewhere.
+func (s *Storage) ListPartsWithContext(ctx context.Context, o *Object) (parts []*MultipartPart, err error) {
+	defer func() {
+		err = s.formatError("list_parts", err, o.Path)
+	}()
+
+	if !o.Mode.IsPart() {
+		return nil, services.ObjectModeInvalidError{Expected: ModePart, Actual: o.Mode}
+	}
+
+	input := &partPageStatus{
+		limit:    200,
+		prefix:   o.ID,
+		uploadID: o.MustGetMultipartID(),
+	}
+	for {
+		v, err := s.fetchPartPage(ctx, input)
+		if err != nil && !errors.Is(err, IterateDone) {
+			return nil, err
+		}
+		for _, p := range v {
+			parts = append(parts, &MultipartPart{
+				Part:    *formatPart(p),
+				Created: service.TimeValue(p.Created),
+			})
+		}
+		if err != nil {
+			return parts, nil
+		}
+	}
+}
+
+func formatPart(v *service.ObjectPartType) *Part {
+	return &Part{
+		Index: service.IntValue(v.PartNumber),
+		Size:  service.Int64Value(v.Size),
+		ETag:  service.StringValue(v.Etag),
+	}
+}
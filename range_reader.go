@@ -0,0 +1,413 @@
+package qingstor
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// readerBlockSizeDefault is the default size of blocks fetched by RangeReader.
+const readerBlockSizeDefault = 1024 * 1024
+
+// RangeReader is a random access reader of object content backed by ranged requests,
+// it implements io.ReaderAt, io.ReadSeeker and io.Closer.
+//
+// ReadAt could be called concurrently, but Read and Seek should not.
+type RangeReader struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	s      *Storage
+	path   string
+	opt    pairStorageRead
+
+	size    int64
+	etag    string
+	limiter *rateLimiter
+	meter   *transferMeter
+
+	offset int64
+
+	blockSize int64
+	cache     *blockCache
+	readAhead int
+
+	mu       sync.Mutex
+	inflight map[int64]*blockFetch
+}
+
+// blockFetch is a block being fetched, done will be closed after fetched.
+type blockFetch struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// ReaderAt will return a RangeReader which reads the content of path by ranged requests.
+func (s *Storage) ReaderAt(path string, pairs ...Pair) (r *RangeReader, err error) {
+	ctx := context.Background()
+	return s.ReaderAtWithContext(ctx, path, pairs...)
+}
+
+// ReaderAtWithContext will return a RangeReader which reads the content of path by ranged requests.
+//
+// Pairs for Read are supported except offset, size, suffix_size, compression, verify_sha256 and verify_etag.
+// The etag of object will be checked by every request, so reading fails with ErrPreconditionFailed
+// if the object is changed. Storage with key provider could not be read by ranges.
+//
+// While reader_block_cache is set, content will be fetched and cached by blocks of reader_block_size,
+// which reduces requests for small reads like archive/zip and parquet do.
+//
+// While reader_read_ahead is set, blocks after the current position will be prefetched in background
+// by Read, which fits sequential scans with occasional seeks. At most reader_block_cache blocks
+// will be kept in memory, and it will be set to reader_read_ahead+1 if not large enough.
+func (s *Storage) ReaderAtWithContext(ctx context.Context, path string, pairs ...Pair) (r *RangeReader, err error) {
+	defer func() {
+		err = s.formatError("reader_at", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Read...)
+	opt, err := s.parsePairStorageRead(pairs)
+	if err != nil {
+		return
+	}
+
+	// Encrypted chunks could not be read by ranges.
+	if s.keyProvider != nil {
+		return nil, services.ErrCapabilityInsufficient
+	}
+	switch {
+	case opt.HasOffset:
+		return nil, services.PairUnsupportedError{Pair: ps.WithOffset(opt.Offset)}
+	case opt.HasSize:
+		return nil, services.PairUnsupportedError{Pair: ps.WithSize(opt.Size)}
+	case opt.HasSuffixSize:
+		return nil, services.PairUnsupportedError{Pair: WithSuffixSize(opt.SuffixSize)}
+	case opt.HasCompression:
+		return nil, services.PairUnsupportedError{Pair: WithCompression(opt.Compression)}
+	case opt.HasVerifySha256:
+		return nil, services.PairUnsupportedError{Pair: WithVerifySha256()}
+	case opt.HasVerifyEtag:
+		return nil, services.PairUnsupportedError{Pair: WithVerifyEtag()}
+	case opt.HasReaderBlockSize && opt.ReaderBlockSize <= 0:
+		return nil, services.PairUnsupportedError{Pair: WithReaderBlockSize(opt.ReaderBlockSize)}
+	case opt.HasReaderReadAhead && opt.ReaderReadAhead < 0:
+		return nil, services.PairUnsupportedError{Pair: WithReaderReadAhead(opt.ReaderReadAhead)}
+	case opt.HasReadRateLimit && opt.ReadRateLimit <= 0:
+		return nil, services.PairUnsupportedError{Pair: WithReadRateLimit(opt.ReadRateLimit)}
+	}
+
+	path = strings.ReplaceAll(path, "\\", "/")
+	o, err := s.stat(ctx, path, pairStorageStat{
+		HasEncryptionCustomerAlgorithm: opt.HasEncryptionCustomerAlgorithm,
+		EncryptionCustomerAlgorithm:    opt.EncryptionCustomerAlgorithm,
+		HasEncryptionCustomerKey:       opt.HasEncryptionCustomerKey,
+		EncryptionCustomerKey:          opt.EncryptionCustomerKey,
+	})
+	if err != nil {
+		return
+	}
+
+	r = &RangeReader{
+		s:         s,
+		path:      path,
+		opt:       opt,
+		size:      o.MustGetContentLength(),
+		blockSize: readerBlockSizeDefault,
+		inflight:  make(map[int64]*blockFetch),
+	}
+	// Prefetching blocks will be canceled while closing.
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	r.etag, _ = o.GetEtag()
+	// All requests share the same limiter, so that the total rate is limited.
+	if opt.HasReadRateLimit {
+		r.limiter = newRateLimiter(opt.ReadRateLimit)
+		r.opt.HasReadRateLimit = false
+	}
+	if opt.HasTransferCallback {
+		r.meter = newTransferMeter(opt.TransferCallback, r.size)
+		r.opt.HasTransferCallback = false
+	}
+	if opt.HasReaderBlockSize {
+		r.blockSize = opt.ReaderBlockSize
+	}
+	cacheSize := 0
+	if opt.HasReaderBlockCache {
+		cacheSize = opt.ReaderBlockCache
+	}
+	if opt.HasReaderReadAhead && opt.ReaderReadAhead > 0 {
+		r.readAhead = opt.ReaderReadAhead
+		// The current block and all prefetched blocks should be kept.
+		if cacheSize < r.readAhead+1 {
+			cacheSize = r.readAhead + 1
+		}
+	}
+	if cacheSize > 0 {
+		r.cache = newBlockCache(cacheSize)
+	}
+	return r, nil
+}
+
+// Size returns the content length of object.
+func (r *RangeReader) Size() int64 {
+	return r.size
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *RangeReader) ReadAt(p []byte, off int64) (n int, err error) {
+	defer func() {
+		if err != nil && err != io.EOF {
+			err = r.s.formatError("read_at", err, r.path)
+		}
+	}()
+
+	if off < 0 {
+		return 0, ErrSeekOffsetInvalid
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	end := off + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+
+	if r.cache == nil {
+		n, err = r.fetch(p[:end-off], off)
+	} else {
+		n, err = r.readBlocks(p[:end-off], off)
+	}
+	if err != nil {
+		return
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// readBlocks will read p from blocks, missing blocks will be fetched and cached.
+func (r *RangeReader) readBlocks(p []byte, off int64) (n int, err error) {
+	for n < len(p) {
+		pos := off + int64(n)
+		index := pos / r.blockSize
+
+		block, err := r.block(index)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], block[pos-index*r.blockSize:])
+	}
+	return n, nil
+}
+
+// block will return the block at index from cache, or fetch it if missing. The same block
+// will not be fetched concurrently.
+func (r *RangeReader) block(index int64) ([]byte, error) {
+	if data, ok := r.cache.get(index); ok {
+		return data, nil
+	}
+
+	r.mu.Lock()
+	if f, ok := r.inflight[index]; ok {
+		r.mu.Unlock()
+		<-f.done
+		if f.err == nil {
+			return f.data, nil
+		}
+		// Failed prefetching will be retried by the caller.
+		r.mu.Lock()
+	}
+	// The block could have been cached before locked.
+	if data, ok := r.cache.get(index); ok {
+		r.mu.Unlock()
+		return data, nil
+	}
+	f := &blockFetch{done: make(chan struct{})}
+	r.inflight[index] = f
+	r.mu.Unlock()
+
+	start := index * r.blockSize
+	length := r.blockSize
+	if start+length > r.size {
+		length = r.size - start
+	}
+	f.data = make([]byte, length)
+	if _, f.err = r.fetch(f.data, start); f.err == nil {
+		r.cache.add(index, f.data)
+	}
+
+	r.mu.Lock()
+	delete(r.inflight, index)
+	r.mu.Unlock()
+	close(f.done)
+	return f.data, f.err
+}
+
+// prefetch will fetch blocks after index in background.
+func (r *RangeReader) prefetch(index int64) {
+	for i := index + 1; i <= index+int64(r.readAhead) && i*r.blockSize < r.size; i++ {
+		if _, ok := r.cache.get(i); ok {
+			continue
+		}
+		r.mu.Lock()
+		_, ok := r.inflight[i]
+		r.mu.Unlock()
+		if ok {
+			continue
+		}
+		go func(i int64) {
+			_, _ = r.block(i)
+		}(i)
+	}
+}
+
+// fetch will read len(p) bytes from off via a ranged request.
+func (r *RangeReader) fetch(p []byte, off int64) (n int, err error) {
+	opt := r.opt
+	opt.HasOffset, opt.Offset = true, off
+	opt.HasSize, opt.Size = true, int64(len(p))
+	// Make sure all ranges come from the same object.
+	if r.etag != "" {
+		opt.HasIfMatch, opt.IfMatch = true, r.etag
+	}
+
+	// p will be filled in place, since the buffer could not grow beyond its capacity.
+	var w io.Writer = bytes.NewBuffer(p[:0:len(p)])
+	if r.limiter != nil {
+		w = &rateLimitWriter{ctx: r.ctx, w: w, l: r.limiter}
+	}
+	if r.meter != nil {
+		w = &transferWriter{w: w, m: r.meter}
+	}
+	written, err := r.s.read(r.ctx, r.path, &limitedWriter{w: w, n: int64(len(p))}, opt)
+	if err != nil {
+		return int(written), err
+	}
+	if written != int64(len(p)) {
+		return int(written), io.ErrUnexpectedEOF
+	}
+	return len(p), nil
+}
+
+// Read implements io.Reader.
+func (r *RangeReader) Read(p []byte) (n int, err error) {
+	if r.readAhead > 0 && r.offset < r.size {
+		r.prefetch(r.offset / r.blockSize)
+	}
+
+	n, err = r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return
+}
+
+// Seek implements io.Seeker.
+func (r *RangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, r.s.formatError("seek", ErrSeekOffsetInvalid, r.path)
+	}
+	if offset < 0 {
+		return 0, r.s.formatError("seek", ErrSeekOffsetInvalid, r.path)
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// Close will cancel prefetching and release cached blocks.
+func (r *RangeReader) Close() error {
+	r.cancel()
+	if r.cache != nil {
+		r.cache.clear()
+	}
+	return nil
+}
+
+// limitedWriter will fail while writing more than n bytes, so that p in fetch will never be
+// reallocated.
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *limitedWriter) Write(p []byte) (n int, err error) {
+	if int64(len(p)) > l.n {
+		return 0, io.ErrShortWrite
+	}
+	n, err = l.w.Write(p)
+	l.n -= int64(n)
+	return
+}
+
+// blockCache is a LRU cache of blocks.
+type blockCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[int64]*list.Element
+}
+
+type blockCacheEntry struct {
+	index int64
+	data  []byte
+}
+
+func newBlockCache(capacity int) *blockCache {
+	return &blockCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[int64]*list.Element),
+	}
+}
+
+func (c *blockCache) get(index int64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[index]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*blockCacheEntry).data, true
+}
+
+func (c *blockCache) add(index int64, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[index]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*blockCacheEntry).data = data
+		return
+	}
+	c.items[index] = c.ll.PushFront(&blockCacheEntry{index: index, data: data})
+	for c.ll.Len() > c.capacity {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*blockCacheEntry).index)
+	}
+}
+
+func (c *blockCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[int64]*list.Element)
+}
@@ -0,0 +1,109 @@
+package qingstor
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket which allows bursts up to one second of limit, it could be
+// shared by concurrent readers and writers.
+type rateLimiter struct {
+	mu    sync.Mutex
+	limit int64
+
+	tokens int64
+	last   time.Time
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	return &rateLimiter{
+		limit:  bytesPerSecond,
+		tokens: bytesPerSecond,
+		last:   time.Now(),
+	}
+}
+
+// wait will consume n tokens, and wait until the consumed tokens have been refilled.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += int64(now.Sub(l.last).Seconds() * float64(l.limit))
+	if l.tokens > l.limit {
+		l.tokens = l.limit
+	}
+	l.last = now
+
+	l.tokens -= int64(n)
+	tokens := l.tokens
+	l.mu.Unlock()
+	if tokens >= 0 {
+		return nil
+	}
+
+	wait := time.Duration(float64(-tokens) / float64(l.limit) * float64(time.Second))
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimitReader will limit the rate of reading from r.
+type rateLimitReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *rateLimiter
+}
+
+func newRateLimitReader(ctx context.Context, r io.Reader, bytesPerSecond int64) io.Reader {
+	return &rateLimitReader{
+		ctx: ctx,
+		r:   r,
+		l:   newRateLimiter(bytesPerSecond),
+	}
+}
+
+func (r *rateLimitReader) Read(p []byte) (n int, err error) {
+	if int64(len(p)) > r.l.limit {
+		p = p[:r.l.limit]
+	}
+
+	n, err = r.r.Read(p)
+	if werr := r.l.wait(r.ctx, n); werr != nil {
+		return n, werr
+	}
+	return
+}
+
+// rateLimitWriter will limit the rate of writing into w.
+type rateLimitWriter struct {
+	ctx context.Context
+	w   io.Writer
+	l   *rateLimiter
+}
+
+func (w *rateLimitWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p
+		if int64(len(chunk)) > w.l.limit {
+			chunk = chunk[:w.l.limit]
+		}
+
+		var written int
+		written, err = w.w.Write(chunk)
+		n += written
+		if err != nil {
+			return
+		}
+		if err = w.l.wait(w.ctx, written); err != nil {
+			return
+		}
+		p = p[written:]
+	}
+	return
+}
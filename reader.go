@@ -0,0 +1,332 @@
+package qingstor
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/pkg/iowrap"
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// ReadCloser will return an io.ReadCloser which streams the content of path.
+func (s *Storage) ReadCloser(path string, pairs ...Pair) (rc io.ReadCloser, err error) {
+	ctx := context.Background()
+	return s.ReadCloserWithContext(ctx, path, pairs...)
+}
+
+// ReadCloserWithContext will return an io.ReadCloser which streams the content of path.
+//
+// Pairs for Read are supported. Caller should close the returned reader after reading.
+func (s *Storage) ReadCloserWithContext(ctx context.Context, path string, pairs ...Pair) (rc io.ReadCloser, err error) {
+	defer func() {
+		err = s.formatError("read_closer", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Read...)
+	opt, err := s.parsePairStorageRead(pairs)
+	if err != nil {
+		return
+	}
+
+	r, _, err := s.openReader(ctx, strings.ReplaceAll(path, "\\", "/"), opt)
+	if err != nil {
+		return
+	}
+	return &formatErrorReader{ReadCloser: r, s: s, path: path}, nil
+}
+
+// formatErrorReader will format errors returned while reading.
+type formatErrorReader struct {
+	io.ReadCloser
+	s    *Storage
+	path string
+}
+
+func (r *formatErrorReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = r.s.formatError("read", err, r.path)
+	}
+	return
+}
+
+// ReadWithMetadata will read the content of path into w, and returns the object built from
+// response headers.
+func (s *Storage) ReadWithMetadata(path string, w io.Writer, pairs ...Pair) (o *Object, n int64, err error) {
+	ctx := context.Background()
+	return s.ReadWithMetadataWithContext(ctx, path, w, pairs...)
+}
+
+// ReadWithMetadataWithContext will read the content of path into w, and returns the object
+// built from response headers.
+//
+// Pairs for Read are supported. Content length, content type, etag, last modified, user
+// metadata and system metadata will be set in the returned object, so there is no need to
+// call Stat before reading.
+func (s *Storage) ReadWithMetadataWithContext(ctx context.Context, path string, w io.Writer, pairs ...Pair) (o *Object, n int64, err error) {
+	defer func() {
+		err = s.formatError("read", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Read...)
+	opt, err := s.parsePairStorageRead(pairs)
+	if err != nil {
+		return
+	}
+
+	rc, o, err := s.openReader(ctx, strings.ReplaceAll(path, "\\", "/"), opt)
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	n, err = s.copyBuffer(w, rc, opt.CopyBufferSize)
+	if err != nil {
+		return nil, n, err
+	}
+	return o, n, nil
+}
+
+// openReader will send the read request, and returns a reader which decrypts, verifies
+// and decompresses the content as specified by opt, along with the object built from
+// response headers.
+func (s *Storage) openReader(ctx context.Context, path string, opt pairStorageRead) (rc io.ReadCloser, o *Object, err error) {
+	if opt.HasReadRateLimit && opt.ReadRateLimit <= 0 {
+		err = services.PairUnsupportedError{Pair: WithReadRateLimit(opt.ReadRateLimit)}
+		return
+	}
+	if opt.HasCopyBufferSize && opt.CopyBufferSize <= 0 {
+		err = services.PairUnsupportedError{Pair: WithCopyBufferSize(opt.CopyBufferSize)}
+		return
+	}
+	if opt.HasImageProcess {
+		return s.openImageReader(ctx, path, opt)
+	}
+	if opt.HasCompression && opt.Compression != compressionGzip {
+		err = services.PairUnsupportedError{Pair: WithCompression(opt.Compression)}
+		return
+	}
+	// Encrypted chunks and compressed content could not be read partially, and checksum
+	// could only be verified with the whole content.
+	verifyEtag := opt.HasVerifyEtag && opt.VerifyEtag
+	partialUnsupported := s.keyProvider != nil || opt.HasCompression || (opt.HasVerifySha256 && opt.VerifySha256) || verifyEtag
+	if partialUnsupported && opt.HasOffset {
+		err = services.PairUnsupportedError{Pair: ps.WithOffset(opt.Offset)}
+		return
+	}
+	if partialUnsupported && opt.HasSize {
+		err = services.PairUnsupportedError{Pair: ps.WithSize(opt.Size)}
+		return
+	}
+	if partialUnsupported && opt.HasSuffixSize {
+		err = services.PairUnsupportedError{Pair: WithSuffixSize(opt.SuffixSize)}
+		return
+	}
+
+	input, err := s.formatGetObjectInput(opt)
+	if err != nil {
+		return
+	}
+
+	rp := s.getAbsPath(path)
+
+	output, err := s.bucket.GetObjectWithContext(ctx, rp, input)
+	if err != nil {
+		return
+	}
+	// Conditional requests are treated as succeeded by sdk, so we need to check status code here.
+	switch service.IntValue(output.StatusCode) {
+	case http.StatusPreconditionFailed:
+		err = ErrPreconditionFailed
+	case http.StatusNotModified:
+		err = ErrObjectNotModified
+	}
+	if err != nil {
+		if output.Body != nil {
+			_ = output.Body.Close()
+		}
+		return
+	}
+
+	or := &objectReader{
+		r:       output.Body,
+		closers: []io.Closer{output.Body},
+	}
+	if opt.HasReadRetry && opt.ReadRetry > 0 {
+		rr := s.newResumeReader(ctx, rp, input, output, opt.ReadRetry)
+		or.wrap(rr)
+		or.closers = []io.Closer{rr}
+	}
+	// Etag is the md5 of content stored, so it should be calculated before decrypted and decompressed.
+	// Etag of objects encrypted by server or uploaded by multipart is not md5, they will be read directly.
+	if verifyEtag && output.XQSEncryptionCustomerAlgorithm == nil {
+		if etag := strings.Trim(service.StringValue(output.ETag), "\""); isMd5Etag(etag) {
+			h := md5.New()
+			or.wrap(io.TeeReader(or.r, h))
+			or.checksums = append(or.checksums, checksum{h: h, want: strings.ToLower(etag)})
+		}
+	}
+	defer func() {
+		if err != nil {
+			_ = or.Close()
+		}
+	}()
+
+	// Limit the bytes transferred on the wire, before decrypted and decompressed.
+	if opt.HasReadRateLimit {
+		or.wrap(newRateLimitReader(ctx, or.r, opt.ReadRateLimit))
+	}
+	decrypted := false
+	if s.keyProvider != nil && output.XQSMetaData != nil {
+		// Objects without data key are not encrypted, read them directly.
+		if key, ok := getClientEncryptionKey(*output.XQSMetaData); ok {
+			var aead cipher.AEAD
+			aead, err = s.openDataKey(ctx, key)
+			if err != nil {
+				return
+			}
+			or.wrap(newDecryptReader(or.r, aead))
+			decrypted = true
+		}
+	}
+	// Objects without checksum will be read directly.
+	if opt.HasVerifySha256 && opt.VerifySha256 && output.XQSMetaData != nil {
+		var want string
+		for k, v := range *output.XQSMetaData {
+			if strings.ToLower(k) == metadataContentSha256 {
+				want = strings.ToLower(v)
+			}
+		}
+		if want != "" {
+			h := sha256.New()
+			or.wrap(io.TeeReader(or.r, h))
+			or.checksums = append(or.checksums, checksum{h: h, want: want})
+		}
+	}
+	// Objects not compressed by gzip will be read directly.
+	if opt.HasCompression && isGzipEncoding(service.StringValue(output.ContentEncoding)) {
+		var gr *gzip.Reader
+		gr, err = gzip.NewReader(or.r)
+		if err != nil {
+			return
+		}
+		or.wrap(gr)
+		or.closers = append(or.closers, gr)
+	}
+	if opt.HasIoCallback {
+		or.wrap(iowrap.CallbackReader(or.r, opt.IoCallback))
+	}
+
+	o = s.formatObjectFromGetOutput(rp, path, output)
+	if opt.HasTransferCallback {
+		// Decompressed size is unknown.
+		total := int64(-1)
+		if !opt.HasCompression {
+			total = service.Int64Value(output.ContentLength)
+			if decrypted {
+				total = decryptedSize(total)
+			}
+		}
+		or.wrap(&transferReader{r: or.r, m: newTransferMeter(opt.TransferCallback, total)})
+	}
+	return or, o, nil
+}
+
+// formatObjectFromGetOutput will build object from the headers of read response.
+func (s *Storage) formatObjectFromGetOutput(rp, path string, output *service.GetObjectOutput) *Object {
+	o := s.newObject(true)
+	o.ID = rp
+	o.Path = path
+	o.Mode |= ModeRead
+
+	// Content length is the size of the range while reading partially.
+	size := service.Int64Value(output.ContentLength)
+	if _, _, total, ok := parseContentRange(service.StringValue(output.ContentRange)); ok {
+		size = total
+	}
+
+	sm := s.formatSystemMetadata(output.XQSStorageClass, output.XQSEncryptionCustomerAlgorithm, output.XQSMetaData)
+	if sm.ClientEncryptionKey != "" {
+		size = decryptedSize(size)
+	}
+	o.SetContentLength(size)
+	o.SetSystemMetadata(sm)
+
+	if output.ContentType != nil {
+		o.SetContentType(service.StringValue(output.ContentType))
+	}
+	if output.ETag != nil {
+		o.SetEtag(service.StringValue(output.ETag))
+	}
+	if output.LastModified != nil {
+		o.SetLastModified(service.TimeValue(output.LastModified))
+	}
+	if output.XQSMetaData != nil {
+		if um := parseUserMetadata(*output.XQSMetaData); len(um) > 0 {
+			o.SetUserMetadata(um)
+		}
+	}
+	return o
+}
+
+// objectReader is the reader of object content, checksum will be verified at EOF.
+type objectReader struct {
+	r       io.Reader
+	closers []io.Closer
+	// wrapped means r is not the response body anymore.
+	wrapped bool
+
+	// checksums will be verified after all content has been read.
+	checksums []checksum
+}
+
+// checksum is the hash of content being read and the expected sum in hex.
+type checksum struct {
+	h    hash.Hash
+	want string
+}
+
+func (o *objectReader) Read(p []byte) (n int, err error) {
+	n, err = o.r.Read(p)
+	if err == io.EOF {
+		for _, c := range o.checksums {
+			if hex.EncodeToString(c.h.Sum(nil)) != c.want {
+				return n, ErrContentCorrupted
+			}
+		}
+	}
+	return
+}
+
+// wrap will replace r with the reader decoding content from it.
+func (o *objectReader) wrap(r io.Reader) {
+	o.r = r
+	o.wrapped = true
+}
+
+// direct will check whether content could be read from the response body directly.
+func (o *objectReader) direct() bool {
+	return !o.wrapped
+}
+
+// Close will close all underlying readers in reverse order.
+func (o *objectReader) Close() (err error) {
+	for i := len(o.closers) - 1; i >= 0; i-- {
+		if cerr := o.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return
+}
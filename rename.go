@@ -0,0 +1,146 @@
+package qingstor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
+	typ "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// Rename will rename bucket from oldName to newName, and returns all renamed object keys.
+func (s *Service) Rename(oldName, newName string, pairs ...typ.Pair) (keys []string, err error) {
+	ctx := context.Background()
+	return s.RenameWithContext(ctx, oldName, newName, pairs...)
+}
+
+// RenameWithContext will rename bucket from oldName to newName, and returns all renamed object keys.
+//
+// QingStor doesn't support renaming bucket, so we will create the new bucket in the same
+// location, copy every object into it on server side and delete the old bucket at last.
+// Objects larger than the limit of a single copy will be copied part by part.
+//
+// Only objects listed before copying will be copied and deleted, so the old bucket will not
+// be deleted if objects are written into it while renaming. Renaming will fail with
+// ErrMultipartUploadInProgress if there are multipart uploads in progress, please complete
+// or abort them before renaming.
+//
+// The location of the old bucket could be specified by location, which will be detected
+// automatically if not set. With dry_run, the object keys that would be renamed are returned
+// without making any changes. Other pairs are not supported.
+func (s *Service) RenameWithContext(ctx context.Context, oldName, newName string, pairs ...typ.Pair) (keys []string, err error) {
+	defer func() {
+		err = s.formatError("rename", err, oldName)
+	}()
+
+	pairs = append(pairs, s.defaultLocationPairs()...)
+	opt, err := parsePairServiceRename(pairs)
+	if err != nil {
+		return
+	}
+
+	oldPairs := []typ.Pair{ps.WithName(oldName)}
+	if opt.HasLocation {
+		oldPairs = append(oldPairs, ps.WithLocation(opt.Location))
+	}
+	oldStore, err := s.newStorage(oldPairs...)
+	if err != nil {
+		return
+	}
+
+	// Multipart uploads could not be copied, and would be aborted while deleting the old bucket.
+	output, err := oldStore.bucket.ListMultipartUploadsWithContext(ctx, &service.ListMultipartUploadsInput{
+		Limit: service.Int(1),
+	})
+	if err != nil {
+		return
+	}
+	if len(output.Uploads) > 0 {
+		return nil, ErrMultipartUploadInProgress
+	}
+
+	it, err := oldStore.listListed(ctx, "", ps.WithListMode(typ.ListModePrefix))
+	if err != nil {
+		return
+	}
+	for {
+		var o *typ.Object
+		o, err = it.Next()
+		if err != nil {
+			break
+		}
+		keys = append(keys, o.ID)
+	}
+	if !errors.Is(err, typ.IterateDone) {
+		return nil, err
+	}
+	if opt.HasDryRun && opt.DryRun {
+		return keys, nil
+	}
+
+	newStore, err := s.newStorage(ps.WithName(newName), ps.WithLocation(*oldStore.properties.Zone))
+	if err != nil {
+		return
+	}
+	_, err = newStore.bucket.PutWithContext(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, key := range keys {
+		err = newStore.copyFrom(ctx, oldStore, key, key, pairStorageCopy{})
+		if err != nil {
+			return
+		}
+	}
+
+	// Objects written into the old bucket after listed are kept, and the old bucket
+	// could not be deleted then.
+	err = oldStore.deleteObjects(ctx, keys)
+	if err != nil {
+		return
+	}
+	_, err = oldStore.bucket.DeleteWithContext(ctx)
+	if err != nil {
+		return
+	}
+	return keys, nil
+}
+
+// pairServiceRename is the parsed struct of pairs for Rename.
+type pairServiceRename struct {
+	pairs []typ.Pair
+
+	HasDryRun   bool
+	DryRun      bool
+	HasLocation bool
+	Location    string
+}
+
+// parsePairServiceRename will parse Pair slice into pairServiceRename.
+func parsePairServiceRename(opts []typ.Pair) (pairServiceRename, error) {
+	result := pairServiceRename{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		case "dry_run":
+			if result.HasDryRun {
+				continue
+			}
+			result.HasDryRun = true
+			result.DryRun = v.Value.(bool)
+		case "location":
+			if result.HasLocation {
+				continue
+			}
+			result.HasLocation = true
+			result.Location = v.Value.(string)
+		default:
+			return pairServiceRename{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+	return result, nil
+}
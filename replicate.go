@@ -0,0 +1,270 @@
+package qingstor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
+	typ "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// replicateConcurrencyDefault is the default number of objects replicated at the same time.
+const replicateConcurrencyDefault = 4
+
+// ReplicateProgressCallback will be called after an object has been replicated or skipped.
+type ReplicateProgressCallback func(path string, size int64, skipped bool)
+
+// Replicate will copy all objects from src bucket to dst bucket.
+func (s *Service) Replicate(src, dst string, pairs ...typ.Pair) (err error) {
+	ctx := context.Background()
+	return s.ReplicateWithContext(ctx, src, dst, pairs...)
+}
+
+// ReplicateWithContext will copy all objects from src bucket to dst bucket.
+//
+// QingStor only supports copying objects in the same zone, so objects will be read
+// from src and written into dst with headers, user metadata and storage class kept.
+// Objects that already exist in dst with the same size and modified after the object
+// in src will be skipped, so a failed replication could be resumed by calling
+// ReplicateWithContext again.
+//
+// The location of src could be specified by location, and the location of dst by dst_location,
+// both will be detected automatically if not set. Objects are replicated by
+// replicate_concurrency (default to 4) workers, and replicate_progress will be called for every
+// object. Other pairs are not supported.
+func (s *Service) ReplicateWithContext(ctx context.Context, src, dst string, pairs ...typ.Pair) (err error) {
+	defer func() {
+		err = s.formatError("replicate", err, src)
+	}()
+
+	pairs = append(pairs, s.defaultLocationPairs()...)
+	opt, err := parsePairServiceReplicate(pairs)
+	if err != nil {
+		return
+	}
+
+	srcPairs := []typ.Pair{ps.WithName(src)}
+	if opt.HasLocation {
+		srcPairs = append(srcPairs, ps.WithLocation(opt.Location))
+	}
+	srcStore, err := s.newStorage(srcPairs...)
+	if err != nil {
+		return
+	}
+	dstPairs := []typ.Pair{ps.WithName(dst)}
+	if opt.HasDstLocation {
+		dstPairs = append(dstPairs, ps.WithLocation(opt.DstLocation))
+	}
+	dstStore, err := s.newStorage(dstPairs...)
+	if err != nil {
+		return
+	}
+
+	concurrency := replicateConcurrencyDefault
+	if opt.HasReplicateConcurrency {
+		concurrency = opt.ReplicateConcurrency
+		if concurrency <= 0 {
+			return services.PairUnsupportedError{Pair: WithReplicateConcurrency(concurrency)}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		ch       = make(chan *typ.Object)
+	)
+	setErr := func(e error) {
+		once.Do(func() {
+			firstErr = e
+			cancel()
+		})
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for o := range ch {
+				skipped, err := replicateObject(ctx, srcStore, dstStore, o)
+				if err != nil {
+					setErr(err)
+					continue
+				}
+				if opt.HasReplicateProgress {
+					opt.ReplicateProgress(o.Path, o.MustGetContentLength(), skipped)
+				}
+			}
+		}()
+	}
+
+	it, err := srcStore.listListed(ctx, "", ps.WithListMode(typ.ListModePrefix))
+	for err == nil {
+		var o *typ.Object
+		o, err = it.Next()
+		if err != nil {
+			break
+		}
+
+		select {
+		case ch <- o:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	close(ch)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if errors.Is(err, typ.IterateDone) {
+		return nil
+	}
+	return err
+}
+
+// replicateObject will copy object from src to dst, and returns whether this object has been skipped.
+func replicateObject(ctx context.Context, src, dst *Storage, o *typ.Object) (skipped bool, err error) {
+	size := o.MustGetContentLength()
+
+	// ETag of object uploaded via multipart upload differs between buckets, so objects are
+	// compared by size and last modified time.
+	do, err := dst.StatWithContext(ctx, o.Path)
+	if err == nil {
+		doSize, _ := do.GetContentLength()
+		doModified, _ := do.GetLastModified()
+		modified, _ := o.GetLastModified()
+		if doSize == size && !doModified.Before(modified) {
+			return true, nil
+		}
+	} else if !errors.Is(err, services.ErrObjectNotExist) {
+		return false, err
+	}
+
+	output, _, err := src.getObjectHeaders(ctx, o.ID, &service.GetObjectInput{})
+	if err != nil {
+		return
+	}
+	pairs, err := formatHeaderPairs(output)
+	if err != nil {
+		return
+	}
+
+	if size > writeSizeMaximum {
+		return false, replicateMultipart(ctx, src, dst, o, size, pairs)
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		_, err := src.ReadWithContext(ctx, o.Path, w)
+		_ = w.CloseWithError(err)
+	}()
+	defer r.Close()
+
+	_, err = dst.WriteWithContext(ctx, o.Path, r, size, pairs...)
+	return false, err
+}
+
+// replicateMultipart will copy object which exceeds write size limit via multipart upload.
+func replicateMultipart(ctx context.Context, src, dst *Storage, o *typ.Object, size int64, pairs []typ.Pair) (err error) {
+	partSize, err := CalculatePartSize(size)
+	if err != nil {
+		return
+	}
+
+	mo, err := dst.CreateMultipartWithContext(ctx, o.Path, pairs...)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			// Abort the multipart upload so that no parts will be left in dst.
+			_ = dst.Delete(o.Path, ps.WithMultipartID(mo.MustGetMultipartID()))
+		}
+	}()
+
+	parts := make([]*typ.Part, 0, size/partSize+1)
+	for offset, index := int64(0), 0; offset < size; offset, index = offset+partSize, index+1 {
+		n := partSize
+		if size-offset < n {
+			n = size - offset
+		}
+
+		r, w := io.Pipe()
+		go func(offset, n int64) {
+			_, err := src.ReadWithContext(ctx, o.Path, w, ps.WithOffset(offset), ps.WithSize(n))
+			_ = w.CloseWithError(err)
+		}(offset, n)
+
+		var part *typ.Part
+		_, part, err = dst.WriteMultipartWithContext(ctx, mo, r, n, index)
+		_ = r.Close()
+		if err != nil {
+			return
+		}
+		parts = append(parts, part)
+	}
+
+	return dst.CompleteMultipartWithContext(ctx, mo, parts)
+}
+
+// pairServiceReplicate is the parsed struct of pairs for Replicate.
+type pairServiceReplicate struct {
+	pairs []typ.Pair
+
+	HasDstLocation          bool
+	DstLocation             string
+	HasLocation             bool
+	Location                string
+	HasReplicateConcurrency bool
+	ReplicateConcurrency    int
+	HasReplicateProgress    bool
+	ReplicateProgress       ReplicateProgressCallback
+}
+
+// parsePairServiceReplicate will parse Pair slice into pairServiceReplicate.
+func parsePairServiceReplicate(opts []typ.Pair) (pairServiceReplicate, error) {
+	result := pairServiceReplicate{pairs: opts}
+
+	for _, v := range opts {
+		switch v.Key {
+		case "dst_location":
+			if result.HasDstLocation {
+				continue
+			}
+			result.HasDstLocation = true
+			result.DstLocation = v.Value.(string)
+		case "location":
+			if result.HasLocation {
+				continue
+			}
+			result.HasLocation = true
+			result.Location = v.Value.(string)
+		case "replicate_concurrency":
+			if result.HasReplicateConcurrency {
+				continue
+			}
+			result.HasReplicateConcurrency = true
+			result.ReplicateConcurrency = v.Value.(int)
+		case "replicate_progress":
+			if result.HasReplicateProgress {
+				continue
+			}
+			result.HasReplicateProgress = true
+			result.ReplicateProgress = v.Value.(ReplicateProgressCallback)
+		default:
+			return pairServiceReplicate{}, services.PairUnsupportedError{Pair: v}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,306 @@
+package qingstor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	qserror "github.com/qingstor/qingstor-sdk-go/v4/request/errors"
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+
+	"github.com/beyondstorage/go-storage/v4/pkg/headers"
+	"github.com/beyondstorage/go-storage/v4/pkg/iowrap"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+const (
+	// retryBackoffBase is the wait time before the first retry, doubled for every retry.
+	retryBackoffBase = 200 * time.Millisecond
+	// retryBackoffMaximum is the max wait time between two retries.
+	retryBackoffMaximum = 10 * time.Second
+)
+
+// writeWithRetry will retry write for transient failures by rewinding r to the offset where
+// the write started.
+//
+// Readers that are neither io.Seeker nor io.ReaderAt could not be rewound, so they will be
+// written only once. The position of io.ReaderAt is unknown, so it will be read from offset 0.
+func (s *Storage) writeWithRetry(ctx context.Context, path string, r io.Reader, size int64, opt pairStorageWrite) (n int64, err error) {
+	retry := opt.WriteRetry
+	opt.HasWriteRetry = false
+
+	var offset int64
+	var rewind func() (io.Reader, error)
+	switch v := r.(type) {
+	case io.Seeker:
+		if offset, err = v.Seek(0, io.SeekCurrent); err != nil {
+			return
+		}
+		rewind = func() (io.Reader, error) {
+			_, err := v.Seek(offset, io.SeekStart)
+			return r, err
+		}
+	case io.ReaderAt:
+		if size < 0 {
+			break
+		}
+		rewind = func() (io.Reader, error) {
+			return io.NewSectionReader(v, offset, size), nil
+		}
+	}
+	if rewind == nil {
+		return s.write(ctx, path, r, size, opt)
+	}
+
+	// Rate limit and statistics are shared by all attempts, so that the rate budget will not
+	// be reset and bytes sent again will not be counted twice.
+	var limiter *rateLimiter
+	if opt.HasWriteRateLimit {
+		limiter = newRateLimiter(opt.WriteRateLimit)
+		opt.HasWriteRateLimit = false
+	}
+	var tcb *retryCallback
+	if opt.HasTransferCallback {
+		m := newTransferMeter(opt.TransferCallback, size)
+		tcb = &retryCallback{fn: func(b []byte) { m.add(len(b)) }}
+		opt.HasTransferCallback = false
+	}
+
+	cb := &retryCallback{fn: opt.IoCallback}
+	backoff := retryBackoffBase
+	for i := 0; ; i++ {
+		var body io.Reader
+		body, err = rewind()
+		if err != nil {
+			return
+		}
+		if limiter != nil {
+			body = &rateLimitReader{ctx: ctx, r: body, l: limiter}
+		}
+		if tcb != nil {
+			body = iowrap.CallbackReader(body, tcb.attempt())
+		}
+		if opt.HasIoCallback {
+			opt.IoCallback = cb.attempt()
+		}
+		n, err = s.write(ctx, path, body, size, opt)
+		if err == nil || i >= retry || !isRetryableError(err) {
+			return
+		}
+
+		if backoff, err = waitBackoff(ctx, backoff); err != nil {
+			return
+		}
+	}
+}
+
+// isRewindable will check whether r could be rewound by writeWithRetry.
+func isRewindable(r io.Reader) bool {
+	switch r.(type) {
+	case io.Seeker, io.ReaderAt:
+		return true
+	}
+	return false
+}
+
+// writeMultipartWithRetry will retry uploading the part in p for transient failures.
+func (s *Storage) writeMultipartWithRetry(ctx context.Context, o *Object, p []byte, index, retry int, opt pairStorageWriteMultipart) (part *Part, err error) {
+	cb := &retryCallback{fn: opt.IoCallback}
+	backoff := retryBackoffBase
+	for i := 0; ; i++ {
+		if opt.HasIoCallback {
+			opt.IoCallback = cb.attempt()
+		}
+		_, part, err = s.writeMultipart(ctx, o, bytes.NewReader(p), int64(len(p)), index, opt)
+		if err == nil || i >= retry || !isRetryableError(err) {
+			return
+		}
+
+		if backoff, err = waitBackoff(ctx, backoff); err != nil {
+			return
+		}
+	}
+}
+
+// retryCallback wraps io_callback or transfer_callback for retries, so that bytes sent again by
+// a retry will not be reported twice.
+type retryCallback struct {
+	fn       func([]byte)
+	reported int64
+	sent     int64
+}
+
+// attempt will rewind the bytes sent, and returns the callback for a new attempt.
+func (c *retryCallback) attempt() func([]byte) {
+	c.sent = 0
+	return c.call
+}
+
+func (c *retryCallback) call(b []byte) {
+	start := c.sent
+	c.sent += int64(len(b))
+	if c.sent <= c.reported {
+		return
+	}
+	if start < c.reported {
+		b = b[c.reported-start:]
+	}
+	c.reported = c.sent
+	c.fn(b)
+}
+
+// waitBackoff will wait for backoff, and returns the backoff of next retry.
+func waitBackoff(ctx context.Context, backoff time.Duration) (time.Duration, error) {
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+		return backoff, ctx.Err()
+	}
+	if backoff *= 2; backoff > retryBackoffMaximum {
+		backoff = retryBackoffMaximum
+	}
+	return backoff, nil
+}
+
+// retryObjectPage will wrap next to retry fetching the page for transient failures with
+// backoff, so that a long listing will not be broken by throttling or server errors.
+//
+// Page status is only updated after the page fetched, so the same page will be fetched
+// again while retrying.
+func retryObjectPage(next NextObjectFunc, retry int) NextObjectFunc {
+	if retry <= 0 {
+		return next
+	}
+	return func(ctx context.Context, page *ObjectPage) (err error) {
+		n := len(page.Data)
+		backoff := retryBackoffBase
+		for i := 0; ; i++ {
+			page.Data = page.Data[:n]
+			err = next(ctx, page)
+			if err == nil || err == IterateDone || i >= retry || !isRetryableError(err) {
+				return
+			}
+
+			if backoff, err = waitBackoff(ctx, backoff); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// resumeReader will resume reading from the broken position for transient failures by
+// sending ranged requests.
+type resumeReader struct {
+	ctx   context.Context
+	s     *Storage
+	rp    string
+	input *service.GetObjectInput
+	body  io.ReadCloser
+
+	// start and end are the absolute range of content, end is inclusive.
+	start, end int64
+	etag       string
+	read       int64
+
+	retry   int
+	backoff time.Duration
+}
+
+func (s *Storage) newResumeReader(ctx context.Context, rp string, input *service.GetObjectInput, output *service.GetObjectOutput, retry int) *resumeReader {
+	r := &resumeReader{
+		ctx:     ctx,
+		s:       s,
+		rp:      rp,
+		input:   input,
+		body:    output.Body,
+		start:   0,
+		end:     service.Int64Value(output.ContentLength) - 1,
+		etag:    service.StringValue(output.ETag),
+		retry:   retry,
+		backoff: retryBackoffBase,
+	}
+	if start, end, _, ok := parseContentRange(service.StringValue(output.ContentRange)); ok {
+		r.start, r.end = start, end
+	}
+	return r
+}
+
+func (r *resumeReader) Read(p []byte) (n int, err error) {
+	for {
+		n, err = r.body.Read(p)
+		r.read += int64(n)
+		if err == io.EOF && r.start+r.read <= r.end {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil || err == io.EOF || r.retry <= 0 || !isRetryableError(err) {
+			return
+		}
+
+		r.retry--
+		if rerr := r.reopen(); rerr != nil {
+			return n, rerr
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// reopen will send a ranged request starting from the bytes already read.
+func (r *resumeReader) reopen() (err error) {
+	_ = r.body.Close()
+
+	if r.backoff, err = waitBackoff(r.ctx, r.backoff); err != nil {
+		return
+	}
+
+	input := *r.input
+	rs := headers.FormatRange(r.start+r.read, r.end-r.start-r.read+1)
+	input.Range = &rs
+	// Make sure the rest content comes from the same object, other conditions have been
+	// checked by the first request.
+	input.IfNoneMatch, input.IfModifiedSince = nil, nil
+	if r.etag != "" {
+		input.IfMatch = service.String(r.etag)
+	}
+
+	output, err := r.s.bucket.GetObjectWithContext(r.ctx, r.rp, &input)
+	if err != nil {
+		return
+	}
+	if service.IntValue(output.StatusCode) == http.StatusPreconditionFailed {
+		if output.Body != nil {
+			_ = output.Body.Close()
+		}
+		return ErrPreconditionFailed
+	}
+	r.body = output.Body
+	return nil
+}
+
+func (r *resumeReader) Close() error {
+	return r.body.Close()
+}
+
+// isRetryableError will check whether the error is transient.
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrContentCorrupted) {
+		return true
+	}
+
+	var e *qserror.QingStorError
+	if errors.As(err, &e) {
+		return e.StatusCode == 429 || e.StatusCode >= 500
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
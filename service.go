@@ -2,15 +2,30 @@ package qingstor
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
 
+	qserror "github.com/qingstor/qingstor-sdk-go/v4/request/errors"
 	"github.com/qingstor/qingstor-sdk-go/v4/service"
 
 	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
 	. "github.com/beyondstorage/go-storage/v4/types"
 )
 
 func (s *Service) create(ctx context.Context, name string, opt pairServiceCreate) (store Storager, err error) {
+	var rules []ACLRule
+	if opt.HasCannedACL {
+		rules, err = parseCannedACL(opt.CannedACL)
+		if err != nil {
+			return
+		}
+	}
+
 	// ServicePairCreate requires location, so we don't need to add location into pairs
+	// default_storage_class will be handled while creating storage.
 	pairs := append(opt.pairs, ps.WithName(name))
 
 	st, err := s.newStorage(pairs...)
@@ -22,6 +37,16 @@ func (s *Service) create(ctx context.Context, name string, opt pairServiceCreate
 	if err != nil {
 		return
 	}
+
+	// QingStor doesn't support setting ACL while creating bucket, so we need to put it later.
+	if len(rules) > 0 {
+		_, err = st.bucket.PutACLWithContext(ctx, &service.PutBucketACLInput{
+			ACL: formatACLTypes(rules),
+		})
+		if err != nil {
+			return
+		}
+	}
 	return st, nil
 }
 
@@ -32,6 +57,15 @@ func (s *Service) delete(ctx context.Context, name string, opt pairServiceDelete
 	if err != nil {
 		return
 	}
+
+	// QingStor refuses to delete a non-empty bucket, so we need to empty it first.
+	if opt.HasForce && opt.Force {
+		err = store.emptyBucket(ctx)
+		if err != nil {
+			return
+		}
+	}
+
 	_, err = store.bucket.DeleteWithContext(ctx)
 	if err != nil {
 		return
@@ -40,31 +74,124 @@ func (s *Service) delete(ctx context.Context, name string, opt pairServiceDelete
 }
 
 func (s *Service) get(ctx context.Context, name string, opt pairServiceGet) (store Storager, err error) {
+
 	pairs := append(opt.pairs, ps.WithName(name))
 
-	store, err = s.newStorage(pairs...)
+	st, err := s.newStorage(pairs...)
 	if err != nil {
 		return
 	}
-	return
+
+	if opt.HasValidateBucket && opt.ValidateBucket {
+		_, err = st.bucket.HeadWithContext(ctx)
+		if err != nil {
+			// HEAD response doesn't have body, so we need to check the status code.
+			var e *qserror.QingStorError
+			if errors.As(err, &e) && e.StatusCode == 404 {
+				err = ErrBucketNotExist
+			}
+			return
+		}
+	}
+	return st, nil
 }
 
+// list will list buckets in the location specified by the location pair, which is
+// filtered by QingStor on server side. If location is not specified, buckets in all
+// locations will be returned.
+//
+// If locations is specified, buckets in these locations will be listed concurrently
+// and returned in the order of locations.
 func (s *Service) list(ctx context.Context, opt pairServiceList) (it *StoragerIterator, err error) {
-	input := &storagePageStatus{}
+	input := &storagePageStatus{
+		limit: 200,
+	}
 
 	if opt.HasLocation {
 		input.location = opt.Location
 	}
+	if opt.HasPageSize {
+		if opt.PageSize <= 0 {
+			return nil, services.PairUnsupportedError{Pair: WithPageSize(opt.PageSize)}
+		}
+		input.limit = opt.PageSize
+	}
+	// The continuation token is the offset returned by storagePageStatus.ContinuationToken.
+	if opt.HasContinuationToken {
+		input.offset, err = strconv.Atoi(opt.ContinuationToken)
+		if err != nil || input.offset < 0 {
+			return nil, services.PairUnsupportedError{Pair: ps.WithContinuationToken(opt.ContinuationToken)}
+		}
+	}
+
+	if opt.HasLocations {
+		// Buckets from all locations are merged in one page, location and continuation
+		// token could not work together with locations.
+		if opt.HasLocation {
+			return nil, services.PairUnsupportedError{Pair: ps.WithLocation(opt.Location)}
+		}
+		if opt.HasContinuationToken {
+			return nil, services.PairUnsupportedError{Pair: ps.WithContinuationToken(opt.ContinuationToken)}
+		}
+		input.locations = opt.Locations
+		return NewStoragerIterator(ctx, s.nextStoragePageByLocations, input), nil
+	}
 
 	return NewStoragerIterator(ctx, s.nextStoragePage, input), nil
 }
 
+func (s *Service) nextStoragePageByLocations(ctx context.Context, page *StoragerPage) error {
+	input := page.Status.(*storagePageStatus)
+
+	stores := make([][]Storager, len(input.locations))
+	errs := make([]error, len(input.locations))
+
+	var wg sync.WaitGroup
+	for i, location := range input.locations {
+		wg.Add(1)
+		go func(i int, location string) {
+			defer wg.Done()
+
+			stores[i], errs[i] = s.listStoragesInLocation(ctx, location, input.limit)
+		}(i, location)
+	}
+	wg.Wait()
+
+	for i := range input.locations {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		page.Data = append(page.Data, stores[i]...)
+	}
+	return IterateDone
+}
+
+// listStoragesInLocation will list all buckets in the location.
+func (s *Service) listStoragesInLocation(ctx context.Context, location string, limit int) (stores []Storager, err error) {
+	page := &StoragerPage{
+		Status: &storagePageStatus{
+			limit:    limit,
+			location: location,
+		},
+	}
+	for {
+		err = s.nextStoragePage(ctx, page)
+		if err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, IterateDone) {
+		return nil, err
+	}
+	return page.Data, nil
+}
+
 func (s *Service) nextStoragePage(ctx context.Context, page *StoragerPage) error {
 	input := page.Status.(*storagePageStatus)
 
 	serviceInput := &service.ListBucketsInput{
-		Limit:  &input.offset,
-		Offset: &input.limit,
+		Limit:  &input.limit,
+		Offset: &input.offset,
 	}
 	if input.location != "" {
 		serviceInput.Location = &input.location
@@ -76,10 +203,21 @@ func (s *Service) nextStoragePage(ctx context.Context, page *StoragerPage) error
 	}
 
 	for _, v := range output.Buckets {
+		// Make sure we only return buckets in the specified location, even if the
+		// endpoint doesn't support filtering by location.
+		if input.location != "" && service.StringValue(v.Location) != input.location {
+			continue
+		}
+
 		store, err := s.newStorage(ps.WithName(*v.Name), ps.WithLocation(*v.Location))
 		if err != nil {
 			return err
 		}
+		// Carry bucket attributes so that callers don't need to get them again.
+		store.systemMetadata = StorageSystemMetadata{
+			Created: service.TimeValue(v.Created),
+			URL:     service.StringValue(v.URL),
+		}
 		page.Data = append(page.Data, store)
 	}
 
@@ -87,6 +225,99 @@ func (s *Service) nextStoragePage(ctx context.Context, page *StoragerPage) error
 	if input.offset >= service.IntValue(output.Count) {
 		return IterateDone
 	}
+	if len(output.Buckets) == 0 {
+		return IterateDone
+	}
+
+	return nil
+}
+
+// deleteMultipleObjectsLimit is the maximum number of objects could be deleted in one request.
+// ref: https://docs.qingcloud.com/qingstor/api/bucket/delete_multiple
+const deleteMultipleObjectsLimit = 1000
+
+// emptyBucket will abort all multipart uploads and delete all objects in the bucket.
+func (s *Storage) emptyBucket(ctx context.Context) error {
+	// Abort multipart uploads first, or the bucket is still not empty after all objects deleted.
+	keyMarker, uploadIDMarker := "", ""
+	for {
+		output, err := s.bucket.ListMultipartUploadsWithContext(ctx, &service.ListMultipartUploadsInput{
+			KeyMarker:      &keyMarker,
+			Limit:          service.Int(200),
+			UploadIDMarker: &uploadIDMarker,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, v := range output.Uploads {
+			_, err = s.bucket.AbortMultipartUploadWithContext(ctx, *v.Key, &service.AbortMultipartUploadInput{
+				UploadID: v.UploadID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		keyMarker = service.StringValue(output.NextKeyMarker)
+		uploadIDMarker = service.StringValue(output.NextUploadIDMarker)
+		if !service.BoolValue(output.HasMore) || (keyMarker == "" && uploadIDMarker == "") {
+			break
+		}
+	}
 
+	marker := ""
+	for {
+		output, err := s.bucket.ListObjectsWithContext(ctx, &service.ListObjectsInput{
+			Limit:  service.Int(deleteMultipleObjectsLimit),
+			Marker: &marker,
+		})
+		if err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(output.Keys))
+		for _, v := range output.Keys {
+			keys = append(keys, *v.Key)
+		}
+		if err = s.deleteObjects(ctx, keys); err != nil {
+			return err
+		}
+
+		marker = service.StringValue(output.NextMarker)
+		if !service.BoolValue(output.HasMore) || marker == "" {
+			break
+		}
+	}
+	return nil
+}
+
+// deleteObjects will delete objects with keys in batches.
+func (s *Storage) deleteObjects(ctx context.Context, keys []string) error {
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > deleteMultipleObjectsLimit {
+			n = deleteMultipleObjectsLimit
+		}
+
+		objects := make([]*service.KeyType, 0, n)
+		for _, key := range keys[:n] {
+			objects = append(objects, &service.KeyType{Key: service.String(key)})
+		}
+		keys = keys[n:]
+
+		output, err := s.bucket.DeleteMultipleObjectsWithContext(ctx, &service.DeleteMultipleObjectsInput{
+			Objects: objects,
+			Quiet:   service.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+		if len(output.Errors) > 0 {
+			e := output.Errors[0]
+			return fmt.Errorf("delete object %s failed: %s",
+				service.StringValue(e.Key), service.StringValue(e.Message))
+		}
+	}
 	return nil
 }
@@ -1,18 +1,22 @@
 package qingstor
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 
 	"bou.ke/monkey"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/qingstor/qingstor-sdk-go/v4/config"
+	qerror "github.com/qingstor/qingstor-sdk-go/v4/request/errors"
 	"github.com/qingstor/qingstor-sdk-go/v4/service"
 	"github.com/stretchr/testify/assert"
 
@@ -64,6 +68,47 @@ func TestService_Get(t *testing.T) {
 		assert.NotNil(t, s)
 	})
 
+	t.Run("with dry run", func(t *testing.T) {
+		srv := Service{
+			service: NewMockService(ctrl),
+		}
+
+		_, err := srv.Get(uuid.New().String(), WithDryRun())
+		assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+	})
+
+	t.Run("with replicate pairs", func(t *testing.T) {
+		srv := Service{
+			service: NewMockService(ctrl),
+		}
+
+		for _, pair := range []types.Pair{WithDstLocation("pek3b"), WithReplicateConcurrency(1)} {
+			_, err := srv.Get(uuid.New().String(), pair)
+			assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+		}
+	})
+
+	t.Run("with service default storage class", func(t *testing.T) {
+		mockService := NewMockService(ctrl)
+
+		srv := Service{
+			service:             mockService,
+			defaultStorageClass: StorageClassStandardIA,
+		}
+
+		mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).Return(&service.Bucket{}, nil).Times(2)
+
+		s, err := srv.Get(uuid.New().String(), pairs.WithLocation(uuid.New().String()))
+		assert.NoError(t, err)
+		assert.Equal(t, []types.Pair{WithStorageClass(StorageClassStandardIA)}, s.(*Storage).defaultPairs.Write)
+
+		// Storage's own default storage class takes precedence.
+		st, err := srv.newStorage(pairs.WithName(uuid.New().String()), pairs.WithLocation(uuid.New().String()),
+			WithDefaultStorageClass(StorageClassStandard))
+		assert.NoError(t, err)
+		assert.Equal(t, []types.Pair{WithStorageClass(StorageClassStandard)}, st.defaultPairs.Write)
+	})
+
 	t.Run("without location", func(t *testing.T) {
 		mockService := NewMockService(ctrl)
 
@@ -112,6 +157,57 @@ func TestService_Get(t *testing.T) {
 		assert.NotNil(t, s)
 	})
 
+	t.Run("without location and bucket not exist", func(t *testing.T) {
+		srv := &Service{}
+		srv.config = &config.Config{
+			AccessKeyID:     uuid.New().String(),
+			SecretAccessKey: uuid.New().String(),
+			Host:            uuid.New().String(),
+			Port:            1234,
+			Protocol:        "https",
+		}
+
+		// Patch http Head.
+		fn := func(client *http.Client, url string) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Header:     http.Header{},
+			}, nil
+		}
+		monkey.PatchInstanceMethod(reflect.TypeOf(srv.client), "Head", fn)
+		defer monkey.UnpatchInstanceMethod(reflect.TypeOf(srv.client), "Head")
+
+		s, err := srv.Get(uuid.New().String())
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, ErrBucketNotExist))
+		assert.Nil(t, s)
+	})
+
+	t.Run("validate bucket", func(t *testing.T) {
+		mockService := NewMockService(ctrl)
+
+		srv := Service{
+			service: mockService,
+		}
+
+		name := uuid.New().String()
+		location := uuid.New().String()
+
+		bucket := &service.Bucket{}
+		fn := func(*service.Bucket, context.Context) (*service.HeadBucketOutput, error) {
+			return nil, &qerror.QingStorError{StatusCode: 404}
+		}
+		monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "HeadWithContext", fn)
+		defer monkey.UnpatchInstanceMethod(reflect.TypeOf(bucket), "HeadWithContext")
+
+		mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).Return(bucket, nil)
+
+		s, err := srv.Get(name, pairs.WithLocation(location), WithValidateBucket())
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, ErrBucketNotExist))
+		assert.Nil(t, s)
+	})
+
 	t.Run("invalid bucket name", func(t *testing.T) {
 		mockService := NewMockService(ctrl)
 
@@ -166,6 +262,32 @@ func TestService_Create(t *testing.T) {
 
 	_, err = srv.Create(path, pairs.WithLocation(location))
 	assert.NoError(t, err)
+
+	// Test case3: with invalid canned acl.
+	_, err = srv.Create(path, pairs.WithLocation(location), WithCannedACL("invalid"))
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	// Test case4: with canned acl and default storage class.
+	path = uuid.New().String()
+
+	aclFn := func(_ *service.Bucket, _ context.Context, input *service.PutBucketACLInput) (*service.PutBucketACLOutput, error) {
+		assert.Equal(t, 1, len(input.ACL))
+		assert.Equal(t, ACLGroupAllUsers, *input.ACL[0].Grantee.Name)
+		assert.Equal(t, ACLPermissionRead, *input.ACL[0].Permission)
+		return &service.PutBucketACLOutput{}, nil
+	}
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "PutACLWithContext", aclFn)
+
+	mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).Return(bucket, nil)
+
+	store, err := srv.Create(path,
+		pairs.WithLocation(location),
+		WithCannedACL(CannedACLPublicRead),
+		WithDefaultStorageClass(StorageClassStandardIA),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Pair{WithStorageClass(StorageClassStandardIA)}, store.(*Storage).defaultPairs.Write)
 }
 
 func TestService_Delete(t *testing.T) {
@@ -199,6 +321,58 @@ func TestService_Delete(t *testing.T) {
 		err := srv.Delete(name, pairs.WithLocation(location))
 		assert.NoError(t, err)
 	}
+
+	{
+		name := uuid.New().String()
+		location := uuid.New().String()
+		key := uuid.New().String()
+		uploadID := uuid.New().String()
+
+		bucket := &service.Bucket{}
+		monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "DeleteWithContext",
+			func(*service.Bucket, context.Context) (*service.DeleteBucketOutput, error) {
+				return nil, nil
+			})
+		monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "ListMultipartUploadsWithContext",
+			func(_ *service.Bucket, _ context.Context, _ *service.ListMultipartUploadsInput) (*service.ListMultipartUploadsOutput, error) {
+				return &service.ListMultipartUploadsOutput{
+					HasMore: service.Bool(false),
+					Uploads: []*service.UploadsType{
+						{Key: service.String(key), UploadID: service.String(uploadID)},
+					},
+				}, nil
+			})
+		monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "AbortMultipartUploadWithContext",
+			func(_ *service.Bucket, _ context.Context, objectKey string, input *service.AbortMultipartUploadInput) (*service.AbortMultipartUploadOutput, error) {
+				assert.Equal(t, key, objectKey)
+				assert.Equal(t, uploadID, *input.UploadID)
+				return &service.AbortMultipartUploadOutput{}, nil
+			})
+		monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "ListObjectsWithContext",
+			func(_ *service.Bucket, _ context.Context, _ *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+				return &service.ListObjectsOutput{
+					HasMore: service.Bool(false),
+					Keys: []*service.KeyType{
+						{Key: service.String(key)},
+					},
+				}, nil
+			})
+		deleted := false
+		monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "DeleteMultipleObjectsWithContext",
+			func(_ *service.Bucket, _ context.Context, input *service.DeleteMultipleObjectsInput) (*service.DeleteMultipleObjectsOutput, error) {
+				assert.Equal(t, 1, len(input.Objects))
+				assert.Equal(t, key, *input.Objects[0].Key)
+				deleted = true
+				return &service.DeleteMultipleObjectsOutput{}, nil
+			})
+		defer monkey.UnpatchAll()
+
+		mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).Return(bucket, nil)
+
+		err := srv.Delete(name, pairs.WithLocation(location), WithForce())
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+	}
 }
 
 func TestService_List(t *testing.T) {
@@ -220,12 +394,14 @@ func TestService_List(t *testing.T) {
 		// Test request with location.
 		name := uuid.New().String()
 		location := uuid.New().String()
+		url := uuid.New().String()
+		created := time.Now().Truncate(time.Second)
 
 		mockService.EXPECT().ListBucketsWithContext(gomock.Eq(context.Background()), gomock.Any()).DoAndReturn(func(ctx context.Context, input *service.ListBucketsInput) (*service.ListBucketsOutput, error) {
 			assert.Equal(t, location, *input.Location)
 			return &service.ListBucketsOutput{
 				Buckets: []*service.BucketType{
-					{Name: &name, Location: &location},
+					{Name: &name, Location: &location, URL: &url, Created: &created},
 				},
 			}, nil
 		})
@@ -238,6 +414,31 @@ func TestService_List(t *testing.T) {
 			t.Error(err)
 		}
 		assert.NotNil(t, st)
+		assert.Equal(t, StorageSystemMetadata{Created: created, URL: url}, st.(*Storage).systemMetadata)
+	}
+
+	{
+		// Test buckets in other locations are filtered.
+		name := uuid.New().String()
+		location := uuid.New().String()
+		otherLocation := uuid.New().String()
+
+		mockService.EXPECT().ListBucketsWithContext(gomock.Eq(context.Background()), gomock.Any()).DoAndReturn(func(ctx context.Context, input *service.ListBucketsInput) (*service.ListBucketsOutput, error) {
+			return &service.ListBucketsOutput{
+				Buckets: []*service.BucketType{
+					{Name: &name, Location: &otherLocation},
+					{Name: &name, Location: &location},
+				},
+				Count: service.Int(2),
+			}, nil
+		})
+
+		it, err := srv.List(pairs.WithLocation(location))
+		assert.NoError(t, err)
+		_, err = it.Next()
+		assert.NoError(t, err)
+		_, err = it.Next()
+		assert.True(t, errors.Is(err, types.IterateDone))
 	}
 
 	{
@@ -261,6 +462,328 @@ func TestService_List(t *testing.T) {
 		_, err = it.Next()
 		assert.NoError(t, err)
 	}
+
+	{
+		// Test request with page size and continuation token.
+		name := uuid.New().String()
+		location := uuid.New().String()
+
+		mockService.EXPECT().ListBucketsWithContext(gomock.Eq(context.Background()), gomock.Any()).DoAndReturn(func(ctx context.Context, input *service.ListBucketsInput) (*service.ListBucketsOutput, error) {
+			assert.Equal(t, 1, *input.Limit)
+			assert.Equal(t, 10, *input.Offset)
+			return &service.ListBucketsOutput{
+				Buckets: []*service.BucketType{
+					{Name: &name, Location: &location},
+				},
+				Count: service.Int(20),
+			}, nil
+		})
+
+		it, err := srv.List(WithPageSize(1), pairs.WithContinuationToken("10"))
+		assert.NoError(t, err)
+		_, err = it.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, "11", it.ContinuationToken())
+	}
+
+	{
+		// Test request with multiple locations.
+		locations := []string{uuid.New().String(), uuid.New().String()}
+
+		mockService.EXPECT().ListBucketsWithContext(gomock.Eq(context.Background()), gomock.Any()).DoAndReturn(func(ctx context.Context, input *service.ListBucketsInput) (*service.ListBucketsOutput, error) {
+			name := uuid.New().String()
+			return &service.ListBucketsOutput{
+				Buckets: []*service.BucketType{
+					{Name: &name, Location: input.Location},
+				},
+				Count: service.Int(1),
+			}, nil
+		}).Times(2)
+
+		it, err := srv.List(WithLocations(locations))
+		assert.NoError(t, err)
+		for range locations {
+			_, err = it.Next()
+			assert.NoError(t, err)
+		}
+		_, err = it.Next()
+		assert.True(t, errors.Is(err, types.IterateDone))
+
+		_, err = srv.List(WithLocations(locations), pairs.WithLocation(locations[0]))
+		assert.Error(t, err)
+	}
+
+	{
+		// Test request with invalid continuation token.
+		_, err := srv.List(pairs.WithContinuationToken("invalid"))
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+	}
+}
+
+func TestService_ACL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := NewMockService(ctrl)
+
+	srv := Service{
+		service: mockService,
+	}
+
+	name := uuid.New().String()
+	location := uuid.New().String()
+	userID := uuid.New().String()
+
+	bucket := &service.Bucket{}
+	mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).DoAndReturn(func(bucketName, inputLocation string) (*service.Bucket, error) {
+		assert.Equal(t, name, bucketName)
+		assert.Equal(t, location, inputLocation)
+		return bucket, nil
+	}).Times(3)
+
+	// Patch bucket.PutACL
+	putFn := func(_ *service.Bucket, _ context.Context, input *service.PutBucketACLInput) (*service.PutBucketACLOutput, error) {
+		assert.Equal(t, 1, len(input.ACL))
+		assert.Equal(t, ACLGranteeTypeUser, *input.ACL[0].Grantee.Type)
+		assert.Equal(t, userID, *input.ACL[0].Grantee.ID)
+		assert.Nil(t, input.ACL[0].Grantee.Name)
+		assert.Equal(t, ACLPermissionRead, *input.ACL[0].Permission)
+		return &service.PutBucketACLOutput{}, nil
+	}
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "PutACLWithContext", putFn)
+
+	err := srv.SetACL(name, []ACLRule{
+		{
+			Grantee:    ACLGrantee{Type: ACLGranteeTypeUser, ID: userID},
+			Permission: ACLPermissionRead,
+		},
+	}, pairs.WithLocation(location))
+	assert.NoError(t, err)
+
+	// Patch bucket.GetACL
+	getFn := func(_ *service.Bucket, _ context.Context) (*service.GetBucketACLOutput, error) {
+		return &service.GetBucketACLOutput{
+			ACL: []*service.ACLType{
+				{
+					Grantee: &service.GranteeType{
+						Type: service.String(ACLGranteeTypeGroup),
+						Name: service.String(ACLGroupAllUsers),
+					},
+					Permission: service.String(ACLPermissionRead),
+				},
+			},
+		}, nil
+	}
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "GetACLWithContext", getFn)
+
+	rules, err := srv.GetACL(name, pairs.WithLocation(location))
+	assert.NoError(t, err)
+	assert.Equal(t, []ACLRule{
+		{
+			Grantee:    ACLGrantee{Type: ACLGranteeTypeGroup, Name: ACLGroupAllUsers},
+			Permission: ACLPermissionRead,
+		},
+	}, rules)
+
+	// Only location in default pairs of Get is applied.
+	srv.defaultPairs.Get = []types.Pair{pairs.WithLocation(location), WithValidateBucket()}
+	_, err = srv.GetACL(name)
+	assert.NoError(t, err)
+
+	// Pairs for Get other than location are not supported.
+	_, err = srv.GetACL(name, WithValidateBucket())
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+	err = srv.SetACL(name, nil, WithDryRun())
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestService_Replicate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := NewMockService(ctrl)
+
+	srv := Service{
+		service: mockService,
+	}
+
+	src, dst := uuid.New().String(), uuid.New().String()
+	srcLocation, dstLocation := uuid.New().String(), uuid.New().String()
+	key := uuid.New().String()
+	content := []byte(uuid.New().String())
+
+	bucket := &service.Bucket{}
+	mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).DoAndReturn(func(bucketName, inputLocation string) (*service.Bucket, error) {
+		if bucketName == src {
+			assert.Equal(t, srcLocation, inputLocation)
+		} else {
+			assert.Equal(t, dst, bucketName)
+			assert.Equal(t, dstLocation, inputLocation)
+		}
+		return bucket, nil
+	}).Times(2)
+
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "ListObjectsWithContext",
+		func(_ *service.Bucket, _ context.Context, _ *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			return &service.ListObjectsOutput{
+				HasMore: service.Bool(false),
+				Keys: []*service.KeyType{
+					{Key: service.String(key), Size: service.Int64(int64(len(content)))},
+				},
+			}, nil
+		})
+	var headOutput *service.HeadObjectOutput
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "HeadObjectWithContext",
+		func(_ *service.Bucket, _ context.Context, _ string, _ *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+			if headOutput == nil {
+				return nil, &qerror.QingStorError{StatusCode: 404}
+			}
+			return headOutput, nil
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "GetObjectWithContext",
+		func(_ *service.Bucket, _ context.Context, objectKey string, _ *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Equal(t, key, objectKey)
+			return &service.GetObjectOutput{
+				Body:         ioutil.NopCloser(bytes.NewReader(content)),
+				CacheControl: service.String("no-cache"),
+			}, nil
+		})
+	var written []byte
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "PutObjectWithContext",
+		func(_ *service.Bucket, _ context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, key, objectKey)
+			assert.Equal(t, "no-cache", *input.CacheControl)
+			written, _ = ioutil.ReadAll(input.Body)
+			return &service.PutObjectOutput{}, nil
+		})
+	defer monkey.UnpatchAll()
+
+	replicated, skipped := 0, 0
+	progress := WithReplicateProgress(func(path string, size int64, ok bool) {
+		assert.Equal(t, key, path)
+		assert.Equal(t, int64(len(content)), size)
+		if ok {
+			skipped++
+		} else {
+			replicated++
+		}
+	})
+	err := srv.Replicate(src, dst, pairs.WithLocation(srcLocation), WithDstLocation(dstLocation), progress)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replicated)
+	assert.Equal(t, content, written)
+
+	// Object with the same size and modified later in dst will be skipped.
+	mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).Return(bucket, nil).Times(2)
+	headOutput = &service.HeadObjectOutput{
+		ContentLength: service.Int64(int64(len(content))),
+		LastModified:  service.Time(time.Now()),
+	}
+
+	err = srv.Replicate(src, dst, pairs.WithLocation(srcLocation), WithDstLocation(dstLocation), progress)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replicated)
+	assert.Equal(t, 1, skipped)
+
+	err = srv.Replicate(src, dst, WithDryRun())
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+	err = srv.Replicate(src, dst, WithValidateBucket())
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestService_Rename(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := NewMockService(ctrl)
+
+	srv := Service{
+		service: mockService,
+	}
+
+	oldName, newName := uuid.New().String(), uuid.New().String()
+	location := uuid.New().String()
+	key := uuid.New().String()
+
+	bucket := &service.Bucket{
+		Properties: &service.Properties{
+			BucketName: &oldName,
+			Zone:       &location,
+		},
+	}
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "ListObjectsWithContext",
+		func(_ *service.Bucket, _ context.Context, _ *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			return &service.ListObjectsOutput{
+				HasMore: service.Bool(false),
+				Keys: []*service.KeyType{
+					{Key: service.String(key)},
+				},
+			}, nil
+		})
+	uploads := []*service.UploadsType{{Key: service.String(key), UploadID: service.String(uuid.New().String())}}
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "ListMultipartUploadsWithContext",
+		func(_ *service.Bucket, _ context.Context, _ *service.ListMultipartUploadsInput) (*service.ListMultipartUploadsOutput, error) {
+			return &service.ListMultipartUploadsOutput{HasMore: service.Bool(false), Uploads: uploads}, nil
+		})
+	defer monkey.UnpatchAll()
+
+	// Test multipart uploads in progress.
+	mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).Return(bucket, nil)
+
+	_, err := srv.Rename(oldName, newName, pairs.WithLocation(location))
+	assert.True(t, errors.Is(err, ErrMultipartUploadInProgress))
+	uploads = nil
+
+	// Test dry run.
+	mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).Return(bucket, nil)
+
+	keys, err := srv.Rename(oldName, newName, pairs.WithLocation(location), WithDryRun())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{key}, keys)
+
+	// Pairs for Get other than location are not supported.
+	_, err = srv.Rename(oldName, newName, WithValidateBucket())
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	// Test rename.
+	mockService.EXPECT().Bucket(gomock.Any(), gomock.Any()).DoAndReturn(func(bucketName, inputLocation string) (*service.Bucket, error) {
+		assert.Equal(t, location, inputLocation)
+		return bucket, nil
+	}).Times(2)
+
+	created, copied, deleted := false, false, false
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "PutWithContext",
+		func(*service.Bucket, context.Context) (*service.PutBucketOutput, error) {
+			created = true
+			return &service.PutBucketOutput{}, nil
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "PutObjectWithContext",
+		func(_ *service.Bucket, _ context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, key, objectKey)
+			assert.Equal(t, "/"+oldName+"/"+key, *input.XQSCopySource)
+			copied = true
+			return &service.PutObjectOutput{}, nil
+		})
+	// Only objects copied should be deleted.
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "DeleteMultipleObjectsWithContext",
+		func(_ *service.Bucket, _ context.Context, input *service.DeleteMultipleObjectsInput) (*service.DeleteMultipleObjectsOutput, error) {
+			assert.Len(t, input.Objects, 1)
+			assert.Equal(t, key, *input.Objects[0].Key)
+			return &service.DeleteMultipleObjectsOutput{}, nil
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(bucket), "DeleteWithContext",
+		func(*service.Bucket, context.Context) (*service.DeleteBucketOutput, error) {
+			deleted = true
+			return &service.DeleteBucketOutput{}, nil
+		})
+
+	keys, err = srv.Rename(oldName, newName, pairs.WithLocation(location))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{key}, keys)
+	assert.True(t, created)
+	assert.True(t, copied)
+	assert.True(t, deleted)
 }
 
 func ExampleNew() {
@@ -407,6 +930,31 @@ func TestService_newStorage(t *testing.T) {
 			targetErr:  ErrBucketNameInvalid,
 			wantErr:    true,
 		},
+		{
+			name: "invalid copy buffer size",
+			wd:   validWorkDir,
+			args: args{[]types.Pair{
+				{Key: "location", Value: uuid.New().String()},
+				{Key: "name", Value: uuid.New().String()},
+				{Key: "copy_buffer_size", Value: 0},
+			}},
+			wantBucket: nil,
+			targetErr:  services.ErrCapabilityInsufficient,
+			wantErr:    true,
+		},
+		{
+			name: "invalid stat cache size",
+			wd:   validWorkDir,
+			args: args{[]types.Pair{
+				{Key: "location", Value: uuid.New().String()},
+				{Key: "name", Value: uuid.New().String()},
+				{Key: "stat_cache_ttl", Value: time.Minute},
+				{Key: "stat_cache_size", Value: 0},
+			}},
+			wantBucket: nil,
+			targetErr:  services.ErrCapabilityInsufficient,
+			wantErr:    true,
+		},
 		{
 			name:       "no pairs, fail when parse",
 			args:       args{},
@@ -438,4 +986,17 @@ func TestService_newStorage(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("copy buffer size capped", func(t *testing.T) {
+		s := &Service{service: mockService}
+		st, err := s.newStorage(pairs.WithName(uuid.New().String()), pairs.WithLocation(uuid.New().String()),
+			WithCopyBufferSize(copyBufferSizeMaximum*4))
+		assert.NoError(t, err)
+		assert.Equal(t, copyBufferSizeMaximum, st.copyBufferSize)
+
+		st, err = s.newStorage(pairs.WithName(uuid.New().String()), pairs.WithLocation(uuid.New().String()),
+			WithCopyBufferSize(1024))
+		assert.NoError(t, err)
+		assert.Equal(t, 1024, st.copyBufferSize)
+	})
 }
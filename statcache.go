@@ -0,0 +1,188 @@
+package qingstor
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+)
+
+// statCacheSizeDefault is the default max number of cached Stat results.
+const statCacheSizeDefault = 1024
+
+// statCache is a LRU cache of HeadObject results with TTL, keyed by the absolute path.
+//
+// Objects are built from the cached output for every Stat, so that callers could not
+// change the cached result. Objects not exist will be cached with negativeTTL, and zero
+// ttl or negativeTTL disables caching the kind of results. All methods could be called on
+// a nil cache, which caches nothing.
+//
+// A HeadObject started before the object changed could finish after the entry has been
+// invalidated, so results should be added with the generation returned by begin, and will
+// be dropped if the key has been invalidated since then.
+type statCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	negativeTTL time.Duration
+	size        int
+
+	ll      *list.List
+	entries map[string]*list.Element
+	// fetches tracks keys with HeadObject in flight, so that it's bounded by concurrent requests.
+	fetches map[string]*statCacheFetch
+}
+
+type statCacheFetch struct {
+	// n is the number of HeadObject in flight.
+	n int
+	// generation is increased every time the key is invalidated.
+	generation uint64
+}
+
+type statCacheEntry struct {
+	key    string
+	output *service.HeadObjectOutput
+	// err is the error of HeadObject while the object does not exist.
+	err    error
+	expire time.Time
+}
+
+func newStatCache(ttl, negativeTTL time.Duration, size int) *statCache {
+	return &statCache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		size:        size,
+		ll:          list.New(),
+		entries:     make(map[string]*list.Element),
+		fetches:     make(map[string]*statCacheFetch),
+	}
+}
+
+// get returns the cached entry which carries either the output or the error.
+func (c *statCache) get(key string) (*statCacheEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*statCacheEntry)
+	if time.Now().After(entry.expire) {
+		c.ll.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return entry, true
+}
+
+// begin should be called before sending HeadObject for key, the returned generation should be
+// passed to add, addNotExist or done after the request finished.
+func (c *statCache) begin(key string) uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, ok := c.fetches[key]
+	if !ok {
+		f = &statCacheFetch{}
+		c.fetches[key] = f
+	}
+	f.n++
+	return f.generation
+}
+
+func (c *statCache) add(key string, generation uint64, output *service.HeadObjectOutput) {
+	if c == nil {
+		return
+	}
+	var entry *statCacheEntry
+	if c.ttl > 0 {
+		entry = &statCacheEntry{key: key, output: output, expire: time.Now().Add(c.ttl)}
+	}
+	c.finish(key, generation, entry)
+}
+
+// addNotExist will cache err of HeadObject for the object which does not exist.
+func (c *statCache) addNotExist(key string, generation uint64, err error) {
+	if c == nil {
+		return
+	}
+	var entry *statCacheEntry
+	if c.negativeTTL > 0 {
+		entry = &statCacheEntry{key: key, err: err, expire: time.Now().Add(c.negativeTTL)}
+	}
+	c.finish(key, generation, entry)
+}
+
+// done should be called if the result of HeadObject will not be cached.
+func (c *statCache) done(key string, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.finish(key, generation, nil)
+}
+
+// finish will end the fetch of key, and cache entry if key has not been invalidated since the
+// fetch began.
+func (c *statCache) finish(key string, generation uint64, entry *statCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, ok := c.fetches[key]
+	if !ok {
+		return
+	}
+	f.n--
+	if f.n <= 0 {
+		delete(c.fetches, key)
+	}
+	if entry == nil || f.generation != generation {
+		return
+	}
+	c.put(entry)
+}
+
+// put should be called with the lock held.
+func (c *statCache) put(entry *statCacheEntry) {
+	if e, ok := c.entries[entry.key]; ok {
+		e.Value = entry
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.entries[entry.key] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.entries, e.Value.(*statCacheEntry).key)
+	}
+}
+
+// invalidate will remove the cached result of key, it should be called after the object
+// has been changed.
+func (c *statCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.ll.Remove(e)
+		delete(c.entries, key)
+	}
+	// Results of HeadObject in flight are outdated now.
+	if f, ok := c.fetches[key]; ok {
+		f.generation++
+	}
+}
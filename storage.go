@@ -2,13 +2,26 @@ package qingstor
 
 import (
 	"context"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pengsrc/go-shared/convert"
+	qserror "github.com/qingstor/qingstor-sdk-go/v4/request/errors"
 	"github.com/qingstor/qingstor-sdk-go/v4/service"
 
 	ps "github.com/beyondstorage/go-storage/v4/pairs"
@@ -22,6 +35,45 @@ func (s *Storage) commitAppend(ctx context.Context, o *Object, opt pairStorageCo
 }
 
 func (s *Storage) completeMultipart(ctx context.Context, o *Object, parts []*Part, opt pairStorageCompleteMultipart) (err error) {
+	// Parts are validated before completing, errors returned by server don't tell which part is wrong.
+	parts, err = validateParts(parts)
+	if err != nil {
+		return
+	}
+	if s.keyProvider != nil {
+		if err = validateEncryptedParts(parts); err != nil {
+			return
+		}
+	}
+
+	err = s.completeMultipartUpload(ctx, o, parts)
+	if err != nil {
+		return
+	}
+
+	// Attributes of the completed object are not returned, so HEAD is sent to populate them
+	// and callers don't need to Stat again. The upload has been completed, so failures of HEAD,
+	// like objects encrypted with customer key, are ignored.
+	output, herr := s.headObject(ctx, o.ID, &service.HeadObjectInput{}, false)
+	if herr != nil {
+		var size int64
+		for _, v := range parts {
+			if s.keyProvider != nil {
+				// Every part is encrypted separately.
+				size += decryptedSize(v.Size)
+			} else {
+				size += v.Size
+			}
+		}
+		o.SetContentLength(size)
+		return nil
+	}
+	s.setObjectAttributes(o, output)
+	return nil
+}
+
+// completeMultipartUpload will complete the multipart upload without populating attributes of o.
+func (s *Storage) completeMultipartUpload(ctx context.Context, o *Object, parts []*Part) (err error) {
 	objectParts := make([]*service.ObjectPartType, 0, len(parts))
 	for _, v := range parts {
 		objectParts = append(objectParts, &service.ObjectPartType{
@@ -31,6 +83,8 @@ func (s *Storage) completeMultipart(ctx context.Context, o *Object, parts []*Par
 		})
 	}
 
+	defer s.statCache.invalidate(o.ID)
+
 	_, err = s.bucket.CompleteMultipartUploadWithContext(ctx, o.ID, &service.CompleteMultipartUploadInput{
 		UploadID:    service.String(o.MustGetMultipartID()),
 		ObjectParts: objectParts,
@@ -43,14 +97,106 @@ func (s *Storage) completeMultipart(ctx context.Context, o *Object, parts []*Par
 	return
 }
 
+// validateParts will check that parts are within index bounds, contiguous without duplicates,
+// and not empty except the last one, and returns a copy of parts sorted by index.
+func validateParts(parts []*Part) ([]*Part, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no parts: %w", ErrPartsInvalid)
+	}
+
+	sorted := make([]*Part, len(parts))
+	copy(sorted, parts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Index < sorted[j].Index
+	})
+
+	var problems []string
+	for i, p := range sorted {
+		if p.Index < multipartNumberMinimum || p.Index > multipartNumberMaximum {
+			problems = append(problems, fmt.Sprintf("part %d is out of range", p.Index))
+		}
+		if i > 0 {
+			prev := sorted[i-1].Index
+			switch {
+			case p.Index == prev:
+				problems = append(problems, fmt.Sprintf("part %d is duplicated", p.Index))
+			case p.Index == prev+2:
+				problems = append(problems, fmt.Sprintf("part %d is missing", prev+1))
+			case p.Index > prev+2:
+				problems = append(problems, fmt.Sprintf("parts %d-%d are missing", prev+1, p.Index-1))
+			}
+		}
+		if p.Size <= 0 && i != len(sorted)-1 {
+			problems = append(problems, fmt.Sprintf("part %d is empty", p.Index))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s: %w", strings.Join(problems, ", "), ErrPartsInvalid)
+	}
+	return sorted, nil
+}
+
+// validateEncryptedParts will check that sorted parts encrypted by client could be decrypted
+// as a whole, which means parts must start from 0, and every part except the last one must
+// be aligned to the chunk size.
+func validateEncryptedParts(parts []*Part) error {
+	if parts[0].Index != multipartNumberMinimum {
+		return fmt.Errorf("encrypted parts must start from %d: %w", multipartNumberMinimum, ErrPartsInvalid)
+	}
+	for _, p := range parts[:len(parts)-1] {
+		if decryptedSize(p.Size)%clientEncryptionChunkSize != 0 || p.Size != encryptedSize(decryptedSize(p.Size)) {
+			return fmt.Errorf("size of encrypted part %d is not aligned to %d: %w", p.Index, clientEncryptionChunkSize, ErrPartsInvalid)
+		}
+	}
+	return nil
+}
+
+// metadataDirectiveReplace means the metadata of the source object will be replaced by
+// the metadata in copy request.
+const metadataDirectiveReplace = "REPLACE"
+
 func (s *Storage) copy(ctx context.Context, src string, dst string, opt pairStorageCopy) (err error) {
-	rs := s.getAbsPath(src)
+	return s.copyFrom(ctx, s, s.getAbsPath(src), dst, opt)
+}
+
+// copySourcePath will return the copy source of the object rs in the bucket of s.
+func (s *Storage) copySourcePath(rs string) string {
+	return "/" + service.StringValue(s.properties.BucketName) + "/" + url.QueryEscape(rs)
+}
+
+// copyFrom will copy the object rs in the bucket of from into dst on server side, from could
+// be s itself or the storage of another bucket in the same zone.
+func (s *Storage) copyFrom(ctx context.Context, from *Storage, rs string, dst string, opt pairStorageCopy) (err error) {
+	if err = checkStorageClass(opt.HasStorageClass, opt.StorageClass); err != nil {
+		return
+	}
+
 	rd := s.getAbsPath(dst)
+	defer s.statCache.invalidate(rd)
 
-	srcPath := "/" + service.StringValue(s.properties.BucketName) + "/" + url.QueryEscape(rs)
+	srcPath := from.copySourcePath(rs)
 	input := &service.PutObjectInput{
 		XQSCopySource: &srcPath,
 	}
+	putCtx := ctx
+	if (from == s && rs == rd) || opt.HasContentDisposition {
+		// QingStor doesn't allow copying object to itself without replacing metadata, and metadata
+		// of the source is replaced as a whole while content_disposition is set, so headers of the
+		// source are read and sent again.
+		var getInput *service.GetObjectInput
+		getInput, err = formatCopySourceGetInput(opt)
+		if err != nil {
+			return
+		}
+		output, size, err := from.getObjectHeaders(ctx, rs, getInput)
+		if err != nil {
+			return err
+		}
+		if size > copySizeMaximum {
+			return s.copyMultipart(ctx, srcPath, dst, output, size, opt)
+		}
+		putCtx, input = from.formatSelfCopyInput(ctx, rs, output)
+	}
 	if opt.HasEncryptionCustomerAlgorithm {
 		input.XQSEncryptionCustomerAlgorithm, input.XQSEncryptionCustomerKey, input.XQSEncryptionCustomerKeyMD5, err = calculateEncryptionHeaders(opt.EncryptionCustomerAlgorithm, opt.EncryptionCustomerKey)
 		if err != nil {
@@ -63,12 +209,51 @@ func (s *Storage) copy(ctx context.Context, src string, dst string, opt pairStor
 			return
 		}
 	}
+	if opt.HasStorageClass {
+		// Objects could be transitioned to another storage class by copying to itself.
+		input.XQSStorageClass = service.String(opt.StorageClass)
+	}
+	if opt.HasContentDisposition {
+		// Metadata of the source has been replaced with the headers read above.
+		putCtx = withRequestHeader(putCtx, "Content-Disposition", opt.ContentDisposition)
+	}
 
-	_, err = s.bucket.PutObjectWithContext(ctx, rd, input)
-	if err != nil {
+	_, err = s.bucket.PutObjectWithContext(putCtx, rd, input)
+	if err == nil || !isCopyRejected(err) {
 		return
 	}
-	return nil
+
+	// Size of source is only checked after the copy rejected, so that no extra request is sent
+	// for objects smaller than the limit. Objects larger than the limit will be copied part by
+	// part, otherwise the error of copy is returned.
+	getInput, gerr := formatCopySourceGetInput(opt)
+	if gerr != nil {
+		return
+	}
+	output, size, gerr := from.getObjectHeaders(ctx, rs, getInput)
+	if gerr != nil || size <= copySizeMaximum {
+		return
+	}
+	return s.copyMultipart(ctx, srcPath, dst, output, size, opt)
+}
+
+// formatCopySourceGetInput will build the input to read the source of copy.
+func formatCopySourceGetInput(opt pairStorageCopy) (input *service.GetObjectInput, err error) {
+	input = &service.GetObjectInput{}
+	if opt.HasCopySourceEncryptionCustomerAlgorithm {
+		input.XQSEncryptionCustomerAlgorithm, input.XQSEncryptionCustomerKey, input.XQSEncryptionCustomerKeyMD5, err = calculateEncryptionHeaders(opt.CopySourceEncryptionCustomerAlgorithm, opt.CopySourceEncryptionCustomerKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return input, nil
+}
+
+// isCopyRejected will check whether the copy request is rejected as a bad request, which is
+// returned by QingStor while the source is larger than copySizeMaximum.
+func isCopyRejected(err error) bool {
+	var e *qserror.QingStorError
+	return errors.As(err, &e) && (e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusRequestEntityTooLarge)
 }
 
 func (s *Storage) create(path string, opt pairStorageCreate) (o *Object) {
@@ -100,7 +285,12 @@ func (s *Storage) create(path string, opt pairStorageCreate) (o *Object) {
 }
 
 func (s *Storage) createAppend(ctx context.Context, path string, opt pairStorageCreateAppend) (o *Object, err error) {
+	if err = checkStorageClass(opt.HasStorageClass, opt.StorageClass); err != nil {
+		return
+	}
+
 	rp := s.getAbsPath(path)
+	defer s.statCache.invalidate(rp)
 
 	// We should set offset to 0 whether the object exists or not.
 	var offset int64 = 0
@@ -109,7 +299,7 @@ func (s *Storage) createAppend(ctx context.Context, path string, opt pairStorage
 	headInput := &service.HeadObjectInput{}
 	_, err = s.bucket.HeadObjectWithContext(ctx, rp, headInput)
 	if err == nil {
-		_, err = s.bucket.DeleteObject(rp)
+		_, err = s.bucket.DeleteObjectWithContext(ctx, rp)
 		if err != nil {
 			return nil, err
 		}
@@ -150,10 +340,14 @@ func (s *Storage) createAppend(ctx context.Context, path string, opt pairStorage
 	if opt.HasStorageClass {
 		sm.StorageClass = opt.StorageClass
 	}
+	o.SetSystemMetadata(sm)
 	return o, nil
 }
 
 func (s *Storage) createDir(ctx context.Context, path string, opt pairStorageCreateDir) (o *Object, err error) {
+	if err = checkStorageClass(opt.HasStorageClass, opt.StorageClass); err != nil {
+		return
+	}
 	if !s.features.VirtualDir {
 		err = NewOperationNotImplementedError("create_dir")
 		return
@@ -164,6 +358,7 @@ func (s *Storage) createDir(ctx context.Context, path string, opt pairStorageCre
 	// Add `/` at the end of path to simulate a directory.
 	// ref: https://docs.qingcloud.com/qingstor/api/object/put.html
 	rp += "/"
+	defer s.statCache.invalidate(rp)
 
 	input := &service.PutObjectInput{
 		ContentLength: service.Int64(0),
@@ -190,6 +385,7 @@ const metadataLinkTargetHeader = "x-qs-meta-bs-link-target"
 func (s *Storage) createLink(ctx context.Context, path string, target string, opt pairStorageCreateLink) (o *Object, err error) {
 	rt := s.getAbsPath(target)
 	rp := s.getAbsPath(path)
+	defer s.statCache.invalidate(rp)
 
 	input := &service.PutObjectInput{
 		// As qingstor does not support symlink, we can only use user-defined metadata to simulate it.
@@ -221,16 +417,65 @@ func (s *Storage) createLink(ctx context.Context, path string, target string, op
 }
 
 func (s *Storage) createMultipart(ctx context.Context, path string, opt pairStorageCreateMultipart) (o *Object, err error) {
+	if err = checkStorageClass(opt.HasStorageClass, opt.StorageClass); err != nil {
+		return
+	}
+
 	input := &service.InitiateMultipartUploadInput{}
+	if opt.HasStorageClass {
+		input.XQSStorageClass = service.String(opt.StorageClass)
+	}
+	if opt.HasContentType {
+		input.ContentType = service.String(opt.ContentType)
+	}
 	if opt.HasEncryptionCustomerAlgorithm {
 		input.XQSEncryptionCustomerAlgorithm, input.XQSEncryptionCustomerKey, input.XQSEncryptionCustomerKeyMD5, err = calculateEncryptionHeaders(opt.EncryptionCustomerAlgorithm, opt.EncryptionCustomerKey)
 		if err != nil {
 			return
 		}
 	}
+	if opt.HasUserMetadata {
+		input.XQSMetaData = formatUserMetadata(opt.UserMetadata)
+	}
+	if opt.HasContentSha256 {
+		if !isSha256Valid(opt.ContentSha256) {
+			err = services.PairUnsupportedError{Pair: WithContentSha256(opt.ContentSha256)}
+			return
+		}
+		if input.XQSMetaData == nil {
+			input.XQSMetaData = &map[string]string{}
+		}
+		(*input.XQSMetaData)[metadataContentSha256] = strings.ToLower(opt.ContentSha256)
+	}
+	var cseMetadata map[string]string
+	if s.keyProvider != nil {
+		_, cseMetadata, err = s.newDataKey(ctx)
+		if err != nil {
+			return
+		}
+		if input.XQSMetaData == nil {
+			input.XQSMetaData = &map[string]string{}
+		}
+		for k, v := range cseMetadata {
+			(*input.XQSMetaData)[k] = v
+		}
+	}
 
 	rp := s.getAbsPath(path)
 
+	if opt.HasCacheControl {
+		ctx = withRequestHeader(ctx, "Cache-Control", opt.CacheControl)
+	}
+	if opt.HasContentDisposition {
+		ctx = withRequestHeader(ctx, "Content-Disposition", opt.ContentDisposition)
+	}
+	if opt.HasContentEncoding {
+		ctx = withRequestHeader(ctx, "Content-Encoding", opt.ContentEncoding)
+	}
+	if opt.HasExpires {
+		ctx = withRequestHeader(ctx, "Expires", opt.Expires.UTC().Format(http.TimeFormat))
+	}
+
 	output, err := s.bucket.InitiateMultipartUploadWithContext(ctx, rp, input)
 	if err != nil {
 		return
@@ -241,6 +486,25 @@ func (s *Storage) createMultipart(ctx context.Context, path string, opt pairStor
 	o.Path = path
 	o.Mode |= ModePart
 	o.SetMultipartID(*output.UploadID)
+	// Record the attributes of the object to be completed, like the ones written by a single PUT.
+	if opt.HasContentType {
+		o.SetContentType(opt.ContentType)
+	}
+	if opt.HasUserMetadata && len(opt.UserMetadata) > 0 {
+		o.SetUserMetadata(opt.UserMetadata)
+	}
+	var sm ObjectSystemMetadata
+	if opt.HasStorageClass {
+		sm.StorageClass = opt.StorageClass
+	}
+	if opt.HasEncryptionCustomerAlgorithm {
+		sm.EncryptionCustomerAlgorithm = opt.EncryptionCustomerAlgorithm
+	}
+	if cseMetadata != nil {
+		// Carry the wrapped data key so that parts could be encrypted by the same key.
+		sm.ClientEncryptionKey = cseMetadata[metadataClientEncryptionKey]
+	}
+	o.SetSystemMetadata(sm)
 
 	return o, nil
 }
@@ -272,6 +536,8 @@ func (s *Storage) delete(ctx context.Context, path string, opt pairStorageDelete
 		rp += "/"
 	}
 
+	defer s.statCache.invalidate(rp)
+
 	// QingStor DeleteObject is idempotent, so we don't need to check object_not_exists error.
 	//
 	// - [GSP-46](https://github.com/beyondstorage/specs/blob/master/rfcs/46-idempotent-delete.md)
@@ -284,6 +550,8 @@ func (s *Storage) delete(ctx context.Context, path string, opt pairStorageDelete
 }
 
 func (s *Storage) fetch(ctx context.Context, path string, url string, opt pairStorageFetch) (err error) {
+	defer s.statCache.invalidate(s.getAbsPath(path))
+
 	_, err = s.bucket.PutObjectWithContext(ctx, path, &service.PutObjectInput{
 		XQSFetchSource: service.String(url),
 	})
@@ -291,17 +559,73 @@ func (s *Storage) fetch(ctx context.Context, path string, url string, opt pairSt
 }
 
 func (s *Storage) list(ctx context.Context, path string, opt pairStorageList) (oi *ObjectIterator, err error) {
+	// Size of content encrypted by client is only known after stated.
+	return s.listObjects(ctx, path, opt, s.keyProvider != nil)
+}
+
+// listListed will list objects under path like List, but only attributes returned by List will
+// be set in listed objects even if key_provider is set, which is used internally to avoid
+// sending HEAD for every object.
+func (s *Storage) listListed(ctx context.Context, path string, pairs ...Pair) (oi *ObjectIterator, err error) {
+	defer func() {
+		err = s.formatError("list", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.List...)
+	opt, err := s.parsePairStorageList(pairs)
+	if err != nil {
+		return
+	}
+	return s.listObjects(ctx, strings.ReplaceAll(path, "\\", "/"), opt, false)
+}
+
+// listObjects will list objects under path, listed objects will be stated if stat is true or
+// include_metadata is set.
+func (s *Storage) listObjects(ctx context.Context, path string, opt pairStorageList, stat bool) (oi *ObjectIterator, err error) {
 	input := &objectPageStatus{
 		limit:  200,
 		prefix: s.getAbsPath(path),
 	}
 
+	if opt.HasPageSize {
+		if opt.PageSize <= 0 {
+			return nil, services.PairUnsupportedError{Pair: WithPageSize(opt.PageSize)}
+		}
+		input.limit = opt.PageSize
+	}
+
+	prefixesOnly := opt.HasPrefixesOnly && opt.PrefixesOnly
+	if prefixesOnly {
+		// Common prefixes are only returned in dir mode.
+		if !opt.HasListMode {
+			opt.HasListMode, opt.ListMode = true, ListModeDir
+		}
+		if !opt.ListMode.IsDir() {
+			return nil, services.PairUnsupportedError{Pair: ps.WithListMode(opt.ListMode)}
+		}
+		input.prefixesOnly = true
+	}
+
 	if !opt.HasListMode {
 		// Support `ListModePrefix` as the default `ListMode`.
 		// ref: [GSP-654](https://github.com/beyondstorage/go-storage/blob/master/docs/rfcs/654-unify-list-behavior.md)
 		opt.ListMode = ListModePrefix
 	}
 
+	// The continuation token is the marker returned by ObjectIterator.ContinuationToken, which
+	// is the start of next page. In prefix and dir mode, ID of the last consumed object could be
+	// used as the token too.
+	if opt.HasContinuationToken {
+		input.marker = opt.ContinuationToken
+		// Markers of multipart uploads are formatted like "<key>/<upload_id>", and upload id
+		// contains no "/".
+		if opt.ListMode.IsPart() {
+			if idx := strings.LastIndex(input.marker, "/"); idx >= 0 {
+				input.marker, input.partIdMarker = input.marker[:idx], input.marker[idx+1:]
+			}
+		}
+	}
+
 	var nextFn NextObjectFunc
 
 	switch {
@@ -316,6 +640,42 @@ func (s *Storage) list(ctx context.Context, path string, opt pairStorageList) (o
 		return nil, services.ListModeInvalidError{Actual: opt.ListMode}
 	}
 
+	keep, err := listFilter(opt)
+	if err != nil {
+		return
+	}
+	if prefixesOnly {
+		// Keys are skipped while fetching, and pages without common prefixes will be skipped
+		// by the filter.
+		filter := keep
+		keep = func(o *Object) bool {
+			return o.Mode.IsDir() && (filter == nil || filter(o))
+		}
+	}
+	if opt.HasListRetry {
+		nextFn = retryObjectPage(nextFn, opt.ListRetry)
+	}
+	if opt.HasListSorted && opt.ListSorted {
+		// Multipart uploads of the same key are listed with the same ID.
+		if opt.ListMode.IsPart() {
+			return nil, services.PairUnsupportedError{Pair: WithListSorted()}
+		}
+		nextFn = sortedObjectPage(nextFn)
+	}
+	nextFn = filterObjectPage(contextObjectPage(nextFn), keep)
+	if opt.HasIncludeMetadata && opt.IncludeMetadata {
+		if opt.ListMode.IsPart() {
+			return nil, services.PairUnsupportedError{Pair: WithIncludeMetadata()}
+		}
+		stat = true
+	}
+	if stat && !opt.ListMode.IsPart() {
+		concurrency := listConcurrencyDefault
+		if opt.HasListConcurrency && opt.ListConcurrency > 0 {
+			concurrency = opt.ListConcurrency
+		}
+		nextFn = s.statObjectPage(nextFn, concurrency)
+	}
 	return NewObjectIterator(ctx, nextFn, input), nil
 }
 
@@ -326,10 +686,48 @@ func (s *Storage) listMultipart(ctx context.Context, o *Object, opt pairStorageL
 		uploadID: o.MustGetMultipartID(),
 	}
 
+	if opt.HasPageSize {
+		if opt.PageSize <= 0 {
+			return nil, services.PairUnsupportedError{Pair: WithPageSize(opt.PageSize)}
+		}
+		input.limit = opt.PageSize
+	}
+	// The continuation token is the part number marker returned by PartIterator.ContinuationToken,
+	// which is the number of parts have been listed.
+	if opt.HasContinuationToken {
+		marker, err := strconv.Atoi(opt.ContinuationToken)
+		if err != nil || marker < 0 {
+			return nil, services.PairUnsupportedError{Pair: ps.WithContinuationToken(opt.ContinuationToken)}
+		}
+		input.partNumberMarker = marker
+	}
+
 	return NewPartIterator(ctx, s.nextPartPage, input), nil
 }
 
 func (s *Storage) metadata(opt pairStorageMetadata) (meta *StorageMeta) {
+	// Metadata is a local function which could not return error, so statistics will be left
+	// empty if we failed to get them. MetadataWithContext should be used to get the error.
+	meta, _ = s.metadataWithContext(context.Background(), opt)
+	return meta
+}
+
+// MetadataWithContext will return current storager metadata like Metadata, the error will be
+// returned if failed to get bucket statistics with statistics.
+func (s *Storage) MetadataWithContext(ctx context.Context, pairs ...Pair) (meta *StorageMeta, err error) {
+	defer func() {
+		err = s.formatError("metadata", err)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Metadata...)
+	opt, err := s.parsePairStorageMetadata(pairs)
+	if err != nil {
+		return
+	}
+	return s.metadataWithContext(ctx, opt)
+}
+
+func (s *Storage) metadataWithContext(ctx context.Context, opt pairStorageMetadata) (meta *StorageMeta, err error) {
 	meta = NewStorageMeta()
 	meta.Name = *s.properties.BucketName
 	meta.WorkDir = s.workDir
@@ -345,12 +743,33 @@ func (s *Storage) metadata(opt pairStorageMetadata) (meta *StorageMeta) {
 	meta.SetMultipartNumberMaximum(multipartNumberMaximum)
 	meta.SetMultipartSizeMaximum(multipartSizeMaximum)
 	meta.SetMultipartSizeMinimum(multipartSizeMinimum)
-	return meta
+
+	sm := s.systemMetadata
+	defer func() {
+		meta.SetSystemMetadata(sm)
+	}()
+	if opt.HasStatistics && opt.Statistics {
+		var output *service.GetBucketStatisticsOutput
+		output, err = s.bucket.GetStatisticsWithContext(ctx)
+		if err != nil {
+			return meta, err
+		}
+		sm.Count = service.Int64Value(output.Count)
+		sm.Size = service.Int64Value(output.Size)
+		sm.Status = service.StringValue(output.Status)
+		sm.Created = service.TimeValue(output.Created)
+		sm.URL = service.StringValue(output.URL)
+	}
+	return meta, nil
 }
 
 func (s *Storage) move(ctx context.Context, src string, dst string, opt pairStorageMove) (err error) {
 	rs := s.getAbsPath(src)
 	rd := s.getAbsPath(dst)
+	defer func() {
+		s.statCache.invalidate(rs)
+		s.statCache.invalidate(rd)
+	}()
 
 	srcPath := "/" + service.StringValue(s.properties.BucketName) + "/" + url.QueryEscape(rs)
 	_, err = s.bucket.PutObjectWithContext(ctx, rd, &service.PutObjectInput{
@@ -385,11 +804,14 @@ func (s *Storage) nextObjectPageByDir(ctx context.Context, page *ObjectPage) err
 	}
 
 	for _, v := range output.Keys {
+		if input.prefixesOnly {
+			break
+		}
 		// add filter to exclude dir-key itself, which would exist if created in console, see issue #365
 		if convert.StringValue(v.Key) == input.prefix {
 			continue
 		}
-		o, err := s.formatFileObject(v)
+		o, err := s.formatFileObject(v, output.Owner)
 		if err != nil {
 			return err
 		}
@@ -424,7 +846,7 @@ func (s *Storage) nextObjectPageByPrefix(ctx context.Context, page *ObjectPage)
 	}
 
 	for _, v := range output.Keys {
-		o, err := s.formatFileObject(v)
+		o, err := s.formatFileObject(v, output.Owner)
 		if err != nil {
 			return err
 		}
@@ -465,6 +887,15 @@ func (s *Storage) nextPartObjectPageByPrefix(ctx context.Context, page *ObjectPa
 		o.Path = s.getRelPath(*v.Key)
 		o.Mode |= ModePart
 		o.SetMultipartID(*v.UploadID)
+		// Initiated time helps callers to find abandoned uploads, and is used as the last
+		// modified time so that uploads could be filtered by modified_before.
+		if v.Created != nil {
+			o.SetLastModified(*v.Created)
+
+			var sm ObjectSystemMetadata
+			sm.Initiated = *v.Created
+			o.SetSystemMetadata(sm)
+		}
 
 		page.Data = append(page.Data, o)
 	}
@@ -487,33 +918,37 @@ func (s *Storage) nextPartObjectPageByPrefix(ctx context.Context, page *ObjectPa
 func (s *Storage) nextPartPage(ctx context.Context, page *PartPage) error {
 	input := page.Status.(*partPageStatus)
 
+	parts, err := s.fetchPartPage(ctx, input)
+	if err != nil && err != IterateDone {
+		return err
+	}
+
+	for _, v := range parts {
+		page.Data = append(page.Data, formatPart(v))
+	}
+	return err
+}
+
+// fetchPartPage will fetch a page of uploaded parts, and returns IterateDone along with the
+// last page.
+func (s *Storage) fetchPartPage(ctx context.Context, input *partPageStatus) ([]*service.ObjectPartType, error) {
 	output, err := s.bucket.ListMultipartWithContext(ctx, input.prefix, &service.ListMultipartInput{
 		Limit:            &input.limit,
 		PartNumberMarker: &input.partNumberMarker,
 		UploadID:         &input.uploadID,
 	})
 	if err != nil {
-		return err
-	}
-
-	for _, v := range output.ObjectParts {
-		p := &Part{
-			Index: *v.PartNumber,
-			Size:  *v.Size,
-			ETag:  service.StringValue(v.Etag),
-		}
-
-		page.Data = append(page.Data, p)
+		return nil, err
 	}
 
 	// FIXME: QingStor ListMulitpart API looks like buggy.
 	offset := input.partNumberMarker + len(output.ObjectParts)
 	if offset >= service.IntValue(output.Count) {
-		return IterateDone
+		return output.ObjectParts, IterateDone
 	}
 
 	input.partNumberMarker = offset
-	return nil
+	return output.ObjectParts, nil
 }
 
 func (s *Storage) querySignHTTPDelete(ctx context.Context, path string, expire time.Duration, opt pairStorageQuerySignHTTPDelete) (req *http.Request, err error) {
@@ -555,6 +990,9 @@ func (s *Storage) querySignHTTPWrite(ctx context.Context, path string, size int6
 	if err != nil {
 		return
 	}
+	if err = checkStorageClass(pairs.HasStorageClass, pairs.StorageClass); err != nil {
+		return
+	}
 
 	input, err := s.formatPutObjectInput(size, pairs)
 	if err != nil {
@@ -581,6 +1019,10 @@ func (s *Storage) querySignHTTPWrite(ctx context.Context, path string, size int6
 }
 
 func (s *Storage) reach(ctx context.Context, path string, opt pairStorageReach) (url string, err error) {
+	if opt.HasImageProcess {
+		return s.reachImage(ctx, path, opt)
+	}
+
 	// FIXME: sdk should export GetObjectRequest as interface too?
 	bucket := s.bucket.(*service.Bucket)
 
@@ -602,25 +1044,13 @@ func (s *Storage) reach(ctx context.Context, path string, opt pairStorageReach)
 }
 
 func (s *Storage) read(ctx context.Context, path string, w io.Writer, opt pairStorageRead) (n int64, err error) {
-	input, err := s.formatGetObjectInput(opt)
+	rc, _, err := s.openReader(ctx, path, opt)
 	if err != nil {
 		return
 	}
+	defer rc.Close()
 
-	rp := s.getAbsPath(path)
-
-	output, err := s.bucket.GetObjectWithContext(ctx, rp, input)
-	if err != nil {
-		return n, err
-	}
-	defer output.Body.Close()
-
-	rc := output.Body
-	if opt.HasIoCallback {
-		rc = iowrap.CallbackReadCloser(rc, opt.IoCallback)
-	}
-
-	return io.Copy(w, rc)
+	return s.copyBuffer(w, rc, opt.CopyBufferSize)
 }
 
 func (s *Storage) stat(ctx context.Context, path string, opt pairStorageStat) (o *Object, err error) {
@@ -645,18 +1075,33 @@ func (s *Storage) stat(ctx context.Context, path string, opt pairStorageStat) (o
 		return o, nil
 	}
 
+	// Path with trailing slash will be treated as a directory while virtual dir is enabled.
+	isDir := s.features.VirtualDir && strings.HasSuffix(rp, "/")
 	if opt.HasObjectMode && opt.ObjectMode.IsDir() {
 		if !s.features.VirtualDir {
 			err = services.PairUnsupportedError{Pair: ps.WithObjectMode(opt.ObjectMode)}
 			return
 		}
 
-		rp += "/"
+		if !isDir {
+			rp += "/"
+		}
+		isDir = true
 	}
 
 	input := &service.HeadObjectInput{}
-	output, err := s.bucket.HeadObjectWithContext(ctx, rp, input)
+	if opt.HasEncryptionCustomerAlgorithm {
+		input.XQSEncryptionCustomerAlgorithm, input.XQSEncryptionCustomerKey, input.XQSEncryptionCustomerKeyMD5, err = calculateEncryptionHeaders(opt.EncryptionCustomerAlgorithm, opt.EncryptionCustomerKey)
+		if err != nil {
+			return
+		}
+	}
+	// Results with encryption customer key are not cached, since the key is not verified by cache.
+	output, err := s.headObject(ctx, rp, input, !opt.HasEncryptionCustomerAlgorithm)
 	if err != nil {
+		if opt.HasImplicitDir && opt.ImplicitDir && strings.HasSuffix(rp, "/") && errors.Is(formatError(err), services.ErrObjectNotExist) {
+			return s.statImplicitDir(ctx, path, rp, err)
+		}
 		return
 	}
 
@@ -681,14 +1126,32 @@ func (s *Storage) stat(ctx context.Context, path string, opt pairStorageStat) (o
 		}
 	}
 
+	// Directory objects created in console carry the directory mime type without trailing slash.
+	if s.features.VirtualDir && service.StringValue(output.ContentType) == mimeTypeDirectory {
+		isDir = true
+	}
 	if o.Mode&ModeLink == 0 && o.Mode&ModeRead == 0 {
-		if opt.HasObjectMode && opt.ObjectMode.IsDir() {
+		if isDir {
 			o.Mode |= ModeDir
 		} else {
 			o.Mode |= ModeRead
 		}
 	}
 
+	// Next append position only returns for appendable object, carry it so that
+	// callers could continue appending to this object.
+	if output.XQSNextAppendPosition != nil {
+		o.Mode |= ModeAppend
+		o.SetAppendOffset(*output.XQSNextAppendPosition)
+	}
+	s.setObjectAttributes(o, output)
+
+	return o, nil
+}
+
+// setObjectAttributes will set content attributes and system metadata returned by HeadObject
+// on o.
+func (s *Storage) setObjectAttributes(o *Object, output *service.HeadObjectOutput) {
 	o.SetContentLength(service.Int64Value(output.ContentLength))
 	o.SetLastModified(service.TimeValue(output.LastModified))
 
@@ -699,47 +1162,263 @@ func (s *Storage) stat(ctx context.Context, path string, opt pairStorageStat) (o
 		o.SetEtag(service.StringValue(output.ETag))
 	}
 
-	var sm ObjectSystemMetadata
-	if v := service.StringValue(output.XQSStorageClass); v != "" {
-		sm.StorageClass = v
+	if output.XQSMetaData != nil {
+		if um := parseUserMetadata(*output.XQSMetaData); len(um) > 0 {
+			o.SetUserMetadata(um)
+		}
 	}
-	if v := service.StringValue(output.XQSEncryptionCustomerAlgorithm); v != "" {
-		sm.EncryptionCustomerAlgorithm = v
+
+	sm := s.formatSystemMetadata(output.XQSStorageClass, output.XQSEncryptionCustomerAlgorithm, output.XQSMetaData)
+	if sm.ClientEncryptionKey != "" {
+		// Content length should be the size before encrypted.
+		o.SetContentLength(decryptedSize(service.Int64Value(output.ContentLength)))
 	}
 	o.SetSystemMetadata(sm)
+}
 
-	return o, nil
+// headObject will send HeadObject for rp, results will be cached in statCache if cacheable.
+func (s *Storage) headObject(ctx context.Context, rp string, input *service.HeadObjectInput, cacheable bool) (output *service.HeadObjectOutput, err error) {
+	if cacheable {
+		if entry, ok := s.statCache.get(rp); ok {
+			return entry.output, entry.err
+		}
+	}
+
+	if !cacheable {
+		return s.bucket.HeadObjectWithContext(ctx, rp, input)
+	}
+
+	generation := s.statCache.begin(rp)
+	output, err = s.bucket.HeadObjectWithContext(ctx, rp, input)
+	if err != nil {
+		// Keys not exist yet could be polled frequently, cache them to avoid requests.
+		if errors.Is(formatError(err), services.ErrObjectNotExist) {
+			s.statCache.addNotExist(rp, generation, err)
+		} else {
+			s.statCache.done(rp, generation)
+		}
+		return
+	}
+	s.statCache.add(rp, generation, output)
+	return output, nil
 }
 
-func (s *Storage) write(ctx context.Context, path string, r io.Reader, size int64, opt pairStorageWrite) (n int64, err error) {
-	if size > writeSizeMaximum {
-		err = fmt.Errorf("size limit exceeded: %w", services.ErrRestrictionDissatisfied)
+// statImplicitDir will return a directory object for rp if any key exists under it, otherwise
+// err of HeadObject will be returned.
+func (s *Storage) statImplicitDir(ctx context.Context, path, rp string, headErr error) (o *Object, err error) {
+	output, err := s.bucket.ListObjectsWithContext(ctx, &service.ListObjectsInput{
+		Limit:  service.Int(1),
+		Prefix: service.String(rp),
+	})
+	if err != nil {
 		return
 	}
+	if len(output.Keys) == 0 && len(output.CommonPrefixes) == 0 {
+		return nil, headErr
+	}
 
+	o = s.newObject(true)
+	o.ID = rp
+	o.Path = path
+	o.Mode |= ModeDir
+	return o, nil
+}
+
+func (s *Storage) write(ctx context.Context, path string, r io.Reader, size int64, opt pairStorageWrite) (n int64, err error) {
 	// According to GSP-751, we should allow the user to pass in a nil io.Reader.
 	// ref: https://github.com/beyondstorage/go-storage/blob/master/docs/rfcs/751-write-empty-file-behavior.md
 	if r == nil && size != 0 {
 		return 0, fmt.Errorf("reader is nil but size is not 0")
 	}
+	if err = checkStorageClass(opt.HasStorageClass, opt.StorageClass); err != nil {
+		return
+	}
+	if opt.HasUploadConcurrency && opt.UploadConcurrency <= 0 {
+		err = services.PairUnsupportedError{Pair: WithUploadConcurrency(opt.UploadConcurrency)}
+		return
+	}
+
+	// Path with trailing slash will be written as a directory while virtual dir is enabled.
+	isDir := s.features.VirtualDir && strings.HasSuffix(path, "/")
+	if opt.HasObjectMode {
+		if !opt.ObjectMode.IsDir() || !s.features.VirtualDir {
+			err = services.PairUnsupportedError{Pair: ps.WithObjectMode(opt.ObjectMode)}
+			return
+		}
+		if !isDir {
+			path += "/"
+		}
+		isDir = true
+	}
+	if isDir {
+		if size != 0 {
+			return 0, fmt.Errorf("directory must be empty: %w", services.ErrRestrictionDissatisfied)
+		}
+		if opt.HasCompression {
+			err = services.PairUnsupportedError{Pair: WithCompression(opt.Compression)}
+			return
+		}
+	}
+
+	// Cached result should be dropped after written, even if failed halfway.
+	defer s.statCache.invalidate(s.getAbsPath(path))
+
+	// Content is copied by write only while compressing, otherwise it's read by the http client.
+	if opt.HasCopyBufferSize && (opt.CopyBufferSize <= 0 || !opt.HasCompression) {
+		err = services.PairUnsupportedError{Pair: WithCopyBufferSize(opt.CopyBufferSize)}
+		return
+	}
+
+	if opt.HasWriteRateLimit && opt.WriteRateLimit <= 0 {
+		err = services.PairUnsupportedError{Pair: WithWriteRateLimit(opt.WriteRateLimit)}
+		return
+	}
+
+	if opt.HasWriteRetry && opt.WriteRetry > 0 {
+		return s.writeWithRetry(ctx, path, r, size, opt)
+	}
+
+	// Rate limit should be shared by all parts if write switches to multipart upload.
+	if opt.HasWriteRateLimit && r != nil {
+		r = newRateLimitReader(ctx, r, opt.WriteRateLimit)
+		opt.HasWriteRateLimit = false
+	}
+
+	// Statistics should cover the whole content if write switches to multipart upload.
+	if opt.HasTransferCallback && r != nil {
+		r = &transferReader{r: r, m: newTransferMeter(opt.TransferCallback, size)}
+		opt.HasTransferCallback = false
+	}
+
+	// Compressed size is unknown, so content will be written as a stream.
+	if opt.HasCompression {
+		return s.writeCompressed(ctx, path, r, size, opt)
+	}
+
+	// Content size is unknown, read until EOF and fall back to multipart upload if needed.
+	if size < 0 {
+		return s.writeStream(ctx, path, r, opt)
+	}
+
+	if opt.HasMultipartThreshold && size > opt.MultipartThreshold && r != nil {
+		return s.writeMultipartSized(ctx, path, r, size, opt)
+	}
+
+	// SHA-256 checksum should be calculated from content before encrypted.
+	if opt.HasAutoContentSha256 && opt.AutoContentSha256 && !opt.HasContentSha256 {
+		var sum []byte
+		sum, r, err = calculateHash(sha256.New(), r, size)
+		if err != nil {
+			return
+		}
+		opt.HasContentSha256 = true
+		opt.ContentSha256 = hex.EncodeToString(sum)
+	}
+	if opt.HasContentSha256 && !isSha256Valid(opt.ContentSha256) {
+		err = services.PairUnsupportedError{Pair: WithContentSha256(opt.ContentSha256)}
+		return
+	}
+
+	// Content will be encrypted before uploading, so size and md5 should be calculated from encrypted content.
+	plainSize := size
+	var cseMetadata map[string]string
+	// Directory is a marker without content, so it's not encrypted like CreateDir.
+	if s.keyProvider != nil && !isDir {
+		var aead cipher.AEAD
+		aead, cseMetadata, err = s.newDataKey(ctx)
+		if err != nil {
+			return
+		}
+		if r == nil {
+			// Empty content is encrypted into an empty chunk as well.
+			r = strings.NewReader("")
+		}
+		// Progress should be reported with the size before encrypted.
+		if opt.HasIoCallback {
+			r = iowrap.CallbackReader(r, opt.IoCallback)
+			opt.HasIoCallback = false
+		}
+		r = newEncryptReader(io.LimitReader(r, size), aead, multipartNumberMinimum, true)
+		size = encryptedSize(size)
+	}
+
+	// Reject content that exceeds the limit before sending any bytes.
+	if size > writeSizeMaximum {
+		err = ErrWriteSizeExceeded
+		return
+	}
+
+	// Content MD5 should be calculated before wrapping io callback, or the callback will be called twice.
+	if opt.HasAutoContentMd5 && opt.AutoContentMd5 && !opt.HasContentMd5 {
+		var sum []byte
+		sum, r, err = calculateMD5(r, size)
+		if err != nil {
+			return
+		}
+		opt.HasContentMd5 = true
+		opt.ContentMd5 = base64.StdEncoding.EncodeToString(sum)
+	}
+
+	// Calculate md5 while uploading so that we could verify the etag returned by server.
+	var h hash.Hash
+	if opt.HasVerifyEtag && opt.VerifyEtag && r != nil {
+		h = md5.New()
+		r = io.TeeReader(r, h)
+	}
 
 	if opt.HasIoCallback {
 		r = iowrap.CallbackReader(r, opt.IoCallback)
 	}
 
+	if opt.HasDetectContentType && opt.DetectContentType && !opt.HasContentType {
+		if v := mime.TypeByExtension(filepath.Ext(path)); v != "" {
+			opt.HasContentType = true
+			opt.ContentType = v
+		}
+	}
+
 	input, err := s.formatPutObjectInput(size, opt)
 	if err != nil {
 		return
 	}
 	input.Body = io.LimitReader(r, size)
+	if cseMetadata != nil {
+		if input.XQSMetaData == nil {
+			input.XQSMetaData = &map[string]string{}
+		}
+		for k, v := range cseMetadata {
+			(*input.XQSMetaData)[k] = v
+		}
+	}
 
 	rp := s.getAbsPath(path)
 
-	_, err = s.bucket.PutObjectWithContext(ctx, rp, input)
+	if opt.HasContentDisposition {
+		ctx = withRequestHeader(ctx, "Content-Disposition", opt.ContentDisposition)
+	}
+	if opt.HasExpires {
+		ctx = withRequestHeader(ctx, "Expires", opt.Expires.UTC().Format(http.TimeFormat))
+	}
+	if opt.HasIfNoneMatch {
+		ctx = withRequestHeader(ctx, "If-None-Match", opt.IfNoneMatch)
+	}
+
+	output, err := s.bucket.PutObjectWithContext(ctx, rp, input)
 	if err != nil {
+		var e *qserror.QingStorError
+		if opt.HasIfNoneMatch && errors.As(err, &e) && e.StatusCode == http.StatusPreconditionFailed {
+			err = ErrObjectAlreadyExist
+		}
 		return
 	}
-	return size, nil
+	if h != nil {
+		etag := strings.Trim(service.StringValue(output.ETag), "\"")
+		if etag != hex.EncodeToString(h.Sum(nil)) {
+			err = ErrContentCorrupted
+			return
+		}
+	}
+	return plainSize, nil
 }
 
 func (s *Storage) writeAppend(ctx context.Context, o *Object, r io.Reader, size int64, opt pairStorageWriteAppend) (n int64, err error) {
@@ -749,6 +1428,7 @@ func (s *Storage) writeAppend(ctx context.Context, o *Object, r io.Reader, size
 	}
 
 	rp := o.GetID()
+	defer s.statCache.invalidate(rp)
 
 	offset, _ := o.GetAppendOffset()
 
@@ -780,18 +1460,61 @@ func (s *Storage) writeAppend(ctx context.Context, o *Object, r io.Reader, size
 }
 
 func (s *Storage) writeMultipart(ctx context.Context, o *Object, r io.Reader, size int64, index int, opt pairStorageWriteMultipart) (n int64, part *Part, err error) {
+	// Parts are validated before sending, so that misuse fails fast instead of failing while
+	// completing. Parts smaller than 4MB are allowed here, since the last part could be smaller.
 	if index < multipartNumberMinimum || index > multipartNumberMaximum {
 		err = ErrPartNumberInvalid
 		return
 	}
-	if size > multipartSizeMaximum {
-		err = fmt.Errorf("size limit exceeded: %w", services.ErrRestrictionDissatisfied)
+	if size < 0 || size > multipartSizeMaximum {
+		err = ErrPartSizeInvalid
+		return
+	}
+
+	if opt.HasWriteRateLimit && opt.WriteRateLimit <= 0 {
+		err = services.PairUnsupportedError{Pair: WithWriteRateLimit(opt.WriteRateLimit)}
 		return
 	}
 
+	plainSize := size
+	if s.keyProvider != nil {
+		sm := GetObjectSystemMetadata(o)
+		if sm.ClientEncryptionKey == "" {
+			err = ErrClientEncryptionKeyInvalid
+			return
+		}
+		var aead cipher.AEAD
+		aead, err = s.openDataKey(ctx, sm.ClientEncryptionKey)
+		if err != nil {
+			return
+		}
+		// Progress should be reported with the size before encrypted.
+		if opt.HasIoCallback {
+			r = iowrap.CallbackReader(r, opt.IoCallback)
+			opt.HasIoCallback = false
+		}
+		r = newEncryptReader(io.LimitReader(r, size), aead, index, false)
+		size = encryptedSize(size)
+	}
+
+	// Content MD5 should be calculated from the content sent, and before wrapping io callback,
+	// or the callback will be called twice.
+	if opt.HasAutoContentMd5 && opt.AutoContentMd5 && !opt.HasContentMd5 {
+		var sum []byte
+		sum, r, err = calculateMD5(r, size)
+		if err != nil {
+			return
+		}
+		opt.HasContentMd5 = true
+		opt.ContentMd5 = base64.StdEncoding.EncodeToString(sum)
+	}
+
 	if opt.HasIoCallback {
 		r = iowrap.CallbackReader(r, opt.IoCallback)
 	}
+	if opt.HasWriteRateLimit {
+		r = newRateLimitReader(ctx, r, opt.WriteRateLimit)
+	}
 
 	input := &service.UploadMultipartInput{
 		PartNumber:    service.Int(index),
@@ -799,6 +1522,9 @@ func (s *Storage) writeMultipart(ctx context.Context, o *Object, r io.Reader, si
 		ContentLength: &size,
 		Body:          io.LimitReader(r, size),
 	}
+	if opt.HasContentMd5 {
+		input.ContentMD5 = service.String(opt.ContentMd5)
+	}
 	if opt.HasEncryptionCustomerAlgorithm {
 		input.XQSEncryptionCustomerAlgorithm, input.XQSEncryptionCustomerKey, input.XQSEncryptionCustomerKeyMD5, err = calculateEncryptionHeaders(opt.EncryptionCustomerAlgorithm, opt.EncryptionCustomerKey)
 		if err != nil {
@@ -816,5 +1542,5 @@ func (s *Storage) writeMultipart(ctx context.Context, o *Object, r io.Reader, si
 		Size:  size,
 		ETag:  service.StringValue(output.ETag),
 	}
-	return size, part, nil
+	return plainSize, part, nil
 }
@@ -3,10 +3,25 @@ package qingstor
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
@@ -57,6 +72,40 @@ func TestStorage_Metadata(t *testing.T) {
 		assert.Equal(t, name, m.Name)
 		assert.Equal(t, location, m.MustGetLocation())
 	}
+
+	{
+		name := uuid.New().String()
+		location := uuid.New().String()
+		status := "active"
+
+		client := Storage{
+			bucket: mockBucket,
+			properties: &service.Properties{
+				BucketName: &name,
+				Zone:       &location,
+			},
+		}
+
+		mockBucket.EXPECT().GetStatisticsWithContext(gomock.Any()).Return(&service.GetBucketStatisticsOutput{
+			Count:  service.Int64(10),
+			Size:   service.Int64(1024),
+			Status: &status,
+		}, nil)
+
+		m := client.Metadata(WithStatistics())
+		assert.NotNil(t, m)
+		sm := GetStorageSystemMetadata(m)
+		assert.Equal(t, int64(10), sm.Count)
+		assert.Equal(t, int64(1024), sm.Size)
+		assert.Equal(t, status, sm.Status)
+
+		// Error of statistics is returned by MetadataWithContext.
+		mockBucket.EXPECT().GetStatisticsWithContext(gomock.Any()).Return(nil, &qerror.QingStorError{StatusCode: 500})
+
+		m, err := client.MetadataWithContext(context.Background(), WithStatistics())
+		assert.Error(t, err)
+		assert.Equal(t, name, m.Name)
+	}
 }
 
 func TestStorage_Copy(t *testing.T) {
@@ -88,6 +137,7 @@ func TestStorage_Copy(t *testing.T) {
 	}
 
 	for _, v := range tests {
+		// Size of source is not checked before copying.
 		mockBucket.EXPECT().PutObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).Do(v.mockFn)
 
 		client := Storage{
@@ -108,6 +158,213 @@ func TestStorage_Copy(t *testing.T) {
 	}
 }
 
+func TestStorage_CopyStorageClass(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	name := uuid.New().String()
+	client := Storage{
+		bucket: mockBucket,
+		properties: &service.Properties{
+			BucketName: &name,
+		},
+	}
+
+	// Headers of the object should be kept while copying to itself.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Eq("test_src"), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			CacheControl:    service.String("max-age=60"),
+			ContentRange:    service.String("bytes 0-0/100"),
+			XQSStorageClass: service.String(StorageClassStandard),
+		}, nil)
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Eq("test_src"), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, "/"+name+"/test_src", *input.XQSCopySource)
+			assert.Equal(t, metadataDirectiveReplace, *input.XQSMetadataDirective)
+			assert.Equal(t, "max-age=60", *input.CacheControl)
+			assert.Equal(t, StorageClassStandardIA, *input.XQSStorageClass)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	// Transition the object to STANDARD_IA by copying to itself.
+	err := client.Copy("test_src", "test_src", WithStorageClass(StorageClassStandardIA))
+	assert.NoError(t, err)
+
+	err = client.Copy("test_src", "test_dst", WithStorageClass("GLACIER"))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_CopyContentDisposition(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	name := uuid.New().String()
+	client := Storage{
+		bucket: mockBucket,
+		properties: &service.Properties{
+			BucketName: &name,
+		},
+	}
+
+	// Metadata of the source is replaced while content_disposition is set, so headers of the
+	// source should be sent again.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Eq("test_src"), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			CacheControl:       service.String("max-age=60"),
+			ContentDisposition: service.String("inline"),
+			ContentEncoding:    service.String("gzip"),
+			ContentType:        service.String("text/plain"),
+			ContentRange:       service.String("bytes 0-0/100"),
+			XQSMetaData:        &map[string]string{"X-QS-Meta-Tenant": "test_tenant"},
+		}, nil)
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Eq("test_dst"), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, "/"+name+"/test_src", *input.XQSCopySource)
+			assert.Equal(t, metadataDirectiveReplace, *input.XQSMetadataDirective)
+			assert.Equal(t, "max-age=60", *input.CacheControl)
+			assert.Equal(t, "gzip", *input.ContentEncoding)
+			assert.Equal(t, "text/plain", *input.ContentType)
+			assert.Equal(t, map[string]string{"x-qs-meta-tenant": "test_tenant"}, *input.XQSMetaData)
+			assert.Equal(t, "attachment", requestHeadersFromContext(ctx).Get("Content-Disposition"))
+			return &service.PutObjectOutput{}, nil
+		})
+
+	err := client.Copy("test_src", "test_dst", WithContentDisposition("attachment"))
+	assert.NoError(t, err)
+}
+
+func TestStorage_CopyLargeObject(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	name := uuid.New().String()
+	location := uuid.New().String()
+
+	client := Storage{
+		bucket: mockBucket,
+		properties: &service.Properties{
+			BucketName: &name,
+			Zone:       &location,
+		},
+	}
+
+	size := int64(copySizeMaximum + 1)
+	uploadID := uuid.NewString()
+
+	expires := time.Unix(1600000000, 0).UTC()
+
+	// Source is only read after the single copy rejected.
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Eq("test_dst"), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: http.StatusBadRequest, Code: "invalid_request"})
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Eq("test_src"), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Equal(t, "bytes=0-0", *input.Range)
+			return &service.GetObjectOutput{
+				Body:               ioutil.NopCloser(strings.NewReader("a")),
+				CacheControl:       service.String("max-age=60"),
+				ContentDisposition: service.String("attachment"),
+				ContentEncoding:    service.String("gzip"),
+				ContentLength:      service.Int64(1),
+				ContentRange:       service.String(fmt.Sprintf("bytes 0-0/%d", size)),
+				ContentType:        service.String("video/mp4"),
+				Expires:            service.String(expires.Format(http.TimeFormat)),
+				XQSStorageClass:    service.String(StorageClassStandardIA),
+				XQSMetaData:        &map[string]string{"X-Qs-Meta-Tenant": "test_tenant"},
+			}, nil
+		})
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Eq("test_dst"), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.InitiateMultipartUploadInput) (*service.InitiateMultipartUploadOutput, error) {
+			// Attributes of source should be kept.
+			h := requestHeadersFromContext(ctx)
+			assert.Equal(t, "max-age=60", h.Get("Cache-Control"))
+			assert.Equal(t, "gzip", h.Get("Content-Encoding"))
+			assert.Equal(t, "attachment", h.Get("Content-Disposition"))
+			assert.Equal(t, expires.Format(http.TimeFormat), h.Get("Expires"))
+			assert.Equal(t, "video/mp4", *input.ContentType)
+			assert.Equal(t, StorageClassStandardIA, *input.XQSStorageClass)
+			assert.Equal(t, map[string]string{"x-qs-meta-tenant": "test_tenant"}, *input.XQSMetaData)
+			return &service.InitiateMultipartUploadOutput{UploadID: service.String(uploadID)}, nil
+		})
+	var mu sync.Mutex
+	ranges := make(map[int]string)
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Eq("test_dst"), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			assert.Equal(t, "/"+name+"/test_src", *input.XQSCopySource)
+			mu.Lock()
+			ranges[*input.PartNumber] = *input.XQSCopyRange
+			mu.Unlock()
+			return &service.UploadMultipartOutput{ETag: service.String(fmt.Sprintf("etag-%d", *input.PartNumber))}, nil
+		}).Times(6)
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Eq("test_dst"), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.CompleteMultipartUploadInput) (*service.CompleteMultipartUploadOutput, error) {
+			assert.Len(t, input.ObjectParts, 6)
+			for i, p := range input.ObjectParts {
+				assert.Equal(t, i, *p.PartNumber)
+				assert.Equal(t, fmt.Sprintf("etag-%d", i), *p.Etag)
+			}
+			return &service.CompleteMultipartUploadOutput{}, nil
+		})
+
+	err := client.Copy("test_src", "test_dst")
+	assert.NoError(t, err)
+	assert.Equal(t, "bytes=0-1073741823", ranges[0])
+	assert.Equal(t, fmt.Sprintf("bytes=%d-%d", 5*copyPartSize, size-1), ranges[5])
+}
+
+func TestStorage_WriteMultipartCopy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	name := uuid.New().String()
+	location := uuid.New().String()
+
+	client := Storage{
+		bucket: mockBucket,
+		properties: &service.Properties{
+			BucketName: &name,
+			Zone:       &location,
+		},
+	}
+
+	uploadID := uuid.NewString()
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			assert.Equal(t, "test_dst", objectKey)
+			assert.Equal(t, uploadID, *input.UploadID)
+			assert.Equal(t, 2, *input.PartNumber)
+			assert.Equal(t, "/"+name+"/test_src", *input.XQSCopySource)
+			assert.Equal(t, "bytes=100-199", *input.XQSCopyRange)
+			assert.Nil(t, input.Body)
+			return &service.UploadMultipartOutput{ETag: service.String("test_etag")}, nil
+		})
+
+	o := client.Create("test_dst", pairs.WithMultipartID(uploadID))
+	part, err := client.WriteMultipartCopy(o, "test_src", 100, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &Part{Index: 2, Size: 100, ETag: "test_etag"}, part)
+
+	_, err = client.WriteMultipartCopy(o, "test_src", 0, multipartSizeMaximum+1, 0)
+	assert.True(t, errors.Is(err, ErrPartSizeInvalid))
+
+	_, err = client.WriteMultipartCopy(o, "test_src", 0, 100, multipartNumberMaximum+1)
+	assert.True(t, errors.Is(err, ErrPartNumberInvalid))
+
+	_, err = client.WriteMultipartCopy(client.Create("test_dst"), "test_src", 0, 100, 0)
+	assert.True(t, errors.Is(err, services.ErrObjectModeInvalid))
+
+	// Storage class is specified while creating the multipart upload.
+	_, err = client.WriteMultipartCopy(o, "test_src", 0, 100, 0, WithStorageClass(StorageClassStandardIA))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
 func TestStorage_Delete(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -165,9 +422,15 @@ func TestStorage_ListPrefix(t *testing.T) {
 				HasMore: service.Bool(false),
 				Keys: []*service.KeyType{
 					{
-						Key: service.String(key),
+						Key:          service.String(key),
+						Etag:         service.String("etag"),
+						Size:         service.Int64(100),
+						Modified:     service.Int(1600000000),
+						StorageClass: service.String(StorageClassStandardIA),
+						Encrypted:    service.Bool(true),
 					},
 				},
+				Owner: &service.OwnerType{ID: service.String("usr-xxx"), Name: service.String("owner")},
 			}, nil
 		})
 
@@ -182,381 +445,4243 @@ func TestStorage_ListPrefix(t *testing.T) {
 	object, err := it.Next()
 	assert.Equal(t, object.ID, key)
 	assert.Nil(t, err)
+	// Metadata returned by List should be read without stat.
+	assert.Equal(t, "etag", object.MustGetEtag())
+	assert.Equal(t, int64(100), object.MustGetContentLength())
+	assert.Equal(t, time.Unix(1600000000, 0), object.MustGetLastModified())
+	sm := GetObjectSystemMetadata(object)
+	assert.Equal(t, StorageClassStandardIA, sm.StorageClass)
+	assert.True(t, sm.Encrypted)
+	assert.Equal(t, "usr-xxx", sm.OwnerID)
+	assert.Equal(t, "owner", sm.OwnerName)
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Eq(context.Background()), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			assert.Equal(t, 1000, *input.Limit)
+			return &service.ListObjectsOutput{HasMore: service.Bool(false)}, nil
+		})
+
+	it, err = client.List(path, WithPageSize(1000))
+	assert.NoError(t, err)
+	_, err = it.Next()
+	assert.True(t, errors.Is(err, IterateDone))
+
+	_, err = client.List(path, WithPageSize(0))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
 }
 
-func TestStorage_Move(t *testing.T) {
+func TestStorage_ListContinuationToken(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockBucket := NewMockBucket(ctrl)
 
-	name := uuid.New().String()
-	location := uuid.New().String()
-
-	tests := []struct {
-		name     string
-		src      string
-		dst      string
-		mockFn   func(context.Context, string, *service.PutObjectInput)
-		hasError bool
-		wantErr  error
-	}{
-		{
-			"valid copy",
-			"test_src", "test_dst",
-			func(ctx context.Context, inputObjectKey string, input *service.PutObjectInput) {
-				assert.Equal(t, "test_dst", inputObjectKey)
-				assert.Equal(t, "/"+name+"/"+"test_src", *input.XQSMoveSource)
-			},
-			false, nil,
-		},
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
 	}
 
-	for _, v := range tests {
-		mockBucket.EXPECT().PutObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).Do(v.mockFn)
+	path := uuid.New().String()
+	token := path + uuid.New().String()
+	next := path + uuid.New().String()
 
-		client := Storage{
-			bucket: mockBucket,
-			properties: &service.Properties{
-				BucketName: &name,
-				Zone:       &location,
-			},
-		}
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Eq(context.Background()), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			assert.Equal(t, token, *input.Marker)
+			return &service.ListObjectsOutput{
+				HasMore:    service.Bool(true),
+				NextMarker: service.String(next),
+				Keys:       []*service.KeyType{{Key: service.String(next)}},
+			}, nil
+		})
 
-		err := client.Move(v.src, v.dst)
-		if v.hasError {
-			assert.Error(t, err)
-			assert.True(t, errors.Is(err, v.wantErr))
-		} else {
-			assert.NoError(t, err)
-		}
-	}
+	it, err := client.List(path, pairs.WithContinuationToken(token))
+	assert.NoError(t, err)
+	_, err = it.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, next, it.ContinuationToken())
+
+	// Token of multipart uploads carries the upload id.
+	uploadID := uuid.New().String()
+	mockBucket.EXPECT().ListMultipartUploadsWithContext(gomock.Eq(context.Background()), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListMultipartUploadsInput) (*service.ListMultipartUploadsOutput, error) {
+			assert.Equal(t, token, *input.KeyMarker)
+			assert.Equal(t, uploadID, *input.UploadIDMarker)
+			return &service.ListMultipartUploadsOutput{HasMore: service.Bool(false)}, nil
+		})
+
+	it, err = client.List(path, pairs.WithListMode(ListModePart), pairs.WithContinuationToken(token+"/"+uploadID))
+	assert.NoError(t, err)
+	_, err = it.Next()
+	assert.True(t, errors.Is(err, IterateDone))
 }
 
-func TestStorage_Read(t *testing.T) {
+func TestStorage_ListAll(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockBucket := NewMockBucket(ctrl)
 
-	tests := []struct {
-		name     string
-		path     string
-		mockFn   func(context.Context, string, *service.GetObjectInput) (*service.GetObjectOutput, error)
-		hasError bool
-		wantErr  error
-	}{
-		{
-			"valid copy",
-			"test_src",
-			func(ctx context.Context, inputPath string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
-				assert.Equal(t, "test_src", inputPath)
-				return &service.GetObjectOutput{
-					Body: ioutil.NopCloser(bytes.NewBuffer([]byte("content"))),
-				}, nil
-			},
-			false, nil,
-		},
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
 	}
 
-	for _, v := range tests {
-		mockBucket.EXPECT().GetObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).DoAndReturn(v.mockFn)
+	path := uuid.New().String()
+	keys := []*service.KeyType{
+		{Key: service.String(path + "1")},
+		{Key: service.String(path + "2")},
+		{Key: service.String(path + "3")},
+	}
 
-		client := Storage{
-			bucket: mockBucket,
-		}
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{HasMore: service.Bool(false), Keys: keys}, nil).Times(2)
 
-		var buf bytes.Buffer
-		n, err := client.Read(v.path, &buf)
-		if v.hasError {
-			assert.Error(t, err)
-			assert.True(t, errors.Is(err, v.wantErr))
-		} else {
-			assert.Equal(t, "content", buf.String())
-			assert.Equal(t, int64(buf.Len()), n)
-		}
-	}
+	objects, err := client.ListAll(path, 0)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 3)
+
+	objects, err = client.ListAll(path, 2)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, path+"2", objects[1].ID)
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 403})
+	_, err = client.ListAll(path, 0)
+	assert.Error(t, err)
 }
 
-func TestStorage_Stat(t *testing.T) {
+func TestStorage_ListFilter(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockBucket := NewMockBucket(ctrl)
 
-	tests := []struct {
-		name     string
-		src      string
-		mockFn   func(context.Context, string, *service.HeadObjectInput) (*service.HeadObjectOutput, error)
-		hasError bool
-		wantErr  error
-	}{
-		{
-			"valid file",
-			"test_src",
-			func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
-				assert.Equal(t, "test_src", objectKey)
-				length := int64(100)
-				return &service.HeadObjectOutput{
-					ContentLength:   &length,
-					ContentType:     convert.String("test_content_type"),
-					ETag:            convert.String("test_etag"),
-					XQSStorageClass: convert.String("STANDARD"),
-				}, nil
-			},
-			false, nil,
-		},
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
 	}
 
-	for _, v := range tests {
-		mockBucket.EXPECT().HeadObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).DoAndReturn(v.mockFn)
+	path := uuid.New().String() + "/"
 
-		client := Storage{
-			bucket: mockBucket,
-		}
+	// Objects in the first page are all filtered, the page should be skipped instead of
+	// ending the iterating.
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{
+			HasMore:    service.Bool(true),
+			NextMarker: service.String(path + "b.txt"),
+			Keys: []*service.KeyType{
+				{Key: service.String(path + "a.txt")},
+				{Key: service.String(path + "b.txt")},
+			},
+		}, nil).Times(2)
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{
+			HasMore: service.Bool(false),
+			Keys: []*service.KeyType{
+				{Key: service.String(path + "c.gz")},
+				{Key: service.String(path + "d/e.gz")},
+				{Key: service.String(path + "f.gz")},
+			},
+		}, nil).Times(2)
 
-		o, err := client.Stat(v.src)
-		if v.hasError {
-			assert.Error(t, err)
-			assert.True(t, errors.Is(err, v.wantErr))
-		} else {
-			assert.NoError(t, err)
-			assert.NotNil(t, o)
-			assert.Equal(t, ModeRead, o.Mode)
-			assert.Equal(t, int64(100), o.MustGetContentLength())
-			contentType, ok := o.GetContentType()
-			assert.True(t, ok)
-			assert.Equal(t, "test_content_type", contentType)
-			checkSum, ok := o.GetEtag()
-			assert.True(t, ok)
-			assert.Equal(t, "test_etag", checkSum)
+	objects, err := client.ListAll(path, 0, WithListGlob(path+"*.gz"))
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, path+"c.gz", objects[0].Path)
+	assert.Equal(t, path+"f.gz", objects[1].Path)
 
-			om := GetObjectSystemMetadata(o)
-			assert.Equal(t, StorageClassStandard, om.StorageClass)
-		}
-	}
+	objects, err = client.ListAll(path, 0, WithListRegexp(`/d/.*\.gz$`))
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, path+"d/e.gz", objects[0].Path)
+
+	_, err = client.List(path, WithListGlob("["))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	_, err = client.List(path, WithListRegexp("("))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
 }
 
-func TestStorage_Write(t *testing.T) {
+func TestStorage_ListFilterByMtimeAndSize(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockBucket := NewMockBucket(ctrl)
 
-	tests := []struct {
-		name     string
-		path     string
-		size     int64
-		r        io.Reader
-		mockFn   func(context.Context, string, *service.PutObjectInput) (*service.PutObjectOutput, error)
-		hasError bool
-		wantErr  error
-	}{
-		{
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.New().String() + "/"
+	now := time.Now().Truncate(time.Second)
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{
+			HasMore: service.Bool(false),
+			Keys: []*service.KeyType{
+				{Key: service.String(path + "old-small"), Size: service.Int64(1), Modified: service.Int(int(now.Add(-time.Hour).Unix()))},
+				{Key: service.String(path + "old-large"), Size: service.Int64(1024), Modified: service.Int(int(now.Add(-time.Hour).Unix()))},
+				{Key: service.String(path + "new-small"), Size: service.Int64(1), Modified: service.Int(int(now.Unix()))},
+				{Key: service.String(path + "new-large"), Size: service.Int64(1024), Modified: service.Int(int(now.Unix()))},
+				{Key: service.String(path + "dir/"), Modified: service.Int(int(now.Add(-time.Hour).Unix()))},
+			},
+		}, nil).Times(3)
+
+	objects, err := client.ListAll(path, 0, WithModifiedAfter(now.Add(-time.Minute)), WithMinSize(1024))
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, path+"new-large", objects[0].Path)
+	// Directories are always kept.
+	assert.Equal(t, path+"dir/", objects[1].Path)
+
+	objects, err = client.ListAll(path, 0, WithModifiedBefore(now.Add(-time.Minute)), WithMaxSize(1))
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, path+"old-small", objects[0].Path)
+
+	objects, err = client.ListAll(path, 0, WithMinSize(1), WithMaxSize(1))
+	assert.NoError(t, err)
+	assert.Len(t, objects, 3)
+
+	_, err = client.List(path, WithMinSize(-1))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ListChan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.New().String()
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{
+			HasMore:    service.Bool(true),
+			NextMarker: service.String(path + "2"),
+			Keys: []*service.KeyType{
+				{Key: service.String(path + "1")},
+				{Key: service.String(path + "2")},
+			},
+		}, nil)
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{
+			HasMore: service.Bool(false),
+			Keys:    []*service.KeyType{{Key: service.String(path + "3")}},
+		}, nil)
+
+	objects, errc := client.ListChan(path, WithPageSize(2))
+	var ids []string
+	for o := range objects {
+		ids = append(ids, o.ID)
+	}
+	assert.NoError(t, <-errc)
+	assert.Equal(t, []string{path + "1", path + "2", path + "3"}, ids)
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 403})
+
+	objects, errc = client.ListChan(path)
+	for range objects {
+		t.Error("no object should be sent")
+	}
+	assert.Error(t, <-errc)
+
+	_, errc = client.ListChan(path, WithPageSize(0))
+	assert.True(t, errors.Is(<-errc, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ListWithContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.New().String()
+
+	type ctxKey struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, path))
+	defer cancel()
+
+	// Every page should be fetched with the context of the iterator.
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			assert.Equal(t, path, ctx.Value(ctxKey{}))
+			return &service.ListObjectsOutput{
+				HasMore:    service.Bool(true),
+				NextMarker: service.String(path + "1"),
+				Keys:       []*service.KeyType{{Key: service.String(path + "1")}},
+			}, nil
+		}).Times(2)
+
+	it, err := client.ListWithContext(ctx, path)
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = it.Next()
+		assert.NoError(t, err)
+	}
+
+	// No more page should be fetched after ctx canceled.
+	cancel()
+	_, err = it.Next()
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestStorage_ListRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.New().String()
+
+	gomock.InOrder(
+		mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+			Return(&service.ListObjectsOutput{
+				HasMore:    service.Bool(true),
+				NextMarker: service.String(path + "1"),
+				Keys:       []*service.KeyType{{Key: service.String(path + "1")}},
+			}, nil),
+		mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+			Return(nil, &qerror.QingStorError{StatusCode: 503}),
+		// The failed page should be fetched again from the same marker.
+		mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+				assert.Equal(t, path+"1", *input.Marker)
+				return &service.ListObjectsOutput{
+					HasMore: service.Bool(false),
+					Keys:    []*service.KeyType{{Key: service.String(path + "2")}},
+				}, nil
+			}),
+	)
+
+	objects, err := client.ListAll(path, 0, WithListRetry(1))
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+
+	// Errors except transient failures should not be retried.
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 403})
+	_, err = client.ListAll(path, 0, WithListRetry(3))
+	assert.Error(t, err)
+}
+
+func TestStorage_ListIncludeMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.New().String() + "/"
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{
+			HasMore:        service.Bool(false),
+			CommonPrefixes: []*string{service.String(path + "dir/")},
+			Keys: []*service.KeyType{
+				{Key: service.String(path + "1"), Size: service.Int64(100)},
+				{Key: service.String(path + "2"), Size: service.Int64(100)},
+			},
+		}, nil)
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+			// Object 2 is deleted after listed.
+			if objectKey == path+"2" {
+				return nil, &qerror.QingStorError{StatusCode: 404}
+			}
+			assert.Equal(t, path+"1", objectKey)
+			return &service.HeadObjectOutput{
+				ContentLength: service.Int64(100),
+				ContentType:   service.String("text/plain"),
+				XQSMetaData:   &map[string]string{"x-qs-meta-tenant": "test_tenant"},
+			}, nil
+		}).Times(2)
+
+	objects, err := client.ListAll(path, 0, pairs.WithListMode(ListModeDir), WithIncludeMetadata())
+	assert.NoError(t, err)
+	assert.Len(t, objects, 3)
+	assert.Equal(t, path+"dir/", objects[0].ID)
+	assert.Equal(t, path+"1", objects[1].ID)
+	assert.Equal(t, "text/plain", objects[1].MustGetContentType())
+	assert.Equal(t, map[string]string{"tenant": "test_tenant"}, objects[1].MustGetUserMetadata())
+	assert.Equal(t, path+"2", objects[2].ID)
+	_, ok := objects[2].GetUserMetadata()
+	assert.False(t, ok)
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{
+			HasMore: service.Bool(false),
+			Keys:    []*service.KeyType{{Key: service.String(path + "1")}},
+		}, nil)
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 403})
+	_, err = client.ListAll(path, 0, WithIncludeMetadata())
+	assert.Error(t, err)
+
+	_, err = client.List(path, pairs.WithListMode(ListModePart), WithIncludeMetadata())
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ListClientEncryption(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	kp, err := NewAESKeyProvider(bytes.Repeat([]byte{'k'}, 32))
+	assert.NoError(t, err)
+
+	client := Storage{
+		bucket:      mockBucket,
+		workDir:     "/",
+		keyProvider: kp,
+	}
+
+	path := uuid.New().String() + "/"
+
+	// Listed objects should be stated, so that sizes are the same as the ones returned by Stat.
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{
+			HasMore: service.Bool(false),
+			Keys: []*service.KeyType{
+				{Key: service.String(path + "encrypted"), Size: service.Int64(encryptedSize(100))},
+				{Key: service.String(path + "plain"), Size: service.Int64(100)},
+			},
+		}, nil)
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+			if objectKey == path+"plain" {
+				return &service.HeadObjectOutput{ContentLength: service.Int64(100)}, nil
+			}
+			return &service.HeadObjectOutput{
+				ContentLength: service.Int64(encryptedSize(100)),
+				XQSMetaData:   &map[string]string{metadataClientEncryptionKey: "wrapped"},
+			}, nil
+		}).Times(2)
+
+	objects, err := client.ListAll(path, 0)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	for _, o := range objects {
+		assert.Equal(t, int64(100), o.MustGetContentLength())
+	}
+}
+
+func TestStorage_ListSorted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.New().String() + "/"
+
+	// Common prefixes are returned before keys in a page, and the last key of the first page
+	// is returned again in the second page.
+	gomock.InOrder(
+		mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+			Return(&service.ListObjectsOutput{
+				HasMore:        service.Bool(true),
+				NextMarker:     service.String(path + "c"),
+				CommonPrefixes: []*string{service.String(path + "b/")},
+				Keys: []*service.KeyType{
+					{Key: service.String(path + "a")},
+					{Key: service.String(path + "c")},
+				},
+			}, nil),
+		mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+			Return(&service.ListObjectsOutput{
+				HasMore: service.Bool(false),
+				Keys: []*service.KeyType{
+					{Key: service.String(path + "c")},
+					{Key: service.String(path + "d")},
+				},
+			}, nil),
+	)
+
+	objects, err := client.ListAll(path, 0, pairs.WithListMode(ListModeDir), WithListSorted())
+	assert.NoError(t, err)
+	var ids []string
+	for _, o := range objects {
+		ids = append(ids, o.ID)
+	}
+	assert.Equal(t, []string{path + "a", path + "b/", path + "c", path + "d"}, ids)
+
+	gomock.InOrder(
+		mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+			Return(&service.ListObjectsOutput{
+				HasMore:    service.Bool(true),
+				NextMarker: service.String(path + "c"),
+				Keys:       []*service.KeyType{{Key: service.String(path + "c")}},
+			}, nil),
+		mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+			Return(&service.ListObjectsOutput{
+				HasMore: service.Bool(false),
+				Keys:    []*service.KeyType{{Key: service.String(path + "b")}},
+			}, nil),
+	)
+
+	_, err = client.ListAll(path, 0, WithListSorted())
+	assert.True(t, errors.Is(err, ErrListOrderViolated))
+
+	_, err = client.List(path, pairs.WithListMode(ListModePart), WithListSorted())
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ListPrefixesOnly(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.New().String() + "/"
+
+	// The first page has no common prefixes, and should be skipped.
+	gomock.InOrder(
+		mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+				assert.Equal(t, "/", *input.Delimiter)
+				return &service.ListObjectsOutput{
+					HasMore:    service.Bool(true),
+					NextMarker: service.String(path + "2"),
+					Keys: []*service.KeyType{
+						{Key: service.String(path + "1")},
+						{Key: service.String(path + "2")},
+					},
+				}, nil
+			}),
+		mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+			Return(&service.ListObjectsOutput{
+				HasMore:        service.Bool(false),
+				CommonPrefixes: []*string{service.String(path + "a/"), service.String(path + "b/")},
+				Keys:           []*service.KeyType{{Key: service.String(path + "3")}},
+			}, nil),
+	)
+
+	objects, err := client.ListAll(path, 0, WithPrefixesOnly())
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, path+"a/", objects[0].ID)
+	assert.Equal(t, path+"b/", objects[1].ID)
+
+	_, err = client.List(path, pairs.WithListMode(ListModePrefix), WithPrefixesOnly())
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ListConcurrent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.New().String() + "/"
+	prefixes := []string{path + "a/", path + "b/", path + "c/"}
+	file := path + uuid.New().String()
+
+	want := map[string]bool{file: true}
+	for _, p := range prefixes {
+		want[p+"1"] = true
+		want[p+"2"] = true
+	}
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			if service.StringValue(input.Delimiter) == "/" {
+				assert.Equal(t, path, *input.Prefix)
+				return &service.ListObjectsOutput{
+					HasMore:        service.Bool(false),
+					CommonPrefixes: []*string{&prefixes[0], &prefixes[1], &prefixes[2]},
+					Keys:           []*service.KeyType{{Key: service.String(file)}},
+				}, nil
+			}
+
+			// Every prefix has two pages.
+			prefix := *input.Prefix
+			if *input.Marker == "" {
+				return &service.ListObjectsOutput{
+					HasMore:    service.Bool(true),
+					NextMarker: service.String(prefix + "1"),
+					Keys:       []*service.KeyType{{Key: service.String(prefix + "1")}},
+				}, nil
+			}
+			return &service.ListObjectsOutput{
+				HasMore: service.Bool(false),
+				Keys:    []*service.KeyType{{Key: service.String(prefix + "2")}},
+			}, nil
+		}).Times(1 + len(prefixes)*2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	it, err := client.ListConcurrentWithContext(ctx, path, WithListConcurrency(2))
+	assert.NoError(t, err)
+
+	got := make(map[string]bool)
+	for {
+		o, err := it.Next()
+		if errors.Is(err, IterateDone) {
+			break
+		}
+		assert.NoError(t, err)
+		got[o.ID] = true
+	}
+	assert.Equal(t, want, got)
+
+	_, err = client.ListConcurrentWithContext(ctx, path, pairs.WithListMode(ListModeDir))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ListConcurrentCanceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.New().String() + "/"
+
+	// Every prefix has endless pages.
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			if service.StringValue(input.Delimiter) == "/" {
+				return &service.ListObjectsOutput{
+					HasMore:        service.Bool(false),
+					CommonPrefixes: []*string{service.String(path + "a/"), service.String(path + "b/")},
+				}, nil
+			}
+			key := *input.Prefix + uuid.New().String()
+			return &service.ListObjectsOutput{
+				HasMore:    service.Bool(true),
+				NextMarker: service.String(key),
+				Keys:       []*service.KeyType{{Key: service.String(key)}},
+			}, nil
+		}).AnyTimes()
+
+	goroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it, err := client.ListConcurrentWithContext(ctx, path)
+	assert.NoError(t, err)
+	_, err = it.Next()
+	assert.NoError(t, err)
+
+	// Goroutines listing in background should exit after ctx canceled.
+	cancel()
+	for i := 0; i < 100 && runtime.NumGoroutine() > goroutines; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
+
+func TestStorage_ObjectMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:   mockBucket,
+		workDir:  "/",
+		features: StorageFeatures{VirtualDir: true},
+	}
+
+	path := uuid.New().String() + "/"
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{
+			HasMore: service.Bool(false),
+			Keys: []*service.KeyType{
+				{Key: service.String(path + "dir/")},
+				{Key: service.String(path + "console"), MimeType: service.String(mimeTypeDirectory)},
+				{Key: service.String(path + "file")},
+			},
+		}, nil)
+
+	it, err := client.List(path)
+	assert.NoError(t, err)
+	for _, isDir := range []bool{true, true, false} {
+		o, err := it.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, isDir, o.Mode.IsDir())
+		assert.Equal(t, !isDir, o.Mode.IsRead())
+	}
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{ContentType: service.String(mimeTypeDirectory)}, nil)
+	o, err := client.Stat(path + "console")
+	assert.NoError(t, err)
+	assert.True(t, o.Mode.IsDir())
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{XQSNextAppendPosition: service.Int64(10)}, nil)
+	o, err = client.Stat(path + "append")
+	assert.NoError(t, err)
+	assert.True(t, o.Mode.IsRead())
+	assert.True(t, o.Mode.IsAppend())
+}
+
+func TestStorage_StatImplicitDir(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:   mockBucket,
+		workDir:  "/",
+		features: StorageFeatures{VirtualDir: true},
+	}
+
+	path := uuid.New().String() + "/"
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 404}).Times(3)
+
+	// Directory object doesn't exist and implicit dir is not enabled.
+	_, err := client.Stat(path)
+	assert.True(t, errors.Is(err, services.ErrObjectNotExist))
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			assert.Equal(t, path, *input.Prefix)
+			assert.Equal(t, 1, *input.Limit)
+			return &service.ListObjectsOutput{
+				Keys: []*service.KeyType{{Key: service.String(path + "file")}},
+			}, nil
+		})
+	o, err := client.Stat(path, WithImplicitDir())
+	assert.NoError(t, err)
+	assert.True(t, o.Mode.IsDir())
+	assert.Equal(t, path, o.ID)
+
+	// No keys under the directory.
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListObjectsOutput{}, nil)
+	_, err = client.Stat(path, WithImplicitDir())
+	assert.True(t, errors.Is(err, services.ErrObjectNotExist))
+}
+
+func TestStorage_ListDir(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	path := uuid.New().String() + "/"
+	dir := path + uuid.New().String() + "/"
+	file := path + uuid.New().String()
+	consoleDir := path + uuid.New().String()
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Eq(context.Background()), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			assert.Equal(t, path, *input.Prefix)
+			assert.Equal(t, "/", *input.Delimiter)
+			return &service.ListObjectsOutput{
+				HasMore:        service.Bool(false),
+				CommonPrefixes: []*string{service.String(dir)},
+				Keys: []*service.KeyType{
+					// The dir key itself should be excluded.
+					{Key: service.String(path), MimeType: service.String("application/x-directory")},
+					{Key: service.String(file), Size: service.Int64(10)},
+					{Key: service.String(consoleDir), MimeType: service.String("application/x-directory")},
+				},
+			}, nil
+		})
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	it, err := client.List(path, pairs.WithListMode(ListModeDir))
+	assert.NoError(t, err)
+
+	var objects []*Object
+	for {
+		o, err := it.Next()
+		if errors.Is(err, IterateDone) {
+			break
+		}
+		assert.NoError(t, err)
+		objects = append(objects, o)
+	}
+	assert.Len(t, objects, 3)
+	assert.Equal(t, dir, objects[0].ID)
+	assert.True(t, objects[0].Mode.IsDir())
+	assert.Equal(t, file, objects[1].ID)
+	assert.True(t, objects[1].Mode.IsRead())
+	assert.Equal(t, int64(10), objects[1].MustGetContentLength())
+	assert.True(t, objects[2].Mode.IsDir())
+}
+
+func TestStorage_ListPart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	path := uuid.New().String()
+	key := path + uuid.New().String()
+	uploadIDs := []string{uuid.New().String(), uuid.New().String()}
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	mockBucket.EXPECT().ListMultipartUploadsWithContext(gomock.Eq(context.Background()), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListMultipartUploadsInput) (*service.ListMultipartUploadsOutput, error) {
+			assert.Equal(t, path, *input.Prefix)
+			assert.Equal(t, "", *input.KeyMarker)
+			return &service.ListMultipartUploadsOutput{
+				HasMore:            service.Bool(true),
+				NextKeyMarker:      service.String(key),
+				NextUploadIDMarker: service.String(uploadIDs[0]),
+				Uploads: []*service.UploadsType{
+					{Key: service.String(key), UploadID: service.String(uploadIDs[0]), Created: &created},
+				},
+			}, nil
+		})
+	mockBucket.EXPECT().ListMultipartUploadsWithContext(gomock.Eq(context.Background()), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListMultipartUploadsInput) (*service.ListMultipartUploadsOutput, error) {
+			assert.Equal(t, key, *input.KeyMarker)
+			assert.Equal(t, uploadIDs[0], *input.UploadIDMarker)
+			return &service.ListMultipartUploadsOutput{
+				HasMore: service.Bool(false),
+				Uploads: []*service.UploadsType{
+					{Key: service.String(key), UploadID: service.String(uploadIDs[1]), Created: &created},
+				},
+			}, nil
+		})
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	it, err := client.List(path, pairs.WithListMode(ListModePart))
+	assert.NoError(t, err)
+
+	for _, uploadID := range uploadIDs {
+		o, err := it.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, key, o.ID)
+		assert.True(t, o.Mode.IsPart())
+		assert.Equal(t, uploadID, o.MustGetMultipartID())
+		assert.Equal(t, created, o.MustGetLastModified())
+		assert.Equal(t, created, GetObjectSystemMetadata(o).Initiated)
+	}
+	_, err = it.Next()
+	assert.True(t, errors.Is(err, IterateDone))
+
+	// Abandoned uploads could be found by initiated time.
+	mockBucket.EXPECT().ListMultipartUploadsWithContext(gomock.Any(), gomock.Any()).
+		Return(&service.ListMultipartUploadsOutput{
+			HasMore: service.Bool(false),
+			Uploads: []*service.UploadsType{
+				{Key: service.String(key), UploadID: service.String(uploadIDs[0]), Created: &created},
+				{Key: service.String(key), UploadID: service.String(uploadIDs[1]), Created: service.Time(time.Now())},
+			},
+		}, nil)
+
+	it, err = client.List(path, pairs.WithListMode(ListModePart), WithModifiedBefore(time.Now().Add(-time.Minute)))
+	assert.NoError(t, err)
+	o, err := it.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, uploadIDs[0], o.MustGetMultipartID())
+	_, err = it.Next()
+	assert.True(t, errors.Is(err, IterateDone))
+}
+
+func TestStorage_Move(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	name := uuid.New().String()
+	location := uuid.New().String()
+
+	tests := []struct {
+		name     string
+		src      string
+		dst      string
+		mockFn   func(context.Context, string, *service.PutObjectInput)
+		hasError bool
+		wantErr  error
+	}{
+		{
+			"valid copy",
+			"test_src", "test_dst",
+			func(ctx context.Context, inputObjectKey string, input *service.PutObjectInput) {
+				assert.Equal(t, "test_dst", inputObjectKey)
+				assert.Equal(t, "/"+name+"/"+"test_src", *input.XQSMoveSource)
+			},
+			false, nil,
+		},
+	}
+
+	for _, v := range tests {
+		mockBucket.EXPECT().PutObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).Do(v.mockFn)
+
+		client := Storage{
+			bucket: mockBucket,
+			properties: &service.Properties{
+				BucketName: &name,
+				Zone:       &location,
+			},
+		}
+
+		err := client.Move(v.src, v.dst)
+		if v.hasError {
+			assert.Error(t, err)
+			assert.True(t, errors.Is(err, v.wantErr))
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestStorage_Read(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	tests := []struct {
+		name     string
+		path     string
+		pairs    []Pair
+		mockFn   func(context.Context, string, *service.GetObjectInput) (*service.GetObjectOutput, error)
+		hasError bool
+		wantErr  error
+	}{
+		{
+			"valid copy",
+			"test_src",
+			nil,
+			func(ctx context.Context, inputPath string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+				assert.Equal(t, "test_src", inputPath)
+				return &service.GetObjectOutput{
+					Body: ioutil.NopCloser(bytes.NewBuffer([]byte("content"))),
+				}, nil
+			},
+			false, nil,
+		},
+		{
+			"with if match",
+			"test_src",
+			[]Pair{WithIfMatch("test_etag")},
+			func(ctx context.Context, inputPath string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+				assert.Equal(t, "test_etag", *input.IfMatch)
+				return &service.GetObjectOutput{
+					StatusCode: service.Int(http.StatusPreconditionFailed),
+					Body:       ioutil.NopCloser(bytes.NewBuffer(nil)),
+				}, nil
+			},
+			true, ErrPreconditionFailed,
+		},
+		{
+			"with if none match",
+			"test_src",
+			[]Pair{WithIfNoneMatch("test_etag"), WithIfModifiedSince(time.Unix(1600000000, 0))},
+			func(ctx context.Context, inputPath string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+				assert.Equal(t, "test_etag", *input.IfNoneMatch)
+				assert.Equal(t, int64(1600000000), input.IfModifiedSince.Unix())
+				return &service.GetObjectOutput{
+					StatusCode: service.Int(http.StatusNotModified),
+				}, nil
+			},
+			true, ErrObjectNotModified,
+		},
+		{
+			"with suffix size",
+			"test_src",
+			[]Pair{WithSuffixSize(7)},
+			func(ctx context.Context, inputPath string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+				assert.Equal(t, "bytes=-7", *input.Range)
+				return &service.GetObjectOutput{
+					Body: ioutil.NopCloser(bytes.NewBuffer([]byte("content"))),
+				}, nil
+			},
+			false, nil,
+		},
+	}
+
+	for _, v := range tests {
+		mockBucket.EXPECT().GetObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).DoAndReturn(v.mockFn)
+
+		client := Storage{
+			bucket: mockBucket,
+		}
+
+		var buf bytes.Buffer
+		n, err := client.Read(v.path, &buf, v.pairs...)
+		if v.hasError {
+			assert.Error(t, err)
+			assert.True(t, errors.Is(err, v.wantErr))
+		} else {
+			assert.Equal(t, "content", buf.String())
+			assert.Equal(t, int64(buf.Len()), n)
+		}
+	}
+}
+
+func TestStorage_Stat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	tests := []struct {
+		name     string
+		src      string
+		mockFn   func(context.Context, string, *service.HeadObjectInput) (*service.HeadObjectOutput, error)
+		hasError bool
+		wantErr  error
+	}{
+		{
+			"valid file",
+			"test_src",
+			func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+				assert.Equal(t, "test_src", objectKey)
+				length := int64(100)
+				return &service.HeadObjectOutput{
+					ContentLength:   &length,
+					ContentType:     convert.String("test_content_type"),
+					ETag:            convert.String("test_etag"),
+					XQSStorageClass: convert.String("STANDARD"),
+					XQSMetaData: &map[string]string{
+						"x-qs-meta-tenant": "test_tenant",
+					},
+				}, nil
+			},
+			false, nil,
+		},
+	}
+
+	for _, v := range tests {
+		mockBucket.EXPECT().HeadObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).DoAndReturn(v.mockFn)
+
+		client := Storage{
+			bucket: mockBucket,
+		}
+
+		o, err := client.Stat(v.src)
+		if v.hasError {
+			assert.Error(t, err)
+			assert.True(t, errors.Is(err, v.wantErr))
+		} else {
+			assert.NoError(t, err)
+			assert.NotNil(t, o)
+			assert.Equal(t, ModeRead, o.Mode)
+			assert.Equal(t, int64(100), o.MustGetContentLength())
+			contentType, ok := o.GetContentType()
+			assert.True(t, ok)
+			assert.Equal(t, "test_content_type", contentType)
+			checkSum, ok := o.GetEtag()
+			assert.True(t, ok)
+			assert.Equal(t, "test_etag", checkSum)
+
+			om := GetObjectSystemMetadata(o)
+			assert.Equal(t, StorageClassStandard, om.StorageClass)
+			assert.Equal(t, map[string]string{"tenant": "test_tenant"}, o.MustGetUserMetadata())
+		}
+	}
+}
+
+func TestStorage_Write(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	tests := []struct {
+		name     string
+		path     string
+		size     int64
+		r        io.Reader
+		pairs    []Pair
+		mockFn   func(context.Context, string, *service.PutObjectInput) (*service.PutObjectOutput, error)
+		hasError bool
+		wantErr  error
+	}{
+		{
 			"valid copy",
 			"test_src",
 			100,
 			io.LimitReader(randbytes.NewRand(), 100),
+			nil,
 			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
 				assert.Equal(t, "test_src", inputPath)
 				return nil, nil
 			},
-			false, nil,
+			false, nil,
+		},
+		{
+			"with content disposition",
+			"test_src",
+			100,
+			io.LimitReader(randbytes.NewRand(), 100),
+			[]Pair{WithContentDisposition("attachment")},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, "attachment", requestHeadersFromContext(ctx).Get("Content-Disposition"))
+				return nil, nil
+			},
+			false, nil,
+		},
+		{
+			"with cache control",
+			"test_src",
+			100,
+			io.LimitReader(randbytes.NewRand(), 100),
+			[]Pair{WithCacheControl("max-age=31536000")},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, "max-age=31536000", *input.CacheControl)
+				return nil, nil
+			},
+			false, nil,
+		},
+		{
+			"with content encoding",
+			"test_src",
+			100,
+			io.LimitReader(randbytes.NewRand(), 100),
+			[]Pair{WithContentEncoding("gzip")},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, "gzip", *input.ContentEncoding)
+				return nil, nil
+			},
+			false, nil,
+		},
+		{
+			"with expires",
+			"test_src",
+			100,
+			io.LimitReader(randbytes.NewRand(), 100),
+			[]Pair{WithExpires(time.Date(2021, 9, 13, 0, 0, 0, 0, time.UTC))},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, "Mon, 13 Sep 2021 00:00:00 GMT", requestHeadersFromContext(ctx).Get("Expires"))
+				return nil, nil
+			},
+			false, nil,
+		},
+		{
+			"object already exists",
+			"test_src",
+			100,
+			io.LimitReader(randbytes.NewRand(), 100),
+			[]Pair{WithIfNoneMatch("*")},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, "*", requestHeadersFromContext(ctx).Get("If-None-Match"))
+				return nil, &qerror.QingStorError{StatusCode: 412}
+			},
+			true, ErrObjectAlreadyExist,
+		},
+		{
+			"with auto content md5",
+			"test_src",
+			7,
+			bytes.NewBufferString("content"),
+			[]Pair{WithAutoContentMd5()},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, "mgNkuembtIDdJeHwKEyFVQ==", *input.ContentMD5)
+				content, err := ioutil.ReadAll(input.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, "content", string(content))
+				return nil, nil
+			},
+			false, nil,
+		},
+		{
+			"with verify etag",
+			"test_src",
+			7,
+			bytes.NewBufferString("content"),
+			[]Pair{WithVerifyEtag()},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				_, err := ioutil.ReadAll(input.Body)
+				assert.NoError(t, err)
+				return &service.PutObjectOutput{ETag: service.String("\"9a0364b9e99bb480dd25e1f0284c8555\"")}, nil
+			},
+			false, nil,
+		},
+		{
+			"content corrupted",
+			"test_src",
+			7,
+			bytes.NewBufferString("content"),
+			[]Pair{WithVerifyEtag()},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				_, err := ioutil.ReadAll(input.Body)
+				assert.NoError(t, err)
+				return &service.PutObjectOutput{ETag: service.String("\"d41d8cd98f00b204e9800998ecf8427e\"")}, nil
+			},
+			true, ErrContentCorrupted,
+		},
+		{
+			"with content type",
+			"test_src",
+			100,
+			io.LimitReader(randbytes.NewRand(), 100),
+			[]Pair{pairs.WithContentType("application/json"), WithDetectContentType()},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, "application/json", *input.ContentType)
+				return nil, nil
+			},
+			false, nil,
+		},
+		{
+			"with detect content type",
+			"index.html",
+			100,
+			io.LimitReader(randbytes.NewRand(), 100),
+			[]Pair{WithDetectContentType()},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, "text/html; charset=utf-8", *input.ContentType)
+				return nil, nil
+			},
+			false, nil,
+		},
+		{
+			"with user metadata",
+			"test_src",
+			100,
+			io.LimitReader(randbytes.NewRand(), 100),
+			[]Pair{WithUserMetadata(map[string]string{"tenant": "test_tenant"})},
+			func(ctx context.Context, inputPath string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, map[string]string{"x-qs-meta-tenant": "test_tenant"}, *input.XQSMetaData)
+				return nil, nil
+			},
+			false, nil,
+		},
+	}
+
+	for _, v := range tests {
+		mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(v.mockFn)
+
+		client := Storage{
+			bucket: mockBucket,
+		}
+
+		n, err := client.Write(v.path, v.r, v.size, v.pairs...)
+		if v.hasError {
+			assert.Error(t, err)
+			assert.True(t, errors.Is(err, v.wantErr))
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, v.size, n)
+		}
+	}
+}
+
+func TestStorage_WriteUnknownSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	content := []byte(uuid.NewString())
+
+	// Small content with unknown size will be uploaded via a single PUT.
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, int64(len(content)), *input.ContentLength)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	n, err := c.Write(uuid.NewString(), ioutil.NopCloser(bytes.NewReader(content)), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+
+	_, err = c.Write(uuid.NewString(), nil, -1)
+	assert.Error(t, err)
+}
+
+func TestStorage_WriteMultipartThreshold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	size := int64(9 * 1024 * 1024)
+	uploadID := uuid.NewString()
+
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.InitiateMultipartUploadOutput{UploadID: service.String(uploadID)}, nil)
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(3).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			assert.Equal(t, uploadID, *input.UploadID)
+			return &service.UploadMultipartOutput{ETag: service.String(uuid.NewString())}, nil
+		})
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.CompleteMultipartUploadInput) (*service.CompleteMultipartUploadOutput, error) {
+			assert.Equal(t, 3, len(input.ObjectParts))
+			for k, v := range input.ObjectParts {
+				assert.Equal(t, k, *v.PartNumber)
+			}
+			assert.Equal(t, int64(size-8*1024*1024), *input.ObjectParts[2].Size)
+			return &service.CompleteMultipartUploadOutput{}, nil
+		})
+
+	n, err := c.Write(uuid.NewString(), io.LimitReader(randbytes.NewRand(), size), size,
+		WithMultipartThreshold(1024*1024),
+		WithMultipartPartSize(4*1024*1024),
+		WithMultipartConcurrency(2),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, size, n)
+
+	_, err = c.Write(uuid.NewString(), io.LimitReader(randbytes.NewRand(), size), size,
+		WithMultipartThreshold(1024*1024),
+		WithMultipartPartSize(1024),
+	)
+	assert.True(t, errors.Is(err, ErrPartSizeInvalid))
+}
+
+func TestStorage_WriteDetectContentTypeSniff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	// Path without extension, content will be sniffed.
+	path := uuid.NewString()
+	content := []byte("<html><body>" + uuid.NewString() + "</body></html>")
+
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, "text/html; charset=utf-8", *input.ContentType)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	_, err := c.Write(path, bytes.NewReader(content), -1, WithDetectContentType())
+	assert.NoError(t, err)
+
+	var got []byte
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.InitiateMultipartUploadInput) (*service.InitiateMultipartUploadOutput, error) {
+			assert.Equal(t, "text/html; charset=utf-8", *input.ContentType)
+			return &service.InitiateMultipartUploadOutput{UploadID: service.String(uuid.NewString())}, nil
+		})
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			body, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			got = append(got, body...)
+			return &service.UploadMultipartOutput{ETag: service.String(uuid.NewString())}, nil
+		})
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.CompleteMultipartUploadOutput{}, nil)
+
+	opt, err := c.parsePairStorageWrite([]Pair{WithDetectContentType()})
+	assert.NoError(t, err)
+	n, err := c.writeMultipartStream(context.Background(), path, bytes.NewReader(content), -1, 16, 1, opt)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	// Sniffed head should be uploaded as well.
+	assert.Equal(t, content, got)
+}
+
+func TestStorage_WriteMultipartUnsupportedPairs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	content := []byte("test")
+	sum := md5.Sum(content)
+
+	cases := []struct {
+		name string
+		pair Pair
+	}{
+		{"verify etag", WithVerifyEtag()},
+		{"content md5", pairs.WithContentMd5(base64.StdEncoding.EncodeToString(sum[:]))},
+		{"if none match", WithIfNoneMatch("*")},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// No request should be sent for pairs that multipart upload could not honor.
+			size := int64(2 * 1024 * 1024)
+			_, err := c.Write(uuid.NewString(), io.LimitReader(randbytes.NewRand(), size), size,
+				WithMultipartThreshold(1024*1024), tt.pair)
+			assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+			// Stream fits in one part will be written via a single PUT.
+			mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(2).
+				DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+					body, err := ioutil.ReadAll(input.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, content, body)
+					return &service.PutObjectOutput{ETag: service.String(fmt.Sprintf("\"%x\"", sum))}, nil
+				})
+
+			n, err := c.Write(uuid.NewString(), bytes.NewReader(content), -1, tt.pair)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(content)), n)
+
+			w, err := c.Writer(uuid.NewString(), tt.pair)
+			assert.NoError(t, err)
+			_, err = w.Write(content)
+			assert.NoError(t, err)
+			assert.NoError(t, w.Close())
+		})
+	}
+}
+
+func TestStorage_StorageClassInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Every operation accepting storage_class should reject it before any request is sent.
+	c := Storage{
+		bucket:   NewMockBucket(ctrl),
+		workDir:  "/",
+		features: StorageFeatures{VirtualDir: true},
+	}
+	sc := WithStorageClass("invalid")
+
+	cases := []struct {
+		name string
+		fn   func() error
+	}{
+		{"write", func() error {
+			_, err := c.Write(uuid.NewString(), bytes.NewReader([]byte("test")), 4, sc)
+			return err
+		}},
+		{"write multipart", func() error {
+			size := int64(2 * 1024 * 1024)
+			_, err := c.Write(uuid.NewString(), io.LimitReader(randbytes.NewRand(), size), size,
+				WithMultipartThreshold(1024*1024), sc)
+			return err
+		}},
+		{"write stream", func() error {
+			_, err := c.Write(uuid.NewString(), bytes.NewReader([]byte("test")), -1, sc)
+			return err
+		}},
+		{"writer", func() error {
+			_, err := c.Writer(uuid.NewString(), sc)
+			return err
+		}},
+		{"create dir", func() error {
+			_, err := c.CreateDir(uuid.NewString(), sc)
+			return err
+		}},
+		{"create append", func() error {
+			_, err := c.CreateAppend(uuid.NewString(), sc)
+			return err
+		}},
+		{"create multipart", func() error {
+			_, err := c.CreateMultipart(uuid.NewString(), sc)
+			return err
+		}},
+		{"copy", func() error {
+			return c.Copy(uuid.NewString(), uuid.NewString(), sc)
+		}},
+		{"update metadata", func() error {
+			return c.UpdateMetadata(uuid.NewString(), sc)
+		}},
+		{"query sign http write", func() error {
+			_, err := c.QuerySignHTTPWrite(uuid.NewString(), 4, time.Hour, sc)
+			return err
+		}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+			assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+		})
+	}
+}
+
+func TestStorage_WriteSizeExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := Storage{
+		bucket:  NewMockBucket(ctrl),
+		workDir: "/",
+	}
+
+	// No request should be sent while size exceeds the limit.
+	size := int64(writeSizeMaximum + 1)
+	_, err := c.Write(uuid.NewString(), io.LimitReader(randbytes.NewRand(), size), size)
+	assert.True(t, errors.Is(err, ErrWriteSizeExceeded))
+	assert.True(t, errors.Is(err, services.ErrRestrictionDissatisfied))
+}
+
+func TestStorage_formatError(t *testing.T) {
+	s := &Storage{}
+	errCasual := errors.New("casual error")
+	cases := []struct {
+		name      string
+		op        string
+		err       error
+		path      string
+		targetErr error
+		targetEq  bool
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+		},
+		{
+			name:      "casual error",
+			op:        "stat",
+			err:       errCasual,
+			targetErr: services.ErrUnexpected,
+			targetEq:  true,
+		},
+		{
+			name: "not found with blank code",
+			op:   "stat",
+			err: &qerror.QingStorError{
+				StatusCode: 404,
+				Code:       "",
+				Message:    "msg",
+			},
+			targetErr: services.ErrObjectNotExist,
+			targetEq:  true,
+		},
+		{
+			name: "not found by code",
+			op:   "stat",
+			err: &qerror.QingStorError{
+				StatusCode: 404,
+				Code:       "object_not_exists",
+				Message:    "msg",
+			},
+			targetErr: services.ErrObjectNotExist,
+			targetEq:  true,
+		},
+		{
+			name: "permission denied by code",
+			op:   "stat",
+			err: &qerror.QingStorError{
+				StatusCode: 403,
+				Code:       "permission_denied",
+				Message:    "msg",
+			},
+			targetErr: services.ErrPermissionDenied,
+			targetEq:  true,
+		},
+		{
+			name: "not found by code error not eq",
+			op:   "stat",
+			err: qerror.QingStorError{
+				StatusCode: 404,
+				Code:       "object_not_exists",
+				Message:    "msg",
+			},
+			targetErr: services.ErrObjectNotExist,
+			targetEq:  false,
+		},
+	}
+	for _, tt := range cases {
+		err := s.formatError(tt.op, tt.err, tt.path)
+		if tt.err == nil {
+			assert.Nil(t, err, tt.name)
+			continue
+		}
+
+		var storageErr services.StorageError
+		assert.True(t, errors.As(err, &storageErr), tt.name)
+		assert.Equal(t, tt.op, storageErr.Op, tt.name)
+
+		assert.Equal(t, tt.targetEq, errors.Is(err, tt.targetErr), tt.name)
+	}
+}
+
+func TestStorage_Fetch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	{
+		client := Storage{
+			bucket: mockBucket,
+		}
+
+		name := uuid.New().String()
+		url := uuid.New().String()
+
+		mockBucket.EXPECT().PutObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				assert.Equal(t, name, objectKey)
+				assert.Equal(t, *input.XQSFetchSource, url)
+				return &service.PutObjectOutput{}, nil
+			})
+		err := client.Fetch(name, url)
+		assert.NoError(t, err)
+	}
+
+	{
+		client := Storage{
+			bucket: mockBucket,
+		}
+
+		name := uuid.New().String()
+		url := uuid.New().String()
+
+		mockBucket.EXPECT().PutObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				return nil, &qerror.QingStorError{}
+			})
+		err := client.Fetch(name, url)
+		assert.Error(t, err)
+	}
+}
+
+func TestStorage_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	wd := "/test/"
+	c := Storage{
+		workDir: wd,
+		bucket:  mockBucket,
+	}
+
+	cases := []struct {
+		name        string
+		path        string
+		multipartID string
+	}{
+		{
+			name:        "normal object",
+			path:        uuid.NewString(),
+			multipartID: "",
+		},
+		{
+			name:        "multipart object",
+			path:        uuid.NewString(),
+			multipartID: uuid.NewString(),
+		},
+	}
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+			return &service.HeadObjectOutput{}, nil
+		}).Times(1)
+
+	for _, tt := range cases {
+		ps := make([]Pair, 0)
+		if tt.multipartID != "" {
+			ps = append(ps, pairs.WithMultipartID(tt.multipartID))
+		}
+		obj := c.Create(tt.path, ps...)
+		assert.NotNil(t, obj)
+		assert.Equal(t, c.getAbsPath(tt.path), obj.ID)
+		assert.Equal(t, tt.path, obj.Path)
+		if tt.multipartID != "" {
+			assert.Equal(t, tt.multipartID, obj.MustGetMultipartID())
+			assert.Equal(t, ModePart, obj.Mode)
+		} else {
+			assert.Equal(t, ModeRead, obj.Mode)
+			assert.Panics(t, func() {
+				obj.MustGetMultipartID()
+			})
+		}
+	}
+}
+
+func TestStorage_CreateAppend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+			return &service.HeadObjectOutput{}, nil
+		})
+	mockBucket.EXPECT().DeleteObjectWithContext(gomock.Eq(context.Background()), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string) (*service.DeleteObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			return &service.DeleteObjectOutput{}, nil
+		})
+	mockBucket.EXPECT().AppendObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.AppendObjectInput) (*service.AppendObjectOutput, error) {
+			assert.Equal(t, int64(0), *input.Position)
+			assert.Equal(t, StorageClassStandardIA, *input.XQSStorageClass)
+			return &service.AppendObjectOutput{
+				XQSNextAppendPosition: service.Int64(0),
+			}, nil
+		})
+
+	o, err := c.CreateAppend(path, WithStorageClass(StorageClassStandardIA))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), o.MustGetAppendOffset())
+	assert.Equal(t, StorageClassStandardIA, GetObjectSystemMetadata(o).StorageClass)
+
+	_, err = c.CreateAppend(path, WithStorageClass("invalid"))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_WriteAppend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+
+	// Append offset will be recovered via Stat while resuming appending.
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			return &service.HeadObjectOutput{
+				XQSNextAppendPosition: service.Int64(100),
+			}, nil
+		})
+	mockBucket.EXPECT().AppendObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.AppendObjectInput) (*service.AppendObjectOutput, error) {
+			assert.Equal(t, int64(100), *input.Position)
+			return &service.AppendObjectOutput{
+				XQSNextAppendPosition: service.Int64(100 + int64(len(content))),
+			}, nil
+		})
+
+	o, err := c.Stat(path)
+	assert.NoError(t, err)
+	assert.True(t, o.Mode.IsAppend())
+
+	n, err := c.WriteAppend(o, bytes.NewReader(content), int64(len(content)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, 100+int64(len(content)), o.MustGetAppendOffset())
+}
+
+func TestStorage_Writer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			assert.Equal(t, int64(len(content)), *input.ContentLength)
+			body, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, content, body)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	w, err := c.Writer(path)
+	assert.NoError(t, err)
+
+	_, err = w.Write(content[:10])
+	assert.NoError(t, err)
+	_, err = w.Write(content[10:])
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+}
+
+func TestStorage_WriteMultipartInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No request should be sent for invalid parts.
+	c := Storage{
+		bucket:  NewMockBucket(ctrl),
+		workDir: "/",
+	}
+	o := c.Create(uuid.NewString(), pairs.WithMultipartID(uuid.NewString()))
+
+	cases := []struct {
+		name  string
+		size  int64
+		index int
+		want  error
+	}{
+		{"negative index", 1, -1, ErrPartNumberInvalid},
+		{"index too large", 1, multipartNumberMaximum + 1, ErrPartNumberInvalid},
+		{"negative size", -1, 0, ErrPartSizeInvalid},
+		{"size too large", multipartSizeMaximum + 1, 0, ErrPartSizeInvalid},
+		{"size restriction", multipartSizeMaximum + 1, 0, services.ErrRestrictionDissatisfied},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := c.WriteMultipart(o, bytes.NewReader(nil), tt.size, tt.index)
+			assert.True(t, errors.Is(err, tt.want))
+		})
+	}
+}
+
+func TestStorage_MultipartCheckpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/test/",
+	}
+
+	path, uploadID := uuid.NewString(), uuid.NewString()
+
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.InitiateMultipartUploadOutput{UploadID: service.String(uploadID)}, nil)
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			return &service.UploadMultipartOutput{ETag: service.String(fmt.Sprintf("etag-%d", *input.PartNumber))}, nil
+		}).Times(3)
+
+	o, err := c.CreateMultipart(path)
+	assert.NoError(t, err)
+
+	_, p1, err := c.WriteMultipart(o, bytes.NewReader([]byte("1")), 1, 1)
+	assert.NoError(t, err)
+	_, p0, err := c.WriteMultipart(o, bytes.NewReader([]byte("0")), 1, 0)
+	assert.NoError(t, err)
+
+	cp, err := c.NewMultipartCheckpoint(o, []*Part{p1, p0})
+	assert.NoError(t, err)
+	data, err := json.Marshal(cp)
+	assert.NoError(t, err)
+
+	// Resume the upload from checkpoint in another storager.
+	rc := Storage{
+		bucket:  mockBucket,
+		workDir: "/test/",
+	}
+	var rcp MultipartCheckpoint
+	assert.NoError(t, json.Unmarshal(data, &rcp))
+	ro, parts, err := rc.ResumeMultipart(&rcp)
+	assert.NoError(t, err)
+	assert.Equal(t, "test/"+path, ro.ID)
+	assert.Equal(t, path, ro.Path)
+	assert.Equal(t, uploadID, ro.MustGetMultipartID())
+	assert.Equal(t, []*Part{p0, p1}, parts)
+
+	_, p2, err := rc.WriteMultipart(ro, bytes.NewReader([]byte("2")), 1, 2)
+	assert.NoError(t, err)
+	rcp.AddPart(p2)
+	assert.Len(t, rcp.Parts, 3)
+
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.CompleteMultipartUploadInput) (*service.CompleteMultipartUploadOutput, error) {
+			assert.Equal(t, "test/"+path, objectKey)
+			assert.Equal(t, uploadID, *input.UploadID)
+			assert.Len(t, input.ObjectParts, 3)
+			for i, p := range input.ObjectParts {
+				assert.Equal(t, i, *p.PartNumber)
+				assert.Equal(t, fmt.Sprintf("etag-%d", i), *p.Etag)
+			}
+			return &service.CompleteMultipartUploadOutput{}, nil
+		})
+	// Content length falls back to the size of parts if HEAD failed.
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 400})
+	_, parts, err = rc.ResumeMultipart(&rcp)
+	assert.NoError(t, err)
+	assert.NoError(t, rc.CompleteMultipart(ro, parts))
+	assert.Equal(t, int64(3), ro.MustGetContentLength())
+
+	_, err = c.NewMultipartCheckpoint(c.Create(path), nil)
+	assert.True(t, errors.Is(err, services.ErrObjectModeInvalid))
+
+	_, _, err = c.ResumeMultipart(&MultipartCheckpoint{Key: "test/" + path})
+	assert.True(t, errors.Is(err, services.ErrRestrictionDissatisfied))
+}
+
+func TestStorage_ListMultipartAndListParts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path, uploadID := uuid.NewString(), uuid.NewString()
+	created := time.Now().Truncate(time.Second)
+
+	// Parts are returned in two pages.
+	mockBucket.EXPECT().ListMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.ListMultipartInput) (*service.ListMultipartOutput, error) {
+			assert.Equal(t, path, objectKey)
+			assert.Equal(t, uploadID, *input.UploadID)
+
+			idx := *input.PartNumberMarker
+			return &service.ListMultipartOutput{
+				Count: service.Int(2),
+				ObjectParts: []*service.ObjectPartType{{
+					PartNumber: service.Int(idx),
+					Size:       service.Int64(int64(idx + 1)),
+					Etag:       service.String(fmt.Sprintf("etag-%d", idx)),
+					Created:    service.Time(created),
+				}},
+			}, nil
+		}).Times(4)
+
+	o := c.Create(path, pairs.WithMultipartID(uploadID))
+
+	it, err := c.ListMultipart(o)
+	assert.NoError(t, err)
+	var got []*Part
+	for {
+		p, err := it.Next()
+		if errors.Is(err, IterateDone) {
+			break
+		}
+		assert.NoError(t, err)
+		got = append(got, p)
+	}
+	assert.Equal(t, []*Part{
+		{Index: 0, Size: 1, ETag: "etag-0"},
+		{Index: 1, Size: 2, ETag: "etag-1"},
+	}, got)
+
+	parts, err := c.ListParts(o)
+	assert.NoError(t, err)
+	assert.Equal(t, []*MultipartPart{
+		{Part: Part{Index: 0, Size: 1, ETag: "etag-0"}, Created: created},
+		{Part: Part{Index: 1, Size: 2, ETag: "etag-1"}, Created: created},
+	}, parts)
+
+	_, err = c.ListParts(c.Create(path))
+	assert.True(t, errors.Is(err, services.ErrObjectModeInvalid))
+}
+
+func TestStorage_Upload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path, uploadID := uuid.NewString(), uuid.NewString()
+	content, _ := ioutil.ReadAll(io.LimitReader(randbytes.NewRand(), 9*1024*1024))
+
+	var (
+		mu     sync.Mutex
+		failed bool
+	)
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.InitiateMultipartUploadOutput{UploadID: service.String(uploadID)}, nil)
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			data, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+
+			// The second part fails once, and should be retried with the same content.
+			mu.Lock()
+			defer mu.Unlock()
+			if *input.PartNumber == 1 && !failed {
+				failed = true
+				return nil, &qerror.QingStorError{StatusCode: 503}
+			}
+			offset := *input.PartNumber * 4 * 1024 * 1024
+			assert.Equal(t, content[offset:offset+len(data)], data)
+			return &service.UploadMultipartOutput{ETag: service.String(fmt.Sprintf("etag-%d", *input.PartNumber))}, nil
+		}).Times(4)
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.CompleteMultipartUploadInput) (*service.CompleteMultipartUploadOutput, error) {
+			assert.Equal(t, uploadID, *input.UploadID)
+			assert.Len(t, input.ObjectParts, 3)
+			return &service.CompleteMultipartUploadOutput{}, nil
+		})
+
+	// Bytes sent again by the retry should not be reported twice.
+	var reported int64
+	n, err := c.Upload(path, bytes.NewReader(content), int64(len(content)),
+		WithMultipartThreshold(4*1024*1024), WithMultipartPartSize(4*1024*1024),
+		pairs.WithIoCallback(func(b []byte) {
+			atomic.AddInt64(&reported, int64(len(b)))
+		}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, int64(len(content)), atomic.LoadInt64(&reported))
+
+	// Failed upload should be aborted.
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.InitiateMultipartUploadOutput{UploadID: service.String(uploadID)}, nil)
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			_, _ = io.Copy(ioutil.Discard, input.Body)
+			return nil, &qerror.QingStorError{StatusCode: 503}
+		}).Times(2)
+	mockBucket.EXPECT().AbortMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.AbortMultipartUploadOutput{}, nil)
+
+	_, err = c.Upload(path, bytes.NewReader(content), int64(len(content)),
+		WithMultipartThreshold(4*1024*1024), WithMultipartPartSize(8*1024*1024),
+		WithMultipartConcurrency(1), WithMultipartRetry(1))
+	assert.Error(t, err)
+}
+
+func TestStorage_UploadSinglePutRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	content := []byte(uuid.NewString())
+
+	failed := false
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			body, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, content, body)
+			if !failed {
+				failed = true
+				return nil, &qerror.QingStorError{StatusCode: 503}
+			}
+			return &service.PutObjectOutput{}, nil
+		}).Times(2)
+
+	// Reader that could not be rewound will be buffered.
+	var reported int64
+	n, err := c.Upload(uuid.NewString(), ioutil.NopCloser(bytes.NewReader(content)), int64(len(content)),
+		pairs.WithIoCallback(func(b []byte) {
+			reported += int64(len(b))
+		}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, int64(len(content)), reported)
+}
+
+func TestStorage_UploadConcurrency(t *testing.T) {
+	cases := []struct {
+		name    string
+		storage int
+		pairs   []Pair
+		expect  int32
+	}{
+		{"storage", 2, nil, 2},
+		{"call overrides storage", 2, []Pair{WithUploadConcurrency(1)}, 1},
+		{"call only", 0, []Pair{WithUploadConcurrency(1)}, 1},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockBucket := NewMockBucket(ctrl)
+
+			c := Storage{
+				bucket:            mockBucket,
+				workDir:           "/",
+				uploadConcurrency: tt.storage,
+			}
+
+			content, _ := ioutil.ReadAll(io.LimitReader(randbytes.NewRand(), 16*1024*1024))
+
+			var running, peak int32
+			mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&service.InitiateMultipartUploadOutput{UploadID: service.String(uuid.NewString())}, nil)
+			mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+					v := atomic.AddInt32(&running, 1)
+					defer atomic.AddInt32(&running, -1)
+					for {
+						p := atomic.LoadInt32(&peak)
+						if v <= p || atomic.CompareAndSwapInt32(&peak, p, v) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					_, _ = io.Copy(ioutil.Discard, input.Body)
+					return &service.UploadMultipartOutput{ETag: service.String(uuid.NewString())}, nil
+				}).Times(4)
+			mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&service.CompleteMultipartUploadOutput{}, nil)
+
+			opts := append([]Pair{WithMultipartThreshold(4 * 1024 * 1024), WithMultipartPartSize(4 * 1024 * 1024),
+				WithMultipartConcurrency(4)}, tt.pairs...)
+			_, err := c.Upload(uuid.NewString(), bytes.NewReader(content), int64(len(content)), opts...)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, atomic.LoadInt32(&peak), tt.expect)
+		})
+	}
+}
+
+func TestStorage_UploadConcurrencyInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No request should be sent while upload_concurrency is not positive.
+	c := Storage{
+		bucket:  NewMockBucket(ctrl),
+		workDir: "/",
+	}
+
+	_, err := c.Write(uuid.NewString(), bytes.NewReader([]byte("test")), 4, WithUploadConcurrency(0))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	_, err = c.Writer(uuid.NewString(), WithUploadConcurrency(-1))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_UploadMultipartProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content, _ := ioutil.ReadAll(io.LimitReader(randbytes.NewRand(), 9*1024*1024))
+
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.InitiateMultipartUploadOutput{UploadID: service.String(uuid.NewString())}, nil)
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			_, _ = io.Copy(ioutil.Discard, input.Body)
+			return &service.UploadMultipartOutput{ETag: service.String(uuid.NewString())}, nil
+		}).Times(3)
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.CompleteMultipartUploadOutput{}, nil)
+
+	var progress []MultipartProgress
+	_, err := c.Upload(path, bytes.NewReader(content), int64(len(content)),
+		WithMultipartThreshold(4*1024*1024), WithMultipartPartSize(4*1024*1024),
+		WithMultipartProgress(func(p MultipartProgress) {
+			progress = append(progress, p)
+		}))
+	assert.NoError(t, err)
+
+	assert.Len(t, progress, 3)
+	for i, p := range progress {
+		assert.Equal(t, i+1, p.Parts)
+		assert.Equal(t, int64(len(content)), p.Total)
+		if i > 0 {
+			assert.Greater(t, p.Bytes, progress[i-1].Bytes)
+		}
+	}
+	assert.Equal(t, float64(100), progress[2].Percent())
+	assert.Equal(t, float64(-1), MultipartProgress{Bytes: 1, Total: -1}.Percent())
+}
+
+func TestStorage_WriteMultipartContentMd5(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	content := []byte("hello, multipart")
+	sum := md5.Sum(content)
+	want := base64.StdEncoding.EncodeToString(sum[:])
+
+	var got []*string
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			data, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, content, data)
+			got = append(got, input.ContentMD5)
+			return &service.UploadMultipartOutput{}, nil
+		}).Times(3)
+
+	total := 0
+	fn := func(bs []byte) { total += len(bs) }
+
+	o := c.Create(uuid.NewString(), pairs.WithMultipartID(uuid.NewString()))
+	_, _, err := c.WriteMultipart(o, bytes.NewReader(content), int64(len(content)), 0, WithAutoContentMd5(), pairs.WithIoCallback(fn))
+	assert.NoError(t, err)
+	// Content read for calculating md5 should not be reported.
+	assert.Equal(t, len(content), total)
+
+	// Content not seekable will be buffered.
+	_, _, err = c.WriteMultipart(o, bytes.NewBuffer(content), int64(len(content)), 1, WithAutoContentMd5())
+	assert.NoError(t, err)
+
+	_, _, err = c.WriteMultipart(o, bytes.NewReader(content), int64(len(content)), 2, pairs.WithContentMd5(want))
+	assert.NoError(t, err)
+
+	for _, v := range got {
+		assert.Equal(t, want, service.StringValue(v))
+	}
+}
+
+func TestStorage_CreateMultipartHeaders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.InitiateMultipartUploadInput) (*service.InitiateMultipartUploadOutput, error) {
+			assert.Equal(t, StorageClassStandardIA, service.StringValue(input.XQSStorageClass))
+			assert.Equal(t, "application/json", service.StringValue(input.ContentType))
+			assert.Equal(t, map[string]string{"x-qs-meta-tenant": "test_tenant"}, *input.XQSMetaData)
+			return &service.InitiateMultipartUploadOutput{UploadID: service.String(uuid.NewString())}, nil
+		})
+
+	o, err := c.CreateMultipart(uuid.NewString(), WithStorageClass(StorageClassStandardIA),
+		pairs.WithContentType("application/json"), WithUserMetadata(map[string]string{"tenant": "test_tenant"}))
+	assert.NoError(t, err)
+
+	// Attributes should be recorded in the object like the ones written by a single PUT.
+	contentType, ok := o.GetContentType()
+	assert.True(t, ok)
+	assert.Equal(t, "application/json", contentType)
+	um, ok := o.GetUserMetadata()
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"tenant": "test_tenant"}, um)
+	assert.Equal(t, StorageClassStandardIA, GetObjectSystemMetadata(o).StorageClass)
+
+	_, err = c.CreateMultipart(uuid.NewString(), WithStorageClass("GLACIER"))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	// Pairs of write should be carried while switching to multipart upload.
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.InitiateMultipartUploadInput) (*service.InitiateMultipartUploadOutput, error) {
+			assert.Equal(t, StorageClassStandardIA, service.StringValue(input.XQSStorageClass))
+			assert.Equal(t, "text/plain; charset=utf-8", service.StringValue(input.ContentType))
+			return &service.InitiateMultipartUploadOutput{UploadID: service.String(uuid.NewString())}, nil
+		})
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			_, _ = io.Copy(ioutil.Discard, input.Body)
+			return &service.UploadMultipartOutput{}, nil
+		})
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.CompleteMultipartUploadOutput{}, nil)
+
+	_, err = c.Write(uuid.NewString()+".txt", bytes.NewReader([]byte("hello")), 5,
+		WithMultipartThreshold(1), WithStorageClass(StorageClassStandardIA), WithDetectContentType())
+	assert.NoError(t, err)
+}
+
+func TestStorage_CompleteMultipart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path, uploadID := uuid.NewString(), uuid.NewString()
+	modified := time.Now().Truncate(time.Second)
+
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.CompleteMultipartUploadOutput{}, nil)
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			return &service.HeadObjectOutput{
+				ContentLength:   service.Int64(10),
+				ContentType:     service.String("text/plain"),
+				ETag:            service.String(`"etag-2"`),
+				LastModified:    service.Time(modified),
+				XQSStorageClass: service.String(StorageClassStandardIA),
+				XQSMetaData:     &map[string]string{"X-Qs-Meta-Tenant": "test_tenant"},
+			}, nil
+		})
+
+	o := c.Create(path, pairs.WithMultipartID(uploadID))
+	err := c.CompleteMultipart(o, []*Part{{Index: 0, Size: 5, ETag: "a"}, {Index: 1, Size: 5, ETag: "b"}})
+	assert.NoError(t, err)
+	assert.True(t, o.Mode.IsRead())
+	assert.False(t, o.Mode.IsPart())
+	assert.Equal(t, int64(10), o.MustGetContentLength())
+	assert.Equal(t, `"etag-2"`, o.MustGetEtag())
+	assert.Equal(t, modified, o.MustGetLastModified())
+	assert.Equal(t, "text/plain", o.MustGetContentType())
+	assert.Equal(t, map[string]string{"tenant": "test_tenant"}, o.MustGetUserMetadata())
+	assert.Equal(t, StorageClassStandardIA, GetObjectSystemMetadata(o).StorageClass)
+}
+
+func TestStorage_CompleteMultipartInvalidParts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	o := c.Create(uuid.NewString(), pairs.WithMultipartID(uuid.NewString()))
+
+	cases := []struct {
+		name   string
+		parts  []*Part
+		expect string
+	}{
+		{"empty", nil, "no parts"},
+		{"gap", []*Part{{Index: 0, Size: 1}, {Index: 2, Size: 1}, {Index: 6, Size: 1}}, "part 1 is missing, parts 3-5 are missing"},
+		{"duplicated", []*Part{{Index: 0, Size: 1}, {Index: 0, Size: 1}}, "part 0 is duplicated"},
+		{"out of range", []*Part{{Index: -1, Size: 1}, {Index: 0, Size: 1}}, "part -1 is out of range"},
+		{"empty part", []*Part{{Index: 0}, {Index: 1, Size: 1}}, "part 0 is empty"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.CompleteMultipart(o, tt.parts)
+			assert.True(t, errors.Is(err, ErrPartsInvalid))
+			assert.Contains(t, err.Error(), tt.expect)
+		})
+	}
+
+	// Parts should be sorted by index while completing, and the last part could be empty.
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.CompleteMultipartUploadInput) (*service.CompleteMultipartUploadOutput, error) {
+			assert.Equal(t, 1, *input.ObjectParts[0].PartNumber)
+			assert.Equal(t, 2, *input.ObjectParts[1].PartNumber)
+			return &service.CompleteMultipartUploadOutput{}, nil
+		})
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{ContentLength: service.Int64(1)}, nil)
+
+	err := c.CompleteMultipart(o, []*Part{{Index: 2}, {Index: 1, Size: 1}})
+	assert.NoError(t, err)
+}
+
+func TestStorage_WriteMultipartAt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	content := []byte("abcdefgh")
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			data, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			offset := *input.PartNumber * 3
+			assert.Equal(t, content[offset:offset+int(*input.ContentLength)], data)
+			return &service.UploadMultipartOutput{ETag: service.String(fmt.Sprintf("etag-%d", *input.PartNumber))}, nil
+		}).Times(3)
+
+	o := c.Create(uuid.NewString(), pairs.WithMultipartID(uuid.NewString()))
+
+	// Parts are uploaded concurrently from the same reader.
+	r := bytes.NewReader(content)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			offset := int64(index * 3)
+			size := int64(3)
+			if offset+size > int64(len(content)) {
+				size = int64(len(content)) - offset
+			}
+			n, part, err := c.WriteMultipartAt(o, r, offset, size, index)
+			assert.NoError(t, err)
+			assert.Equal(t, size, n)
+			assert.Equal(t, fmt.Sprintf("etag-%d", index), part.ETag)
+		}(i)
+	}
+	wg.Wait()
+
+	_, _, err := c.WriteMultipartAt(o, r, -1, 3, 0)
+	assert.True(t, errors.Is(err, services.ErrRestrictionDissatisfied))
+}
+
+func TestStorage_RepairMultipart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path, uploadID := uuid.NewString(), uuid.NewString()
+	content := []byte("abcdefgh")
+
+	// Part 1 is truncated and part 2 is missing on server side.
+	mockBucket.EXPECT().ListMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.ListMultipartOutput{
+			Count: service.Int(2),
+			ObjectParts: []*service.ObjectPartType{
+				{PartNumber: service.Int(0), Size: service.Int64(3), Etag: service.String("etag-0")},
+				{PartNumber: service.Int(1), Size: service.Int64(2), Etag: service.String("broken")},
+			},
+		}, nil)
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Eq(path), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			data, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			switch *input.PartNumber {
+			case 1:
+				assert.Equal(t, []byte("def"), data)
+			case 2:
+				assert.Equal(t, []byte("gh"), data)
+			default:
+				t.Errorf("part %d should not be uploaded", *input.PartNumber)
+			}
+			return &service.UploadMultipartOutput{ETag: service.String(fmt.Sprintf("etag-%d", *input.PartNumber))}, nil
+		}).Times(2)
+
+	o := c.Create(path, pairs.WithMultipartID(uploadID))
+	parts, err := c.RepairMultipart(o, bytes.NewReader(content), []*Part{
+		{Index: 2, Size: 2},
+		{Index: 0, Size: 3},
+		{Index: 1, Size: 3},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*Part{
+		{Index: 0, Size: 3, ETag: "etag-0"},
+		{Index: 1, Size: 3, ETag: "etag-1"},
+		{Index: 2, Size: 2, ETag: "etag-2"},
+	}, parts)
+
+	_, err = c.RepairMultipart(o, bytes.NewReader(content), []*Part{{Index: 0, Size: 3}, {Index: 0, Size: 3}})
+	assert.True(t, errors.Is(err, services.ErrRestrictionDissatisfied))
+
+	// Offset of parts after a gap is unknown, so nothing will be listed or uploaded.
+	_, err = c.RepairMultipart(o, bytes.NewReader(content), []*Part{{Index: 0, Size: 3}, {Index: 2, Size: 2}})
+	assert.True(t, errors.Is(err, services.ErrRestrictionDissatisfied))
+
+	_, err = c.RepairMultipart(o, bytes.NewReader(content), []*Part{{Index: 1, Size: 3}, {Index: 2, Size: 3}})
+	assert.True(t, errors.Is(err, services.ErrRestrictionDissatisfied))
+}
+
+func TestStorage_ListMultipartPagination(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	o := c.Create(uuid.NewString(), pairs.WithMultipartID(uuid.NewString()))
+
+	mockBucket.EXPECT().ListMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.ListMultipartInput) (*service.ListMultipartOutput, error) {
+			assert.Equal(t, 1, *input.Limit)
+			assert.Equal(t, 2, *input.PartNumberMarker)
+			return &service.ListMultipartOutput{
+				Count:       service.Int(4),
+				ObjectParts: []*service.ObjectPartType{{PartNumber: service.Int(2), Size: service.Int64(1)}},
+			}, nil
+		})
+
+	it, err := c.ListMultipart(o, WithPageSize(1), pairs.WithContinuationToken("2"))
+	assert.NoError(t, err)
+	p, err := it.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, p.Index)
+	// The next page could be resumed from the token.
+	assert.Equal(t, "3", it.ContinuationToken())
+
+	_, err = c.ListMultipart(o, WithPageSize(0))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	_, err = c.ListMultipart(o, pairs.WithContinuationToken("invalid"))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_writeMultipartStream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	uploadID := uuid.NewString()
+	content := []byte(uuid.NewString())
+
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.InitiateMultipartUploadInput) (*service.InitiateMultipartUploadOutput, error) {
+			assert.Equal(t, path, objectKey)
+			return &service.InitiateMultipartUploadOutput{UploadID: service.String(uploadID)}, nil
+		})
+	var got []byte
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(4).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			assert.Equal(t, uploadID, *input.UploadID)
+			body, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			got = append(got, body...)
+			return &service.UploadMultipartOutput{ETag: service.String(uuid.NewString())}, nil
+		})
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.CompleteMultipartUploadInput) (*service.CompleteMultipartUploadOutput, error) {
+			assert.Equal(t, 4, len(input.ObjectParts))
+			for k, v := range input.ObjectParts {
+				assert.Equal(t, k, *v.PartNumber)
+			}
+			return &service.CompleteMultipartUploadOutput{}, nil
+		})
+
+	// uuid is 36 bytes, so it will be split into 4 parts.
+	n, err := c.writeMultipartStream(context.Background(), path, bytes.NewReader(content), -1, 10, 1, pairStorageWrite{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, got)
+
+	// Multipart upload will be aborted while uploading failed.
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.InitiateMultipartUploadOutput{UploadID: service.String(uploadID)}, nil)
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("upload failed"))
+	mockBucket.EXPECT().AbortMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.AbortMultipartUploadInput) (*service.AbortMultipartUploadOutput, error) {
+			assert.Equal(t, uploadID, *input.UploadID)
+			return &service.AbortMultipartUploadOutput{}, nil
+		})
+
+	_, err = c.writeMultipartStream(context.Background(), path, bytes.NewReader(content), -1, 10, 1, pairStorageWrite{})
+	assert.Error(t, err)
+}
+
+func TestStorage_Touch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+		properties: &service.Properties{
+			BucketName: service.String("test_bucket"),
+		},
+	}
+
+	path := uuid.NewString()
+
+	// Empty object will be created if not exist.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 404})
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			assert.Equal(t, int64(0), *input.ContentLength)
+			assert.Nil(t, input.XQSCopySource)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	assert.NoError(t, c.Touch(path))
+
+	// Existing object will be copied to itself with headers and metadata kept.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Equal(t, "bytes=0-0", *input.Range)
+			return &service.GetObjectOutput{
+				Body:         ioutil.NopCloser(strings.NewReader("a")),
+				CacheControl: service.String("max-age=60"),
+				ContentType:  service.String("text/plain"),
+				ContentRange: service.String("bytes 0-0/10"),
+				XQSMetaData:  &map[string]string{"X-QS-Meta-Tenant": "test_tenant"},
+			}, nil
+		})
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			assert.Equal(t, "/test_bucket/"+path, *input.XQSCopySource)
+			assert.Equal(t, metadataDirectiveReplace, *input.XQSMetadataDirective)
+			assert.Equal(t, "max-age=60", *input.CacheControl)
+			assert.Equal(t, "text/plain", *input.ContentType)
+			assert.Equal(t, map[string]string{"x-qs-meta-tenant": "test_tenant"}, *input.XQSMetaData)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	assert.NoError(t, c.Touch(path))
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 403, Code: "permission_denied"})
+
+	err := c.Touch(path)
+	assert.True(t, errors.Is(err, services.ErrPermissionDenied))
+
+	// Objects encrypted by customer key should be read and copied with the key.
+	key := bytes.Repeat([]byte{'k'}, 32)
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Equal(t, "AES256", *input.XQSEncryptionCustomerAlgorithm)
+			return &service.GetObjectOutput{ContentRange: service.String("bytes 0-0/10")}, nil
+		})
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, "AES256", *input.XQSEncryptionCustomerAlgorithm)
+			assert.Equal(t, "AES256", *input.XQSCopySourceEncryptionCustomerAlgorithm)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	err = c.Touch(path, WithEncryptionCustomerAlgorithm("AES256"), WithEncryptionCustomerKey(key))
+	assert.NoError(t, err)
+
+	// Objects larger than the limit of a single copy should be copied part by part.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{ContentRange: service.String(fmt.Sprintf("bytes 0-0/%d", int64(copySizeMaximum+1)))}, nil)
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Eq(path), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 403, Code: "permission_denied"})
+
+	err = c.Touch(path)
+	assert.True(t, errors.Is(err, services.ErrPermissionDenied))
+}
+
+func TestStorage_CreateDir(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:   mockBucket,
+		workDir:  "/",
+		features: StorageFeatures{VirtualDir: true},
+	}
+
+	path := uuid.NewString()
+
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, path+"/", objectKey)
+			assert.Equal(t, int64(0), *input.ContentLength)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	o, err := c.CreateDir(path)
+	assert.NoError(t, err)
+	assert.True(t, o.Mode.IsDir())
+
+	// Path with trailing slash will be treated as a directory.
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+			assert.Equal(t, path+"/", objectKey)
+			return &service.HeadObjectOutput{ContentLength: service.Int64(0)}, nil
+		})
+
+	o, err = c.Stat(path + "/")
+	assert.NoError(t, err)
+	assert.True(t, o.Mode.IsDir())
+
+	o, err = c.Stat(path+"/", pairs.WithObjectMode(ModeDir))
+	assert.NoError(t, err)
+	assert.True(t, o.Mode.IsDir())
+
+	// Directory could be written by Write with object mode or trailing slash.
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, path+"/", objectKey)
+			assert.Equal(t, int64(0), *input.ContentLength)
+			assert.Equal(t, "no-cache", *input.CacheControl)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	_, err = c.Write(path, nil, 0, pairs.WithObjectMode(ModeDir), WithCacheControl("no-cache"))
+	assert.NoError(t, err)
+	_, err = c.Write(path+"/", nil, 0, WithCacheControl("no-cache"))
+	assert.NoError(t, err)
+
+	_, err = c.Write(path+"/", bytes.NewReader([]byte("test")), 4)
+	assert.True(t, errors.Is(err, services.ErrRestrictionDissatisfied))
+	_, err = c.Write(path, nil, 0, pairs.WithObjectMode(ModeRead))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	c.features.VirtualDir = false
+	_, err = c.CreateDir(path)
+	assert.Error(t, err)
+	_, err = c.Write(path, nil, 0, pairs.WithObjectMode(ModeDir))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_UpdateMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+		properties: &service.Properties{
+			BucketName: service.String("test_bucket"),
+		},
+	}
+
+	path := uuid.NewString()
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			ContentType:     service.String("text/plain"),
+			XQSStorageClass: service.String(StorageClassStandard),
+			XQSMetaData: &map[string]string{
+				metadataLinkTargetHeader: "target",
+				"x-qs-meta-tenant":       "test_tenant",
+			},
+		}, nil)
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			assert.Equal(t, "/test_bucket/"+path, *input.XQSCopySource)
+			assert.Equal(t, "application/json", *input.ContentType)
+			assert.Equal(t, "no-cache", *input.CacheControl)
+			assert.Equal(t, StorageClassStandard, *input.XQSStorageClass)
+			assert.Equal(t, map[string]string{
+				metadataLinkTargetHeader: "target",
+				"x-qs-meta-owner":        "test_owner",
+			}, *input.XQSMetaData)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	err := c.UpdateMetadata(path,
+		pairs.WithContentType("application/json"),
+		WithCacheControl("no-cache"),
+		WithUserMetadata(map[string]string{"owner": "test_owner"}),
+	)
+	assert.NoError(t, err)
+
+	err = c.UpdateMetadata(path, pairs.WithSize(100))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	// Headers set while writing should be kept after user metadata updated.
+	expires := time.Now().UTC().Format(http.TimeFormat)
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: http.StatusRequestedRangeNotSatisfiable})
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			// Empty object will be read without range.
+			assert.Nil(t, input.Range)
+			return &service.GetObjectOutput{
+				CacheControl:       service.String("max-age=60"),
+				ContentDisposition: service.String("attachment"),
+				ContentEncoding:    service.String("gzip"),
+				Expires:            service.String(expires),
+				XQSMetaData:        &map[string]string{"x-qs-meta-tenant": "test_tenant"},
+			}, nil
+		})
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, "max-age=60", *input.CacheControl)
+			assert.Equal(t, "gzip", *input.ContentEncoding)
+			assert.Equal(t, map[string]string{"x-qs-meta-owner": "test_owner"}, *input.XQSMetaData)
+			h := requestHeadersFromContext(ctx)
+			assert.Equal(t, "attachment", h.Get("Content-Disposition"))
+			assert.Equal(t, expires, h.Get("Expires"))
+			return &service.PutObjectOutput{}, nil
+		})
+
+	err = c.UpdateMetadata(path, WithUserMetadata(map[string]string{"owner": "test_owner"}))
+	assert.NoError(t, err)
+}
+
+func TestStorage_StatWithEncryption(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	key := bytes.Repeat([]byte{'k'}, 32)
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
+			assert.Equal(t, "AES256", *input.XQSEncryptionCustomerAlgorithm)
+			assert.NotNil(t, input.XQSEncryptionCustomerKey)
+			assert.NotNil(t, input.XQSEncryptionCustomerKeyMD5)
+			return &service.HeadObjectOutput{
+				XQSEncryptionCustomerAlgorithm: service.String("AES256"),
+			}, nil
+		})
+
+	o, err := c.Stat(uuid.NewString(), WithEncryptionCustomerAlgorithm("AES256"), WithEncryptionCustomerKey(key))
+	assert.NoError(t, err)
+	assert.Equal(t, "AES256", GetObjectSystemMetadata(o).EncryptionCustomerAlgorithm)
+
+	_, err = c.Stat(uuid.NewString(), WithEncryptionCustomerAlgorithm("AES256"), WithEncryptionCustomerKey([]byte("short")))
+	assert.True(t, errors.Is(err, ErrEncryptionCustomerKeyInvalid))
+}
+
+func TestStorage_ClientEncryption(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	kp, err := NewAESKeyProvider(bytes.Repeat([]byte{'k'}, 32))
+	assert.NoError(t, err)
+
+	c := Storage{
+		bucket:      mockBucket,
+		workDir:     "/",
+		keyProvider: kp,
+	}
+
+	path := uuid.NewString()
+	size := int64(clientEncryptionChunkSize*2 + 100)
+	content, err := ioutil.ReadAll(io.LimitReader(randbytes.NewRand(), size))
+	assert.NoError(t, err)
+
+	var (
+		stored   []byte
+		metadata map[string]string
+	)
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, encryptedSize(size), *input.ContentLength)
+			stored, err = ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			metadata = *input.XQSMetaData
+			return &service.PutObjectOutput{}, nil
+		})
+
+	n, err := c.Write(path, bytes.NewReader(content), size)
+	assert.NoError(t, err)
+	assert.Equal(t, size, n)
+	assert.Equal(t, encryptedSize(size), int64(len(stored)))
+	assert.False(t, bytes.Contains(stored, content[:100]))
+	assert.Equal(t, clientEncryptionAlgorithm, metadata[metadataClientEncryptionAlgorithm])
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			return &service.GetObjectOutput{
+				Body:        ioutil.NopCloser(bytes.NewReader(stored)),
+				XQSMetaData: &metadata,
+			}, nil
+		})
+
+	buf := &bytes.Buffer{}
+	n, err = c.Read(path, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, size, n)
+	assert.Equal(t, content, buf.Bytes())
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{
+			ContentLength: service.Int64(int64(len(stored))),
+			XQSMetaData:   &metadata,
+		}, nil)
+
+	o, err := c.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, size, o.MustGetContentLength())
+	_, ok := o.GetUserMetadata()
+	assert.False(t, ok)
+
+	// Truncated content should not be decrypted.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body:        ioutil.NopCloser(bytes.NewReader(stored[:2*(clientEncryptionChunkSize+clientEncryptionOverhead)])),
+			XQSMetaData: &metadata,
+		}, nil)
+
+	_, err = c.Read(path, ioutil.Discard)
+	assert.True(t, errors.Is(err, ErrContentCorrupted))
+
+	// Tampered content should not be decrypted.
+	stored[len(stored)-1] ^= 0xff
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body:        ioutil.NopCloser(bytes.NewReader(stored)),
+			XQSMetaData: &metadata,
+		}, nil)
+
+	_, err = c.Read(path, ioutil.Discard)
+	assert.True(t, errors.Is(err, ErrContentCorrupted))
+
+	_, err = c.Read(path, ioutil.Discard, pairs.WithOffset(10))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ClientEncryptionMultipart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	kp, err := NewAESKeyProvider(bytes.Repeat([]byte{'k'}, 32))
+	assert.NoError(t, err)
+
+	c := Storage{
+		bucket:      mockBucket,
+		workDir:     "/",
+		keyProvider: kp,
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+
+	var metadata map[string]string
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.InitiateMultipartUploadInput) (*service.InitiateMultipartUploadOutput, error) {
+			metadata = *input.XQSMetaData
+			return &service.InitiateMultipartUploadOutput{UploadID: service.String(uuid.NewString())}, nil
+		})
+	var stored []byte
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			assert.Equal(t, encryptedSize(int64(len(content))), *input.ContentLength)
+			stored, err = ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			return &service.UploadMultipartOutput{}, nil
+		})
+
+	o, err := c.CreateMultipart(path)
+	assert.NoError(t, err)
+	assert.Equal(t, metadata[metadataClientEncryptionKey], GetObjectSystemMetadata(o).ClientEncryptionKey)
+
+	n, part, err := c.WriteMultipart(o, bytes.NewReader(content), int64(len(content)), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, encryptedSize(int64(len(content))), part.Size)
+
+	aead, err := c.openDataKey(context.Background(), metadata[metadataClientEncryptionKey])
+	assert.NoError(t, err)
+	plain, err := ioutil.ReadAll(newDecryptReader(bytes.NewReader(stored), aead))
+	assert.NoError(t, err)
+	assert.Equal(t, content, plain)
+
+	// Parts not aligned to chunk size could not be decrypted as a whole.
+	err = c.CompleteMultipart(o, []*Part{
+		{Index: 0, Size: part.Size},
+		{Index: 1, Size: part.Size},
+	})
+	assert.True(t, errors.Is(err, ErrPartsInvalid))
+	err = c.CompleteMultipart(o, []*Part{
+		{Index: 1, Size: encryptedSize(clientEncryptionChunkSize)},
+		{Index: 2, Size: part.Size},
+	})
+	assert.True(t, errors.Is(err, ErrPartsInvalid))
+
+	// Parts of multipart upload without data key could not be encrypted.
+	_, _, err = c.WriteMultipart(c.Create(path, pairs.WithMultipartID("test")), bytes.NewReader(content), int64(len(content)), 0)
+	assert.True(t, errors.Is(err, ErrClientEncryptionKeyInvalid))
+}
+
+func TestStorage_Compression(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := bytes.Repeat([]byte("qingstor"), 1024)
+
+	var stored []byte
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, "gzip", *input.ContentEncoding)
+			var err error
+			stored, err = ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, *input.ContentLength, int64(len(stored)))
+			return &service.PutObjectOutput{}, nil
+		})
+
+	n, err := c.Write(path, bytes.NewReader(content), int64(len(content)), WithCompression("gzip"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Less(t, len(stored), len(content))
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body:            ioutil.NopCloser(bytes.NewReader(stored)),
+			ContentEncoding: service.String("gzip"),
+		}, nil)
+
+	buf := &bytes.Buffer{}
+	n, err = c.Read(path, buf, WithCompression("gzip"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.Bytes())
+
+	// Objects uploaded by other tools could be encoded as x-gzip.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body:            ioutil.NopCloser(bytes.NewReader(stored)),
+			ContentEncoding: service.String("x-gzip"),
+		}, nil)
+
+	buf.Reset()
+	_, err = c.Read(path, buf, WithCompression("gzip"))
+	assert.NoError(t, err)
+	assert.Equal(t, content, buf.Bytes())
+
+	// Objects not compressed will be read directly.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body: ioutil.NopCloser(bytes.NewReader(content)),
+		}, nil)
+
+	buf.Reset()
+	_, err = c.Read(path, buf, WithCompression("gzip"))
+	assert.NoError(t, err)
+	assert.Equal(t, content, buf.Bytes())
+
+	_, err = c.Write(path, bytes.NewReader(content), int64(len(content)), WithCompression("zstd"))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_WriteRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	content := []byte(uuid.NewString())
+
+	// Content will be rewound and written again after transient failure.
+	gomock.InOrder(
+		mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				_, _ = io.CopyN(ioutil.Discard, input.Body, 10)
+				return nil, &qerror.QingStorError{StatusCode: 503}
+			}),
+		mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				body, err := ioutil.ReadAll(input.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, content, body)
+				return &service.PutObjectOutput{}, nil
+			}),
+	)
+
+	n, err := c.Write(uuid.NewString(), bytes.NewReader(content), int64(len(content)), WithWriteRetry(3))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+
+	// Non-transient failure will not be retried.
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 403, Code: "permission_denied"})
+
+	_, err = c.Write(uuid.NewString(), bytes.NewReader(content), int64(len(content)), WithWriteRetry(3))
+	assert.True(t, errors.Is(err, services.ErrPermissionDenied))
+
+	// Content will be rewound to where the reader was when write started, and transfer
+	// statistics will not count bytes sent again.
+	var stats []TransferStats
+	gomock.InOrder(
+		mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				_, _ = io.CopyN(ioutil.Discard, input.Body, 10)
+				return nil, &qerror.QingStorError{StatusCode: 503}
+			}),
+		mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+				body, err := ioutil.ReadAll(input.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, content[4:], body)
+				return &service.PutObjectOutput{}, nil
+			}),
+	)
+
+	br := bytes.NewReader(content)
+	_, err = br.Seek(4, io.SeekStart)
+	assert.NoError(t, err)
+	n, err = c.Write(uuid.NewString(), br, int64(len(content)-4), WithWriteRetry(3),
+		WithTransferCallback(func(s TransferStats) { stats = append(stats, s) }))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)-4), n)
+	assert.NotEmpty(t, stats)
+	for _, v := range stats {
+		assert.LessOrEqual(t, v.Bytes, v.Total)
+	}
+	assert.Equal(t, int64(len(content)-4), stats[len(stats)-1].Bytes)
+
+	// Rate limit will be checked before any attempt.
+	_, err = c.Write(uuid.NewString(), bytes.NewReader(content), int64(len(content)), WithWriteRetry(3),
+		WithWriteRateLimit(0))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_IoCallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	content := []byte(uuid.NewString())
+	var total int
+	fn := func(b []byte) {
+		total += len(b)
+	}
+
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.UploadMultipartInput) (*service.UploadMultipartOutput, error) {
+			_, err := io.Copy(ioutil.Discard, input.Body)
+			assert.NoError(t, err)
+			return &service.UploadMultipartOutput{}, nil
+		})
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		Return(&service.InitiateMultipartUploadOutput{UploadID: service.String(uuid.NewString())}, nil)
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		Return(&service.CompleteMultipartUploadOutput{}, nil)
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			_, err := io.Copy(ioutil.Discard, input.Body)
+			assert.NoError(t, err)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	// Progress of every part will be reported.
+	o := c.Create(uuid.NewString(), pairs.WithMultipartID(uuid.NewString()))
+	_, _, err := c.WriteMultipart(o, bytes.NewReader(content), int64(len(content)), 0, pairs.WithIoCallback(fn))
+	assert.NoError(t, err)
+	assert.Equal(t, len(content), total)
+
+	// Progress will be reported while write switches to multipart upload.
+	total = 0
+	size := int64(5 * 1024 * 1024)
+	_, err = c.Write(uuid.NewString(), io.LimitReader(randbytes.NewRand(), size), size,
+		WithMultipartThreshold(1), WithMultipartPartSize(4*1024*1024), pairs.WithIoCallback(fn))
+	assert.NoError(t, err)
+	assert.Equal(t, int(size), total)
+
+	// Progress will be reported with the size before compressed.
+	total = 0
+	_, err = c.Write(uuid.NewString(), bytes.NewReader(content), int64(len(content)),
+		WithCompression("gzip"), pairs.WithIoCallback(fn))
+	assert.NoError(t, err)
+	assert.Equal(t, len(content), total)
+}
+
+func TestStorage_WriteBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	items := make([]BatchItem, 10)
+	for i := range items {
+		content := []byte(uuid.NewString())
+		items[i] = BatchItem{
+			Path:   uuid.NewString(),
+			Reader: bytes.NewReader(content),
+			Size:   int64(len(content)),
+		}
+	}
+	failedPath := items[3].Path
+
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(len(items)).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			if objectKey == failedPath {
+				return nil, &qerror.QingStorError{StatusCode: 403, Code: "permission_denied"}
+			}
+			return &service.PutObjectOutput{}, nil
+		})
+
+	var (
+		mu    sync.Mutex
+		count int
+	)
+	err := c.WriteBatch(items, BatchOptions{
+		Concurrency: 4,
+		ProgressFunc: func(path string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			count++
 		},
+	})
+	assert.Equal(t, len(items), count)
+
+	var e *BatchError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, len(items), e.Total)
+	assert.Equal(t, 1, len(e.Failed))
+	assert.Equal(t, failedPath, e.Failed[0].Path)
+	assert.True(t, errors.Is(e.Failed[0].Err, services.ErrPermissionDenied))
+}
+
+func TestStorage_WriteFileAndReadFile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	dir, err := ioutil.TempDir("", "qingstor")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+	localPath := filepath.Join(dir, "src")
+	assert.NoError(t, ioutil.WriteFile(localPath, content, 0644))
+
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			assert.Equal(t, int64(len(content)), *input.ContentLength)
+			body, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, content, body)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	n, err := c.WriteFile(path, localPath)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body: ioutil.NopCloser(bytes.NewReader(content)),
+		}, nil)
+
+	dstPath := filepath.Join(dir, "dst")
+	n, err = c.ReadFile(path, dstPath)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	got, err := ioutil.ReadFile(dstPath)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+
+	// Corrupted content should not replace the local file.
+	sum := md5.Sum(content)
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body: ioutil.NopCloser(bytes.NewReader([]byte(uuid.NewString()))),
+			ETag: service.String(hex.EncodeToString(sum[:])),
+		}, nil)
+
+	_, err = c.ReadFile(path, dstPath)
+	assert.True(t, errors.Is(err, ErrContentCorrupted))
+	got, err = ioutil.ReadFile(dstPath)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	_, err = c.WriteFile(path, filepath.Join(dir, "not_exist"))
+	assert.Error(t, err)
+
+	// Processed image could not be verified, so verify_etag should not be enabled.
+	mockBucket.EXPECT().ImageProcessWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.ImageProcessOutput{
+			Body:          ioutil.NopCloser(bytes.NewReader(content)),
+			ContentLength: service.Int64(int64(len(content))),
+		}, nil)
+
+	imagePath := filepath.Join(dir, "image")
+	n, err = c.ReadFile(path, imagePath, WithImageProcess([]ImageAction{ImageFormat("webp")}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	got, err = ioutil.ReadFile(imagePath)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestStorage_ContentSha256(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	var metadata map[string]string
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			metadata = *input.XQSMetaData
+			body, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, content, body)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	_, err := c.Write(path, ioutil.NopCloser(bytes.NewReader(content)), int64(len(content)), WithAutoContentSha256())
+	assert.NoError(t, err)
+	assert.Equal(t, checksum, metadata[metadataContentSha256])
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{XQSMetaData: &metadata}, nil)
+
+	o, err := c.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, checksum, GetObjectSystemMetadata(o).ContentSha256)
+	_, ok := o.GetUserMetadata()
+	assert.False(t, ok)
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body:        ioutil.NopCloser(bytes.NewReader(content)),
+			XQSMetaData: &metadata,
+		}, nil)
+
+	buf := &bytes.Buffer{}
+	_, err = c.Read(path, buf, WithVerifySha256())
+	assert.NoError(t, err)
+	assert.Equal(t, content, buf.Bytes())
+
+	// Content doesn't match the checksum.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body:        ioutil.NopCloser(bytes.NewReader([]byte(uuid.NewString()))),
+			XQSMetaData: &metadata,
+		}, nil)
+
+	_, err = c.Read(path, ioutil.Discard, WithVerifySha256())
+	assert.True(t, errors.Is(err, ErrContentCorrupted))
+
+	_, err = c.Write(path, bytes.NewReader(content), int64(len(content)), WithContentSha256("invalid"))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ContentSha256Multipart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path, uploadID := uuid.NewString(), uuid.NewString()
+	content := []byte(uuid.NewString())
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	mockBucket.EXPECT().InitiateMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.InitiateMultipartUploadInput) (*service.InitiateMultipartUploadOutput, error) {
+			assert.Equal(t, checksum, (*input.XQSMetaData)[metadataContentSha256])
+			return &service.InitiateMultipartUploadOutput{UploadID: service.String(uploadID)}, nil
+		})
+	mockBucket.EXPECT().UploadMultipartWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(8).
+		Return(&service.UploadMultipartOutput{ETag: service.String(uuid.NewString())}, nil)
+	mockBucket.EXPECT().CompleteMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.CompleteMultipartUploadOutput{}, nil)
+
+	opt, err := c.parsePairStorageWrite([]Pair{WithContentSha256(checksum)})
+	assert.NoError(t, err)
+	_, err = c.writeMultipartStream(context.Background(), path, bytes.NewReader(content), -1, 10, 1, opt)
+	assert.NoError(t, err)
+
+	// Multipart upload will be aborted while content doesn't match the checksum.
+	mockBucket.EXPECT().AbortMultipartUploadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.AbortMultipartUploadOutput{}, nil)
+
+	_, err = c.writeMultipartStream(context.Background(), path, bytes.NewReader([]byte(uuid.NewString())), -1, 10, 1, opt)
+	assert.True(t, errors.Is(err, ErrContentCorrupted))
+
+	// Checksum could not be calculated before initiating.
+	size := int64(2 * 1024 * 1024)
+	_, err = c.Write(path, io.LimitReader(randbytes.NewRand(), size), size,
+		WithMultipartThreshold(1024*1024), WithAutoContentSha256())
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ReadSuffixSize(t *testing.T) {
+	c := Storage{}
+
+	_, err := c.Read(uuid.NewString(), ioutil.Discard, WithSuffixSize(10), pairs.WithOffset(1))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	_, err = c.Read(uuid.NewString(), ioutil.Discard, WithSuffixSize(0))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ReadCloser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			return &service.GetObjectOutput{
+				Body: ioutil.NopCloser(bytes.NewReader(content)),
+			}, nil
+		})
+
+	rc, err := c.ReadCloser(path)
+	assert.NoError(t, err)
+	got, err := ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.NoError(t, rc.Close())
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 404})
+
+	_, err = c.ReadCloser(path)
+	assert.True(t, errors.Is(err, services.ErrObjectNotExist))
+}
+
+func TestStorage_ReadWithMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+	lastModified := time.Now().Truncate(time.Second)
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			return &service.GetObjectOutput{
+				Body:            ioutil.NopCloser(bytes.NewReader(content[:4])),
+				ContentLength:   service.Int64(4),
+				ContentRange:    service.String("bytes 0-3/" + strconv.Itoa(len(content))),
+				ContentType:     service.String("text/plain"),
+				ETag:            service.String("xxxxx"),
+				LastModified:    service.Time(lastModified),
+				XQSStorageClass: service.String(StorageClassStandard),
+				XQSMetaData:     &map[string]string{"x-qs-meta-foo": "bar"},
+			}, nil
+		})
+
+	var buf bytes.Buffer
+	o, n, err := c.ReadWithMetadata(path, &buf, pairs.WithSize(4))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.Equal(t, content[:4], buf.Bytes())
+
+	assert.Equal(t, path, o.Path)
+	assert.Equal(t, int64(len(content)), o.MustGetContentLength())
+	assert.Equal(t, "text/plain", o.MustGetContentType())
+	assert.Equal(t, "xxxxx", o.MustGetEtag())
+	assert.Equal(t, lastModified, o.MustGetLastModified())
+	assert.Equal(t, map[string]string{"foo": "bar"}, o.MustGetUserMetadata())
+	assert.Equal(t, StorageClassStandard, GetObjectSystemMetadata(o).StorageClass)
+}
+
+func TestStorage_Download(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	dir, err := ioutil.TempDir("", "qingstor")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+	etag := "xxxxx"
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{
+			ContentLength: service.Int64(int64(len(content))),
+			ETag:          service.String(etag),
+		}, nil).Times(2)
+
+	// The range at offset 16 fails at the first time, and will be fetched while resuming.
+	var mu sync.Mutex
+	fetched := make(map[string]int)
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			assert.Equal(t, etag, service.StringValue(input.IfMatch))
+
+			var start, end int
+			_, err := fmt.Sscanf(service.StringValue(input.Range), "bytes=%d-%d", &start, &end)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			fetched[*input.Range]++
+			times := fetched[*input.Range]
+			mu.Unlock()
+			if start == 16 && times == 1 {
+				return nil, &qerror.QingStorError{StatusCode: 500}
+			}
+			return &service.GetObjectOutput{
+				Body: ioutil.NopCloser(bytes.NewReader(content[start : end+1])),
+			}, nil
+		}).AnyTimes()
+
+	localPath := filepath.Join(dir, "dst")
+	_, err = c.DownloadFile(path, localPath, WithDownloadPartSize(8), WithDownloadConcurrency(1))
+	assert.Error(t, err)
+	_, err = os.Stat(localPath + downloadCheckpointSuffix)
+	assert.NoError(t, err)
+
+	n, err := c.DownloadFile(path, localPath, WithDownloadPartSize(8), WithDownloadConcurrency(2))
+	assert.NoError(t, err)
+	// Ranges before offset 16 have been fetched and should not be fetched again.
+	assert.Equal(t, int64(len(content)-16), n)
+	assert.Equal(t, 1, fetched["bytes=0-7"])
+	assert.Equal(t, 1, fetched["bytes=8-15"])
+
+	got, err := ioutil.ReadFile(localPath)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+	_, err = os.Stat(localPath + downloadCheckpointSuffix)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = c.Download(path, nil, pairs.WithOffset(1))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ReadRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	limit := int64(100 * 1024)
+	content := make([]byte, limit*3/2)
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body: ioutil.NopCloser(bytes.NewReader(content)),
+		}, nil)
+
+	start := time.Now()
+	n, err := c.Read(uuid.NewString(), ioutil.Discard, WithReadRateLimit(limit))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	// The first second of limit could be read in burst.
+	assert.True(t, time.Since(start) >= 400*time.Millisecond)
+
+	_, err = c.Read(uuid.NewString(), ioutil.Discard, WithReadRateLimit(0))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_WriteRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	limit := int64(100 * 1024)
+	content := make([]byte, limit*3/2)
+
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			body, err := ioutil.ReadAll(input.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, content, body)
+			return &service.PutObjectOutput{}, nil
+		})
+
+	start := time.Now()
+	n, err := c.Write(uuid.NewString(), bytes.NewReader(content), int64(len(content)), WithWriteRateLimit(limit))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	// The first second of limit could be written in burst.
+	assert.True(t, time.Since(start) >= 400*time.Millisecond)
+
+	// Invalid limit will be rejected before sending any bytes.
+	_, err = c.Write(uuid.NewString(), bytes.NewReader(content), int64(len(content)), WithWriteRateLimit(0))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+	_, err = c.Write(uuid.NewString(), bytes.NewReader(content), int64(len(content)), WithWriteRateLimit(-1))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ReadRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+	half := len(content) / 2
+	etag := "xxxxx"
+
+	// The stream breaks after half of content has been delivered.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Nil(t, input.Range)
+			return &service.GetObjectOutput{
+				Body:          ioutil.NopCloser(bytes.NewReader(content[:half])),
+				ContentLength: service.Int64(int64(len(content))),
+				ETag:          service.String(etag),
+			}, nil
+		})
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Equal(t, fmt.Sprintf("bytes=%d-%d", half, len(content)-1), service.StringValue(input.Range))
+			assert.Equal(t, etag, service.StringValue(input.IfMatch))
+			return &service.GetObjectOutput{
+				Body:          ioutil.NopCloser(bytes.NewReader(content[half:])),
+				ContentLength: service.Int64(int64(len(content) - half)),
+				ContentRange:  service.String(fmt.Sprintf("bytes %d-%d/%d", half, len(content)-1, len(content))),
+			}, nil
+		})
+
+	var buf bytes.Buffer
+	n, err := c.Read(path, &buf, WithReadRetry(1))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.Bytes())
+
+	// The object has been changed while resuming.
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body:          ioutil.NopCloser(bytes.NewReader(content[:half])),
+			ContentLength: service.Int64(int64(len(content))),
+			ETag:          service.String(etag),
+		}, nil)
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			StatusCode: service.Int(http.StatusPreconditionFailed),
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		}, nil)
+
+	_, err = c.Read(path, ioutil.Discard, WithReadRetry(1))
+	assert.True(t, errors.Is(err, ErrPreconditionFailed))
+}
+
+func TestStorage_ReaderAt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+	etag := "xxxxx"
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{
+			ContentLength: service.Int64(int64(len(content))),
+			ETag:          service.String(etag),
+		}, nil).Times(2)
+
+	var ranges []string
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			assert.Equal(t, path, objectKey)
+			assert.Equal(t, etag, service.StringValue(input.IfMatch))
+
+			var start, end int
+			_, err := fmt.Sscanf(service.StringValue(input.Range), "bytes=%d-%d", &start, &end)
+			assert.NoError(t, err)
+			ranges = append(ranges, *input.Range)
+			return &service.GetObjectOutput{
+				Body: ioutil.NopCloser(bytes.NewReader(content[start : end+1])),
+			}, nil
+		}).AnyTimes()
+
+	r, err := c.ReaderAt(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), r.Size())
+
+	p := make([]byte, 4)
+	n, err := r.ReadAt(p, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, content[10:14], p)
+	assert.Equal(t, []string{"bytes=10-13"}, ranges)
+
+	// Reading beyond the end returns the rest content with io.EOF.
+	off, err := r.Seek(-2, io.SeekEnd)
+	assert.NoError(t, err)
+	n, err = r.ReadAt(p, off)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, content[len(content)-2:], p[:n])
+	assert.NoError(t, r.Close())
+
+	ranges = nil
+	r, err = c.ReaderAt(path, WithReaderBlockSize(8), WithReaderBlockCache(2))
+	assert.NoError(t, err)
+
+	got, err := ioutil.ReadAll(io.NewSectionReader(r, 4, 8))
+	assert.NoError(t, err)
+	assert.Equal(t, content[4:12], got)
+	// Cached blocks should not be fetched again.
+	_, err = r.ReadAt(p, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, content[9:13], p)
+	assert.Equal(t, []string{"bytes=0-7", "bytes=8-15"}, ranges)
+
+	_, err = r.Seek(-1, io.SeekStart)
+	assert.True(t, errors.Is(err, ErrSeekOffsetInvalid))
+
+	_, err = c.ReaderAt(path, WithCompression(compressionGzip))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_ReaderAtReadAhead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{
+			ContentLength: service.Int64(int64(len(content))),
+		}, nil)
+
+	var mu sync.Mutex
+	fetched := make(map[string]int)
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.GetObjectInput) (*service.GetObjectOutput, error) {
+			var start, end int
+			_, err := fmt.Sscanf(service.StringValue(input.Range), "bytes=%d-%d", &start, &end)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			fetched[*input.Range]++
+			mu.Unlock()
+			return &service.GetObjectOutput{
+				Body: ioutil.NopCloser(bytes.NewReader(content[start : end+1])),
+			}, nil
+		}).AnyTimes()
+
+	r, err := c.ReaderAt(path, WithReaderBlockSize(8), WithReaderReadAhead(2))
+	assert.NoError(t, err)
+	defer r.Close()
+
+	var got []byte
+	p := make([]byte, 3)
+	for {
+		n, err := r.Read(p)
+		got = append(got, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
 	}
+	assert.Equal(t, content, got)
 
-	for _, v := range tests {
-		mockBucket.EXPECT().PutObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).DoAndReturn(v.mockFn)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 5, len(fetched))
+	// Every block should be fetched only once, no matter prefetched or not.
+	for k, v := range fetched {
+		assert.Equal(t, 1, v, k)
+	}
+}
 
-		client := Storage{
-			bucket: mockBucket,
-		}
+func TestStorage_ReadImageProcess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
-		n, err := client.Write(v.path, v.r, v.size)
-		if v.hasError {
-			assert.Error(t, err)
-			assert.True(t, errors.Is(err, v.wantErr))
-		} else {
-			assert.NoError(t, err)
-			assert.Equal(t, v.size, n)
-		}
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
 	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+
+	mockBucket.EXPECT().ImageProcessWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.ImageProcessInput) (*service.ImageProcessOutput, error) {
+			assert.Equal(t, path, objectKey)
+			assert.Equal(t, "resize:w_100,m_1|format:t_webp", service.StringValue(input.Action))
+			return &service.ImageProcessOutput{
+				Body:          ioutil.NopCloser(bytes.NewReader(content)),
+				ContentLength: service.Int64(int64(len(content))),
+			}, nil
+		})
+
+	var buf bytes.Buffer
+	n, err := c.Read(path, &buf, WithImageProcess([]ImageAction{ImageResize(100, 0, 1), ImageFormat("webp")}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.Bytes())
+
+	_, err = c.Read(path, &buf, WithImageProcess([]ImageAction{ImageInfo()}), pairs.WithOffset(1))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
 }
 
-func TestStorage_formatError(t *testing.T) {
-	s := &Storage{}
-	errCasual := errors.New("casual error")
-	cases := []struct {
-		name      string
-		op        string
-		err       error
-		path      string
-		targetErr error
-		targetEq  bool
-	}{
-		{
-			name: "nil error",
-			err:  nil,
-		},
-		{
-			name:      "casual error",
-			op:        "stat",
-			err:       errCasual,
-			targetErr: services.ErrUnexpected,
-			targetEq:  true,
-		},
-		{
-			name: "not found with blank code",
-			op:   "stat",
-			err: &qerror.QingStorError{
-				StatusCode: 404,
-				Code:       "",
-				Message:    "msg",
-			},
-			targetErr: services.ErrObjectNotExist,
-			targetEq:  true,
-		},
-		{
-			name: "not found by code",
-			op:   "stat",
-			err: &qerror.QingStorError{
-				StatusCode: 404,
-				Code:       "object_not_exists",
-				Message:    "msg",
-			},
-			targetErr: services.ErrObjectNotExist,
-			targetEq:  true,
-		},
-		{
-			name: "permission denied by code",
-			op:   "stat",
-			err: &qerror.QingStorError{
-				StatusCode: 403,
-				Code:       "permission_denied",
-				Message:    "msg",
-			},
-			targetErr: services.ErrPermissionDenied,
-			targetEq:  true,
-		},
-		{
-			name: "not found by code error not eq",
-			op:   "stat",
-			err: qerror.QingStorError{
-				StatusCode: 404,
-				Code:       "object_not_exists",
-				Message:    "msg",
-			},
-			targetErr: services.ErrObjectNotExist,
-			targetEq:  false,
-		},
+// readerFromRecorder records the reader handed to ReadFrom.
+type readerFromRecorder struct {
+	bytes.Buffer
+	r io.Reader
+}
+
+func (w *readerFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	w.r = r
+	return w.Buffer.ReadFrom(r)
+}
+
+func TestStorage_ReadReaderFrom(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
 	}
-	for _, tt := range cases {
-		err := s.formatError(tt.op, tt.err, tt.path)
-		if tt.err == nil {
-			assert.Nil(t, err, tt.name)
-			continue
-		}
 
-		var storageErr services.StorageError
-		assert.True(t, errors.As(err, &storageErr), tt.name)
-		assert.Equal(t, tt.op, storageErr.Op, tt.name)
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+	body := ioutil.NopCloser(bytes.NewReader(content))
 
-		assert.Equal(t, tt.targetEq, errors.Is(err, tt.targetErr), tt.name)
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{Body: body}, nil)
+
+	// Response body should be handed to io.ReaderFrom directly.
+	w := &readerFromRecorder{}
+	n, err := c.Read(path, w)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, w.Bytes())
+	assert.Equal(t, body, w.r)
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(content))}, nil)
+
+	// Content with io callback could not be read directly.
+	var called int
+	w = &readerFromRecorder{}
+	_, err = c.Read(path, w, pairs.WithIoCallback(func(b []byte) {
+		called += len(b)
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, content, w.Bytes())
+	assert.Equal(t, len(content), called)
+}
+
+func TestStorage_TransferCallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
+	}
+
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body:          ioutil.NopCloser(bytes.NewReader(content)),
+			ContentLength: service.Int64(int64(len(content))),
+		}, nil)
+
+	var stats TransferStats
+	_, err := c.Read(path, ioutil.Discard, WithTransferCallback(func(s TransferStats) {
+		stats = s
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), stats.Bytes)
+	assert.Equal(t, int64(len(content)), stats.Total)
+	assert.True(t, stats.Elapsed >= 0)
+
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
+			_, err := ioutil.ReadAll(input.Body)
+			return &service.PutObjectOutput{}, err
+		})
+
+	stats = TransferStats{}
+	_, err = c.Write(path, bytes.NewReader(content), int64(len(content)), WithTransferCallback(func(s TransferStats) {
+		stats = s
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), stats.Bytes)
+	assert.Equal(t, int64(len(content)), stats.Total)
+}
+
+func TestStorage_ReadCopyBufferSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
 	}
+
+	content := []byte(uuid.NewString())
+
+	mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.GetObjectOutput{
+			Body: ioutil.NopCloser(bytes.NewReader(content)),
+		}, nil)
+
+	var buf bytes.Buffer
+	n, err := c.Read(uuid.NewString(), &buf, WithCopyBufferSize(4))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.Bytes())
+
+	_, err = c.Read(uuid.NewString(), &buf, WithCopyBufferSize(0))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	_, err = c.Write(uuid.NewString(), bytes.NewReader(content), int64(len(content)), WithCopyBufferSize(-1))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+
+	// Content is not copied by write without compression.
+	_, err = c.Write(uuid.NewString(), bytes.NewReader(content), int64(len(content)), WithCopyBufferSize(4))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
 }
 
-func TestStorage_Fetch(t *testing.T) {
+func TestStorage_StatCache(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockBucket := NewMockBucket(ctrl)
 
-	{
-		client := Storage{
-			bucket: mockBucket,
-		}
+	c := Storage{
+		bucket:    mockBucket,
+		workDir:   "/",
+		statCache: newStatCache(100*time.Millisecond, 0, statCacheSizeDefault),
+	}
 
-		name := uuid.New().String()
-		url := uuid.New().String()
+	path := uuid.NewString()
+	size := int64(1234)
 
-		mockBucket.EXPECT().PutObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
-			DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
-				assert.Equal(t, name, objectKey)
-				assert.Equal(t, *input.XQSFetchSource, url)
-				return &service.PutObjectOutput{}, nil
-			})
-		err := client.Fetch(name, url)
+	// The second Stat is served from cache.
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{ContentLength: service.Int64(size)}, nil)
+	for i := 0; i < 2; i++ {
+		o, err := c.Stat(path)
 		assert.NoError(t, err)
+		assert.Equal(t, size, o.MustGetContentLength())
 	}
 
-	{
-		client := Storage{
-			bucket: mockBucket,
-		}
+	// Cached result is dropped after deleted.
+	mockBucket.EXPECT().DeleteObjectWithContext(gomock.Any(), gomock.Any()).Return(nil, nil)
+	assert.NoError(t, c.Delete(path))
 
-		name := uuid.New().String()
-		url := uuid.New().String()
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{ContentLength: service.Int64(size)}, nil)
+	_, err := c.Stat(path)
+	assert.NoError(t, err)
 
-		mockBucket.EXPECT().PutObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
-			DoAndReturn(func(ctx context.Context, objectKey string, input *service.PutObjectInput) (*service.PutObjectOutput, error) {
-				return nil, &qerror.QingStorError{}
-			})
-		err := client.Fetch(name, url)
-		assert.Error(t, err)
+	// Cached result is dropped after expired.
+	time.Sleep(150 * time.Millisecond)
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{ContentLength: service.Int64(size)}, nil)
+	_, err = c.Stat(path)
+	assert.NoError(t, err)
+}
+
+func TestStorage_StatCacheNotExist(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	c := Storage{
+		bucket:    mockBucket,
+		workDir:   "/",
+		statCache: newStatCache(0, time.Minute, statCacheSizeDefault),
+	}
+
+	path := uuid.NewString()
+
+	// The second Stat is served from cache.
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 404})
+	for i := 0; i < 2; i++ {
+		_, err := c.Stat(path)
+		assert.True(t, errors.Is(err, services.ErrObjectNotExist))
+	}
+
+	// Cached result is dropped after written.
+	mockBucket.EXPECT().PutObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.PutObjectOutput{}, nil)
+	_, err := c.Write(path, nil, 0)
+	assert.NoError(t, err)
+
+	// Results of existing objects are not cached without stat_cache_ttl.
+	mockBucket.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&service.HeadObjectOutput{}, nil).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err = c.Stat(path)
+		assert.NoError(t, err)
 	}
 }
 
-func TestStorage_Create(t *testing.T) {
+func TestStorage_ReadVerifyEtag(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockBucket := NewMockBucket(ctrl)
 
-	wd := "/test/"
 	c := Storage{
-		workDir: wd,
 		bucket:  mockBucket,
+		workDir: "/",
 	}
 
+	path := uuid.NewString()
+	content := []byte(uuid.NewString())
+	sum := md5.Sum(content)
+	etag := "\"" + hex.EncodeToString(sum[:]) + "\""
+
 	cases := []struct {
-		name        string
-		path        string
-		multipartID string
+		name    string
+		content []byte
+		etag    string
+		wantErr error
 	}{
-		{
-			name:        "normal object",
-			path:        uuid.NewString(),
-			multipartID: "",
-		},
-		{
-			name:        "multipart object",
-			path:        uuid.NewString(),
-			multipartID: uuid.NewString(),
-		},
+		{"matched", content, etag, nil},
+		{"corrupted", []byte(uuid.NewString()), etag, ErrContentCorrupted},
+		{"multipart etag", []byte(uuid.NewString()), "\"" + hex.EncodeToString(sum[:]) + "-2\"", nil},
 	}
 
-	mockBucket.EXPECT().HeadObjectWithContext(gomock.Eq(context.Background()), gomock.Any(), gomock.Any()).
-		DoAndReturn(func(ctx context.Context, objectKey string, input *service.HeadObjectInput) (*service.HeadObjectOutput, error) {
-			return &service.HeadObjectOutput{}, nil
-		}).Times(1)
-
 	for _, tt := range cases {
-		ps := make([]Pair, 0)
-		if tt.multipartID != "" {
-			ps = append(ps, pairs.WithMultipartID(tt.multipartID))
-		}
-		obj := c.Create(tt.path, ps...)
-		assert.NotNil(t, obj)
-		assert.Equal(t, c.getAbsPath(tt.path), obj.ID)
-		assert.Equal(t, tt.path, obj.Path)
-		if tt.multipartID != "" {
-			assert.Equal(t, tt.multipartID, obj.MustGetMultipartID())
-			assert.Equal(t, ModePart, obj.Mode)
-		} else {
-			assert.Equal(t, ModeRead, obj.Mode)
-			assert.Panics(t, func() {
-				obj.MustGetMultipartID()
-			})
-		}
+		t.Run(tt.name, func(t *testing.T) {
+			mockBucket.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&service.GetObjectOutput{
+					Body: ioutil.NopCloser(bytes.NewReader(tt.content)),
+					ETag: service.String(tt.etag),
+				}, nil)
+
+			buf := &bytes.Buffer{}
+			_, err := c.Read(path, buf, WithVerifyEtag())
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.content, buf.Bytes())
+		})
+	}
+
+	// Etag could only be verified with the whole content.
+	_, err := c.Read(path, ioutil.Discard, WithVerifyEtag(), pairs.WithOffset(1))
+	assert.True(t, errors.Is(err, services.ErrCapabilityInsufficient))
+}
+
+func TestStorage_DiskUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBucket := NewMockBucket(ctrl)
+
+	client := Storage{
+		bucket:  mockBucket,
+		workDir: "/",
 	}
+
+	path := uuid.New().String() + "/"
+	prefix := path + "a/"
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *service.ListObjectsInput) (*service.ListObjectsOutput, error) {
+			if service.StringValue(input.Delimiter) == "/" {
+				return &service.ListObjectsOutput{
+					HasMore:        service.Bool(false),
+					CommonPrefixes: []*string{&prefix},
+					Keys: []*service.KeyType{
+						{Key: service.String(path + "1"), Size: service.Int64(100), StorageClass: service.String(StorageClassStandard)},
+						{Key: service.String(path + "2"), Size: service.Int64(10)},
+					},
+				}, nil
+			}
+			return &service.ListObjectsOutput{
+				HasMore: service.Bool(false),
+				Keys: []*service.KeyType{
+					{Key: service.String(prefix + "1"), Size: service.Int64(1000), StorageClass: service.String(StorageClassStandardIA)},
+					{Key: service.String(prefix + "2"), Size: service.Int64(1), StorageClass: service.String(StorageClassStandardIA)},
+				},
+			}, nil
+		}).Times(2)
+
+	u, err := client.DiskUsage(path)
+	assert.NoError(t, err)
+	assert.Equal(t, &Usage{
+		Bytes:   1111,
+		Objects: 4,
+		StorageClasses: map[string]StorageClassUsage{
+			StorageClassStandard:   {Bytes: 110, Objects: 2},
+			StorageClassStandardIA: {Bytes: 1001, Objects: 2},
+		},
+	}, u)
+
+	mockBucket.EXPECT().ListObjectsWithContext(gomock.Any(), gomock.Any()).
+		Return(nil, &qerror.QingStorError{StatusCode: 403})
+	_, err = client.DiskUsage(path)
+	assert.Error(t, err)
 }
@@ -0,0 +1,160 @@
+package qingstor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	qserror "github.com/qingstor/qingstor-sdk-go/v4/request/errors"
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// Touch will create an empty object at path, or update its last modified time if it exists.
+func (s *Storage) Touch(path string, pairs ...Pair) (err error) {
+	ctx := context.Background()
+	return s.TouchWithContext(ctx, path, pairs...)
+}
+
+// TouchWithContext will create an empty object at path, or update its last modified time if it exists.
+//
+// QingStor doesn't support updating last modified time directly, so existing object will be
+// copied to itself by Copy with all headers and metadata kept, and objects larger than the
+// limit of a single copy will be copied part by part.
+//
+// Pairs for Copy are supported, the encryption customer key is used to read the object as
+// well if copy_source_encryption_customer_key is not specified. Empty object will be created
+// with encryption and storage class pairs.
+func (s *Storage) TouchWithContext(ctx context.Context, path string, pairs ...Pair) (err error) {
+	defer func() {
+		err = s.formatError("touch", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Copy...)
+	opt, err := s.parsePairStorageCopy(pairs)
+	if err != nil {
+		return
+	}
+	if opt.HasEncryptionCustomerAlgorithm && !opt.HasCopySourceEncryptionCustomerAlgorithm {
+		opt.HasCopySourceEncryptionCustomerAlgorithm, opt.CopySourceEncryptionCustomerAlgorithm = true, opt.EncryptionCustomerAlgorithm
+		opt.HasCopySourceEncryptionCustomerKey, opt.CopySourceEncryptionCustomerKey = true, opt.EncryptionCustomerKey
+	}
+
+	err = s.copy(ctx, path, path, opt)
+	if err == nil || !errors.Is(formatError(err), services.ErrObjectNotExist) {
+		return
+	}
+
+	wopt := pairStorageWrite{
+		HasEncryptionCustomerAlgorithm: opt.HasEncryptionCustomerAlgorithm,
+		EncryptionCustomerAlgorithm:    opt.EncryptionCustomerAlgorithm,
+		HasEncryptionCustomerKey:       opt.HasEncryptionCustomerKey,
+		EncryptionCustomerKey:          opt.EncryptionCustomerKey,
+		HasStorageClass:                opt.HasStorageClass,
+		StorageClass:                   opt.StorageClass,
+		HasContentDisposition:          opt.HasContentDisposition,
+		ContentDisposition:             opt.ContentDisposition,
+	}
+	_, err = s.write(ctx, path, nil, 0, wopt)
+	return
+}
+
+// getObjectHeaders will read the headers of object rp, and returns them along with the size
+// of the object.
+//
+// Headers like Cache-Control and Expires are not returned by HEAD, so the first byte of the
+// object is read by GET instead.
+func (s *Storage) getObjectHeaders(ctx context.Context, rp string, input *service.GetObjectInput) (output *service.GetObjectOutput, size int64, err error) {
+	input.Range = service.String("bytes=0-0")
+	output, err = s.bucket.GetObjectWithContext(ctx, rp, input)
+	if err != nil {
+		// Range of empty objects could not be satisfied.
+		var e *qserror.QingStorError
+		if !errors.As(err, &e) || e.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+			return
+		}
+		input.Range = nil
+		output, err = s.bucket.GetObjectWithContext(ctx, rp, input)
+		if err != nil {
+			return
+		}
+	}
+	if output.Body != nil {
+		_ = output.Body.Close()
+	}
+
+	size = service.Int64Value(output.ContentLength)
+	if _, _, total, ok := parseContentRange(service.StringValue(output.ContentRange)); ok {
+		size = total
+	}
+	return output, size, nil
+}
+
+// formatHeaderPairs will convert headers, user metadata and storage class in output into pairs
+// for Write and CreateMultipart, so that they could be kept while content is written again.
+func formatHeaderPairs(output *service.GetObjectOutput) (pairs []Pair, err error) {
+	if output.ContentType != nil {
+		pairs = append(pairs, ps.WithContentType(*output.ContentType))
+	}
+	if output.CacheControl != nil {
+		pairs = append(pairs, WithCacheControl(*output.CacheControl))
+	}
+	if output.ContentEncoding != nil {
+		pairs = append(pairs, WithContentEncoding(*output.ContentEncoding))
+	}
+	if output.ContentDisposition != nil {
+		pairs = append(pairs, WithContentDisposition(*output.ContentDisposition))
+	}
+	if v := service.StringValue(output.Expires); v != "" {
+		t, err := http.ParseTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse expires %q of source: %w", v, err)
+		}
+		pairs = append(pairs, WithExpires(t))
+	}
+	if output.XQSMetaData != nil {
+		if um := parseUserMetadata(*output.XQSMetaData); len(um) > 0 {
+			pairs = append(pairs, WithUserMetadata(um))
+		}
+	}
+	if v := service.StringValue(output.XQSStorageClass); v != "" {
+		pairs = append(pairs, WithStorageClass(v))
+	}
+	return pairs, nil
+}
+
+// formatSelfCopyInput will build the input to copy object rp of s with all headers in output
+// kept, and metadata replaced by them, which is required to copy object to itself.
+// Content-Disposition and Expires are not supported by the input, so they are set in the
+// returned context.
+func (s *Storage) formatSelfCopyInput(ctx context.Context, rp string, output *service.GetObjectOutput) (context.Context, *service.PutObjectInput) {
+	srcPath := s.copySourcePath(rp)
+	input := &service.PutObjectInput{
+		XQSCopySource: &srcPath,
+		// QingStor doesn't allow copying object to itself without replacing metadata.
+		XQSMetadataDirective: service.String(metadataDirectiveReplace),
+		CacheControl:         output.CacheControl,
+		ContentEncoding:      output.ContentEncoding,
+		ContentType:          output.ContentType,
+		XQSStorageClass:      output.XQSStorageClass,
+	}
+	if output.XQSMetaData != nil {
+		metadata := make(map[string]string, len(*output.XQSMetaData))
+		for k, v := range *output.XQSMetaData {
+			metadata[strings.ToLower(k)] = v
+		}
+		input.XQSMetaData = &metadata
+	}
+	if v := service.StringValue(output.ContentDisposition); v != "" {
+		ctx = withRequestHeader(ctx, "Content-Disposition", v)
+	}
+	if v := service.StringValue(output.Expires); v != "" {
+		ctx = withRequestHeader(ctx, "Expires", v)
+	}
+	return ctx, input
+}
@@ -0,0 +1,129 @@
+package qingstor
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// transferRateWindow is the window in which the instantaneous rate is measured.
+const transferRateWindow = time.Second
+
+// TransferStats is the statistics of a transfer delivered to TransferCallback.
+type TransferStats struct {
+	// Bytes is the number of bytes transferred so far.
+	Bytes int64
+	// Total is the number of bytes to be transferred, -1 means unknown.
+	Total int64
+	// Elapsed is the time since the transfer started.
+	Elapsed time.Duration
+	// Rate is the bytes per second measured in the last second.
+	Rate float64
+}
+
+// TransferCallback will be called every time content has been transferred.
+type TransferCallback func(stats TransferStats)
+
+// MultipartProgress is the overall progress of a multipart upload delivered to
+// MultipartProgressCallback.
+type MultipartProgress struct {
+	// Bytes is the size of content in parts that have been uploaded.
+	Bytes int64
+	// Total is the size of the whole content, -1 means unknown.
+	Total int64
+	// Parts is the number of parts that have been uploaded.
+	Parts int
+}
+
+// Percent returns the percent of content uploaded in [0, 100], or -1 if the total is unknown.
+func (p MultipartProgress) Percent() float64 {
+	if p.Total < 0 {
+		return -1
+	}
+	if p.Total == 0 {
+		return 100
+	}
+	return float64(p.Bytes) * 100 / float64(p.Total)
+}
+
+// MultipartProgressCallback will be called every time a part has been uploaded.
+//
+// Calls are serialized even if parts are uploaded concurrently, and the progress reported is
+// always increasing.
+type MultipartProgressCallback func(progress MultipartProgress)
+
+// transferMeter will measure the statistics of a transfer, it could be shared by concurrent
+// readers and writers.
+type transferMeter struct {
+	mu sync.Mutex
+	fn TransferCallback
+
+	total int64
+	bytes int64
+	start time.Time
+
+	windowStart time.Time
+	windowBytes int64
+	rate        float64
+}
+
+func newTransferMeter(fn TransferCallback, total int64) *transferMeter {
+	now := time.Now()
+	return &transferMeter{
+		fn:          fn,
+		total:       total,
+		start:       now,
+		windowStart: now,
+	}
+}
+
+func (m *transferMeter) add(n int) {
+	if n <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	m.bytes += int64(n)
+	m.windowBytes += int64(n)
+	if d := now.Sub(m.windowStart); d >= transferRateWindow {
+		m.rate = float64(m.windowBytes) / d.Seconds()
+		m.windowStart, m.windowBytes = now, 0
+	} else if m.windowStart == m.start && d > 0 {
+		// Use the average rate until the first window is finished.
+		m.rate = float64(m.bytes) / d.Seconds()
+	}
+	stats := TransferStats{
+		Bytes:   m.bytes,
+		Total:   m.total,
+		Elapsed: now.Sub(m.start),
+		Rate:    m.rate,
+	}
+	m.mu.Unlock()
+
+	m.fn(stats)
+}
+
+// transferReader will measure content read from r.
+type transferReader struct {
+	r io.Reader
+	m *transferMeter
+}
+
+func (t *transferReader) Read(p []byte) (n int, err error) {
+	n, err = t.r.Read(p)
+	t.m.add(n)
+	return
+}
+
+// transferWriter will measure content written into w.
+type transferWriter struct {
+	w io.Writer
+	m *transferMeter
+}
+
+func (t *transferWriter) Write(p []byte) (n int, err error) {
+	n, err = t.w.Write(p)
+	t.m.add(n)
+	return
+}
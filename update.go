@@ -0,0 +1,96 @@
+package qingstor
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/qingstor/qingstor-sdk-go/v4/service"
+
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// updateMetadataPairs are pairs for Write supported by UpdateMetadata, which are all metadata
+// that could be set while writing.
+var updateMetadataPairs = map[string]bool{
+	"cache_control":       true,
+	"content_disposition": true,
+	"content_encoding":    true,
+	"content_type":        true,
+	"expires":             true,
+	"storage_class":       true,
+	"user_metadata":       true,
+}
+
+// UpdateMetadata will update metadata of an existing object without uploading its content again.
+func (s *Storage) UpdateMetadata(path string, pairs ...Pair) (err error) {
+	ctx := context.Background()
+	return s.UpdateMetadataWithContext(ctx, path, pairs...)
+}
+
+// UpdateMetadataWithContext will update metadata of an existing object without uploading its content again.
+//
+// Pairs in updateMetadataPairs are supported, metadata not specified will be kept. User metadata
+// will be replaced as a whole instead of merged.
+func (s *Storage) UpdateMetadataWithContext(ctx context.Context, path string, pairs ...Pair) (err error) {
+	defer func() {
+		err = s.formatError("update_metadata", err, path)
+	}()
+
+	for _, v := range pairs {
+		if !updateMetadataPairs[v.Key] {
+			return services.PairUnsupportedError{Pair: v}
+		}
+	}
+	opt, err := s.parsePairStorageWrite(pairs)
+	if err != nil {
+		return
+	}
+	if err = checkStorageClass(opt.HasStorageClass, opt.StorageClass); err != nil {
+		return
+	}
+
+	rp := s.getAbsPath(path)
+	defer s.statCache.invalidate(rp)
+
+	// Metadata not specified should be copied from the object, since it will be replaced.
+	output, _, err := s.getObjectHeaders(ctx, rp, &service.GetObjectInput{})
+	if err != nil {
+		return
+	}
+
+	ctx, input := s.formatSelfCopyInput(ctx, rp, output)
+	if opt.HasContentType {
+		input.ContentType = service.String(opt.ContentType)
+	}
+	if opt.HasCacheControl {
+		input.CacheControl = service.String(opt.CacheControl)
+	}
+	if opt.HasContentEncoding {
+		input.ContentEncoding = service.String(opt.ContentEncoding)
+	}
+	if opt.HasStorageClass {
+		input.XQSStorageClass = service.String(opt.StorageClass)
+	}
+	if opt.HasUserMetadata {
+		metadata := *formatUserMetadata(opt.UserMetadata)
+		// Metadata used by this service internally should be kept.
+		if input.XQSMetaData != nil {
+			for k, v := range *input.XQSMetaData {
+				if isInternalMetadata(k) {
+					metadata[k] = v
+				}
+			}
+		}
+		input.XQSMetaData = &metadata
+	}
+	if opt.HasContentDisposition {
+		ctx = withRequestHeader(ctx, "Content-Disposition", opt.ContentDisposition)
+	}
+	if opt.HasExpires {
+		ctx = withRequestHeader(ctx, "Expires", opt.Expires.UTC().Format(http.TimeFormat))
+	}
+
+	_, err = s.bucket.PutObjectWithContext(ctx, rp, input)
+	return
+}
@@ -0,0 +1,72 @@
+package qingstor
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// Usage is the disk usage of objects under a path.
+type Usage struct {
+	// Bytes is the total content length of objects.
+	Bytes int64
+	// Objects is the number of objects, including directory objects.
+	Objects int64
+	// StorageClasses is the usage of every storage class, like StorageClassStandard.
+	StorageClasses map[string]StorageClassUsage
+}
+
+// StorageClassUsage is the disk usage of objects in a storage class.
+type StorageClassUsage struct {
+	Bytes   int64
+	Objects int64
+}
+
+// DiskUsage will walk all objects under path, and returns the usage of them.
+func (s *Storage) DiskUsage(path string, pairs ...Pair) (u *Usage, err error) {
+	ctx := context.Background()
+	return s.DiskUsageWithContext(ctx, path, pairs...)
+}
+
+// DiskUsageWithContext will walk all objects under path, and returns the usage of them.
+//
+// Objects are listed by ListConcurrent, so pairs for it like list_concurrency, filters by
+// mtime and size are supported.
+func (s *Storage) DiskUsageWithContext(ctx context.Context, path string, pairs ...Pair) (u *Usage, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	// Stop listing in background while walking failed.
+	defer cancel()
+
+	it, err := s.listConcurrentWithContext(ctx, path, false, pairs...)
+	if err != nil {
+		return
+	}
+
+	u = &Usage{StorageClasses: make(map[string]StorageClassUsage)}
+	for {
+		o, err := it.Next()
+		if err != nil {
+			if errors.Is(err, IterateDone) {
+				break
+			}
+			return nil, s.formatError("disk_usage", err, path)
+		}
+
+		size, _ := o.GetContentLength()
+		class := GetObjectSystemMetadata(o).StorageClass
+		if class == "" {
+			// Objects are stored in STANDARD if storage class is not specified.
+			class = StorageClassStandard
+		}
+
+		u.Bytes += size
+		u.Objects++
+
+		cu := u.StorageClasses[class]
+		cu.Bytes += size
+		cu.Objects++
+		u.StorageClasses[class] = cu
+	}
+	return u, nil
+}
@@ -1,12 +1,18 @@
 package qingstor
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"net/http"
 	"net/url"
+	"reflect"
 	"regexp"
 	"strings"
 	"time"
@@ -36,6 +42,9 @@ type Service struct {
 	defaultPairs DefaultServicePairs
 	features     ServiceFeatures
 
+	// defaultStorageClass will be inherited by all storagers created by this service.
+	defaultStorageClass string
+
 	typ.UnimplementedServicer
 }
 
@@ -56,6 +65,23 @@ type Storage struct {
 	defaultPairs DefaultStoragePairs
 	features     StorageFeatures
 
+	// systemMetadata carries bucket attributes which are returned while listing buckets.
+	systemMetadata StorageSystemMetadata
+
+	// keyProvider enables client-side encryption while not nil.
+	keyProvider KeyProvider
+
+	// copyBufferSize is the copy_buffer_size of storage, 0 means not set.
+	copyBufferSize int
+
+	// statCache caches results of Stat while stat_cache_ttl or stat_cache_negative_ttl is set,
+	// nil means disabled.
+	statCache *statCache
+
+	// uploadConcurrency caps the number of parts uploaded at the same time by every multipart
+	// upload, 0 means unlimited.
+	uploadConcurrency int
+
 	// options for this storager.
 	workDir string // workDir dir for all operation.
 
@@ -111,6 +137,7 @@ func newServicer(pairs ...typ.Pair) (srv *Service, err error) {
 	srv = &Service{
 		client: httpclient.New(opt.HTTPClientOptions),
 	}
+	srv.client.Transport = &headerTransport{base: srv.client.Transport}
 
 	var cfg *qsconfig.Config
 
@@ -159,6 +186,12 @@ func newServicer(pairs ...typ.Pair) (srv *Service, err error) {
 	if opt.HasServiceFeatures {
 		srv.features = opt.ServiceFeatures
 	}
+	if opt.HasDefaultStorageClass {
+		if !isStorageClassValid(opt.DefaultStorageClass) {
+			return nil, services.PairUnsupportedError{Pair: WithDefaultStorageClass(opt.DefaultStorageClass)}
+		}
+		srv.defaultStorageClass = opt.DefaultStorageClass
+	}
 	return
 }
 
@@ -188,13 +221,36 @@ const (
 	multipartSizeMaximum = 5 * 1024 * 1024 * 1024
 	// multipartSizeMinimum is the minimum size for each part, except the last part, 4MB
 	multipartSizeMinimum = 4 * 1024 * 1024
+	// multipartSizeAlignment is the unit which calculated part size is rounded up to, 1MB
+	multipartSizeAlignment = 1024 * 1024
 )
 
+// CalculatePartSize will calculate the minimum part size for uploading content of size via
+// multipart, so that the part count and part size limits of QingStor are both satisfied.
+//
+// Part size is rounded up to 1MB and no less than 4MB, ErrRestrictionDissatisfied will be
+// returned if size could not be uploaded in 10000 parts of 5GB.
+func CalculatePartSize(size int64) (int64, error) {
+	if size < 0 {
+		return 0, fmt.Errorf("size must not be negative: %w", services.ErrRestrictionDissatisfied)
+	}
+
+	partSize := (size + multipartNumberMaximum - 1) / multipartNumberMaximum
+	partSize = (partSize + multipartSizeAlignment - 1) / multipartSizeAlignment * multipartSizeAlignment
+	if partSize < multipartSizeMinimum {
+		partSize = multipartSizeMinimum
+	}
+	if partSize > multipartSizeMaximum {
+		return 0, fmt.Errorf("size limit exceeded: %w", services.ErrRestrictionDissatisfied)
+	}
+	return partSize, nil
+}
+
 const (
 	// writeSizeMaximum is the maximum size for write operation, 5GB.
 	// ref: https://docs.qingcloud.com/qingstor/#object
 	writeSizeMaximum = 5 * 1024 * 1024 * 1024
-	// copySizeMaximum is the maximum size for copy operation, 5GB.
+	// copySizeMaximum is the maximum size for a single copy request, 5GB, larger objects will be copied part by part.
 	// ref: https://docs.qingcloud.com/qingstor/api/object/copy
 	copySizeMaximum = 5 * 1024 * 1024 * 1024
 	// appendSizeMaximum is the maximum append size for per append operation, 5GB.
@@ -217,7 +273,9 @@ func IsBucketNameValid(s string) bool {
 }
 
 func formatError(err error) error {
-	if _, ok := err.(services.InternalError); ok {
+	// Errors could be wrapped by iterators, like "iterator next failed: %w".
+	var ie services.InternalError
+	if errors.As(err, &ie) {
 		return err
 	}
 
@@ -240,6 +298,8 @@ func formatError(err error) error {
 		return fmt.Errorf("%w: %v", services.ErrPermissionDenied, e)
 	case "object_not_exists":
 		return fmt.Errorf("%w: %v", services.ErrObjectNotExist, e)
+	case "bucket_not_exists":
+		return fmt.Errorf("%w: %v", ErrBucketNotExist, e)
 	default:
 		return fmt.Errorf("%w: %v", services.ErrUnexpected, err)
 	}
@@ -258,12 +318,104 @@ const (
 	StorageClassStandardIA = "STANDARD_IA"
 )
 
+// isStorageClassValid checks whether the storage class is supported by qingstor.
+func isStorageClassValid(class string) bool {
+	return class == StorageClassStandard || class == StorageClassStandardIA
+}
+
+// checkStorageClass will reject storage class not supported by qingstor.
+//
+// Values of pairs are not validated by the generated parsers, so every operation accepting
+// storage_class calls it right after parsing, before any request is sent.
+func checkStorageClass(has bool, class string) error {
+	if has && !isStorageClassValid(class) {
+		return services.PairUnsupportedError{Pair: WithStorageClass(class)}
+	}
+	return nil
+}
+
+// ParseStorageClass will convert storage class used by other services into qingstor
+// storage class, like "hot", "warm" or "STANDARD_IA".
+//
+// ErrStorageClassInvalid will be returned if there is no matched storage class.
+func ParseStorageClass(class string) (string, error) {
+	switch strings.ToUpper(strings.ReplaceAll(class, "-", "_")) {
+	case StorageClassStandard, "HOT":
+		return StorageClassStandard, nil
+	case StorageClassStandardIA, "IA", "INFREQUENT_ACCESS", "WARM", "COOL":
+		return StorageClassStandardIA, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrStorageClassInvalid, class)
+	}
+}
+
+// MergeDefaultStoragePairs will merge default pairs of every operation, pairs in the former
+// take precedence over the latter ones, since the first pair wins while parsing.
+func MergeDefaultStoragePairs(ps ...DefaultStoragePairs) DefaultStoragePairs {
+	var result DefaultStoragePairs
+
+	rv := reflect.ValueOf(&result).Elem()
+	for _, p := range ps {
+		pv := reflect.ValueOf(p)
+		// Every field is the []Pair of an operation, so new operations are merged as well.
+		for i := 0; i < pv.NumField(); i++ {
+			if pv.Field(i).Len() == 0 {
+				continue
+			}
+			rv.Field(i).Set(reflect.AppendSlice(rv.Field(i), pv.Field(i)))
+		}
+	}
+	return result
+}
+
+// mergeDefaultStoragePairs will merge all default_storage_pairs into the first one, so that
+// wrappers could add defaults without overwriting the ones set by user.
+func mergeDefaultStoragePairs(pairs []typ.Pair) []typ.Pair {
+	var (
+		idx      = -1
+		defaults []DefaultStoragePairs
+		result   = make([]typ.Pair, 0, len(pairs))
+	)
+	for _, v := range pairs {
+		if v.Key != "default_storage_pairs" {
+			result = append(result, v)
+			continue
+		}
+		dp, ok := v.Value.(DefaultStoragePairs)
+		if !ok {
+			// Leave the invalid value to be reported while parsing.
+			result = append(result, v)
+			continue
+		}
+		if idx < 0 {
+			idx = len(result)
+			result = append(result, v)
+		}
+		defaults = append(defaults, dp)
+	}
+	if len(defaults) > 1 {
+		result[idx] = WithDefaultStoragePairs(MergeDefaultStoragePairs(defaults...))
+	}
+	return result
+}
+
 func (s *Service) newStorage(pairs ...typ.Pair) (store *Storage, err error) {
+	// Service's default storage class should be overwritten by the storage's own.
+	if s.defaultStorageClass != "" {
+		pairs = append(pairs, WithDefaultStorageClass(s.defaultStorageClass))
+	}
+	pairs = mergeDefaultStoragePairs(pairs)
+
 	opt, err := parsePairStorageNew(pairs)
 	if err != nil {
 		return
 	}
 
+	if opt.HasDefaultStorageClass && !isStorageClassValid(opt.DefaultStorageClass) {
+		err = services.PairUnsupportedError{Pair: WithDefaultStorageClass(opt.DefaultStorageClass)}
+		return
+	}
+
 	// WorkDir should be an abs path, start and ends with "/"
 	if opt.HasWorkDir && !isWorkDirValid(opt.WorkDir) {
 		err = ErrWorkDirInvalid
@@ -312,14 +464,91 @@ func (s *Service) newStorage(pairs ...typ.Pair) (store *Storage, err error) {
 	if opt.HasWorkDir {
 		st.workDir = opt.WorkDir
 	}
+	if opt.HasKeyProvider {
+		st.keyProvider = opt.KeyProvider
+	}
+	if opt.HasCopyBufferSize {
+		if opt.CopyBufferSize <= 0 {
+			return nil, services.PairUnsupportedError{Pair: WithCopyBufferSize(opt.CopyBufferSize)}
+		}
+		st.copyBufferSize = opt.CopyBufferSize
+		if st.copyBufferSize > copyBufferSizeMaximum {
+			st.copyBufferSize = copyBufferSizeMaximum
+		}
+	}
+	if opt.HasUploadConcurrency {
+		if opt.UploadConcurrency <= 0 {
+			return nil, services.PairUnsupportedError{Pair: WithUploadConcurrency(opt.UploadConcurrency)}
+		}
+		st.uploadConcurrency = opt.UploadConcurrency
+	}
+	if opt.HasStatCacheTTL || opt.HasStatCacheNegativeTTL {
+		if opt.HasStatCacheTTL && opt.StatCacheTTL <= 0 {
+			return nil, services.PairUnsupportedError{Pair: WithStatCacheTTL(opt.StatCacheTTL)}
+		}
+		if opt.HasStatCacheNegativeTTL && opt.StatCacheNegativeTTL <= 0 {
+			return nil, services.PairUnsupportedError{Pair: WithStatCacheNegativeTTL(opt.StatCacheNegativeTTL)}
+		}
+		size := statCacheSizeDefault
+		if opt.HasStatCacheSize {
+			if opt.StatCacheSize <= 0 {
+				return nil, services.PairUnsupportedError{Pair: WithStatCacheSize(opt.StatCacheSize)}
+			}
+			size = opt.StatCacheSize
+		}
+		st.statCache = newStatCache(opt.StatCacheTTL, opt.StatCacheNegativeTTL, size)
+	}
 	return st, nil
 }
 
-func (s *Service) detectLocation(name string) (location string, err error) {
-	defer func() {
-		err = s.formatError("detect_location", err, "")
-	}()
+type requestHeadersKey struct{}
+
+// withRequestHeader will return a context which carries an extra header for requests
+// sent with it. It's used for headers not supported by qingstor sdk.
+//
+// Headers start with x-qs- should not be set here, because they are signed by sdk.
+func withRequestHeader(ctx context.Context, key, value string) context.Context {
+	h := requestHeadersFromContext(ctx).Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Set(key, value)
+	return context.WithValue(ctx, requestHeadersKey{}, h)
+}
+
+func requestHeadersFromContext(ctx context.Context) http.Header {
+	h, _ := ctx.Value(requestHeadersKey{}).(http.Header)
+	return h
+}
+
+// headerTransport will set extra headers carried by request's context.
+type headerTransport struct {
+	base http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	h := requestHeadersFromContext(r.Context())
+	if len(h) == 0 {
+		return base.RoundTrip(r)
+	}
 
+	// RoundTrip should not modify the request, so we need to clone it.
+	r = r.Clone(r.Context())
+	for k, v := range h {
+		r.Header[k] = v
+	}
+	return base.RoundTrip(r)
+}
+
+// detectLocation will detect bucket's location via an unauthenticated HEAD request.
+//
+// Errors returned here will be formatted by the caller's operation.
+func (s *Service) detectLocation(name string) (location string, err error) {
 	var (
 		u        *url.URL
 		hostPart = s.config.Host
@@ -339,7 +568,15 @@ func (s *Service) detectLocation(name string) (location string, err error) {
 	if err != nil {
 		return
 	}
-	// we don't care about status-code anymore, just check headers.
+	if r.Body != nil {
+		defer r.Body.Close()
+	}
+	// Bucket not exist means there is no location could be detected.
+	if r.StatusCode == http.StatusNotFound {
+		err = ErrBucketNotExist
+		return
+	}
+	// we don't care about other status-code, just check headers.
 	location = r.Header.Get("x-qs-bucket-region")
 	if location != "" {
 		return
@@ -410,13 +647,25 @@ func (s *Storage) newObject(done bool) *typ.Object {
 	return typ.NewObject(s, done)
 }
 
-func (s *Storage) formatFileObject(v *service.KeyType) (o *typ.Object, err error) {
-	o = s.newObject(false)
+// formatFileObject will build object from the key returned by List, owner is the bucket owner
+// returned along with keys.
+//
+// All metadata returned by List will be set and the object is marked as done, so that getters
+// will not send HEAD for every object. User metadata and link target are not returned by List,
+// please use `stat` or include_metadata for them.
+func (s *Storage) formatFileObject(v *service.KeyType, owner *service.OwnerType) (o *typ.Object, err error) {
+	o = s.newObject(true)
 	o.ID = *v.Key
 	o.Path = s.getRelPath(*v.Key)
 	// If you have enabled virtual link, you will not get the accurate object type.
 	// If you want to get the exact object mode, please use `stat`
-	o.Mode |= typ.ModeRead
+	if strings.HasSuffix(*v.Key, "/") || isObjectDirectory(v) {
+		// Directory objects created by CreateDir or in console are not folded into common
+		// prefixes while listing by prefix.
+		o.Mode |= typ.ModeDir
+	} else {
+		o.Mode |= typ.ModeRead
+	}
 
 	o.SetContentLength(service.Int64Value(v.Size))
 	o.SetLastModified(convertUnixTimestampToTime(service.IntValue(v.Modified)))
@@ -432,13 +681,21 @@ func (s *Storage) formatFileObject(v *service.KeyType) (o *typ.Object, err error
 	if value := service.StringValue(v.StorageClass); value != "" {
 		sm.StorageClass = value
 	}
+	sm.Encrypted = service.BoolValue(v.Encrypted)
+	if owner != nil {
+		sm.OwnerID = service.StringValue(owner.ID)
+		sm.OwnerName = service.StringValue(owner.Name)
+	}
 	o.SetSystemMetadata(sm)
 
 	return o, nil
 }
 
+// mimeTypeDirectory is the mime type of directory objects created in console.
+const mimeTypeDirectory = "application/x-directory"
+
 func isObjectDirectory(o *service.KeyType) bool {
-	return convert.StringValue(o.MimeType) == "application/x-directory"
+	return convert.StringValue(o.MimeType) == mimeTypeDirectory
 }
 
 // All available SSE customer algorithms are listed here.
@@ -446,6 +703,42 @@ const (
 	SseCustomerAlgorithmAes256 = "AES256"
 )
 
+// calculateMD5 will calculate md5 of the first size bytes in r, and returns a reader
+// which could read these bytes again.
+//
+// r will be rewound if it's an io.ReadSeeker, otherwise the content will be buffered in memory.
+func calculateMD5(r io.Reader, size int64) (sum []byte, nr io.Reader, err error) {
+	return calculateHash(md5.New(), r, size)
+}
+
+// calculateHash will calculate checksum of the first size bytes in r via h, and returns
+// a reader which could read these bytes again.
+func calculateHash(h hash.Hash, r io.Reader, size int64) (sum []byte, nr io.Reader, err error) {
+	if r == nil {
+		return h.Sum(nil), nil, nil
+	}
+
+	if rs, ok := r.(io.ReadSeeker); ok {
+		offset, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err = io.CopyN(h, rs, size); err != nil {
+			return nil, nil, err
+		}
+		if _, err = rs.Seek(offset, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		return h.Sum(nil), rs, nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	if _, err = io.CopyN(io.MultiWriter(h, buf), r, size); err != nil {
+		return nil, nil, err
+	}
+	return h.Sum(nil), buf, nil
+}
+
 func calculateEncryptionHeaders(algo string, key []byte) (algorithm, keyBase64, keyMD5Base64 *string, err error) {
 	if len(key) != 32 {
 		err = ErrEncryptionCustomerKeyInvalid
@@ -466,6 +759,28 @@ func (s *Storage) formatGetObjectInput(opt pairStorageRead) (input *service.GetO
 		}
 	}
 
+	if opt.HasIfMatch {
+		input.IfMatch = service.String(opt.IfMatch)
+	}
+	if opt.HasIfNoneMatch {
+		input.IfNoneMatch = service.String(opt.IfNoneMatch)
+	}
+	if opt.HasIfModifiedSince {
+		input.IfModifiedSince = service.Time(opt.IfModifiedSince)
+	}
+	if opt.HasSuffixSize {
+		if opt.HasOffset {
+			return nil, services.PairUnsupportedError{Pair: ps.WithOffset(opt.Offset)}
+		}
+		if opt.HasSize {
+			return nil, services.PairUnsupportedError{Pair: ps.WithSize(opt.Size)}
+		}
+		if opt.SuffixSize <= 0 {
+			return nil, services.PairUnsupportedError{Pair: WithSuffixSize(opt.SuffixSize)}
+		}
+		rs := fmt.Sprintf("bytes=-%d", opt.SuffixSize)
+		input.Range = &rs
+	}
 	if opt.HasOffset || opt.HasSize {
 		rs := headers.FormatRange(opt.Offset, opt.Size)
 		input.Range = &rs
@@ -481,6 +796,9 @@ func (s *Storage) formatPutObjectInput(size int64, opt pairStorageWrite) (input
 	if opt.HasContentMd5 {
 		input.ContentMD5 = service.String(opt.ContentMd5)
 	}
+	if opt.HasContentType {
+		input.ContentType = service.String(opt.ContentType)
+	}
 	if opt.HasStorageClass {
 		input.XQSStorageClass = service.String(opt.StorageClass)
 	}
@@ -490,6 +808,102 @@ func (s *Storage) formatPutObjectInput(size int64, opt pairStorageWrite) (input
 			return
 		}
 	}
+	if opt.HasCacheControl {
+		input.CacheControl = service.String(opt.CacheControl)
+	}
+	if opt.HasContentEncoding {
+		input.ContentEncoding = service.String(opt.ContentEncoding)
+	}
+	if opt.HasUserMetadata {
+		input.XQSMetaData = formatUserMetadata(opt.UserMetadata)
+	}
+	if opt.HasContentSha256 {
+		if input.XQSMetaData == nil {
+			input.XQSMetaData = &map[string]string{}
+		}
+		(*input.XQSMetaData)[metadataContentSha256] = strings.ToLower(opt.ContentSha256)
+	}
+
+	return
+}
+
+// metadataContentSha256 is the metadata name used to store the SHA-256 checksum of content.
+const metadataContentSha256 = "x-qs-meta-bs-sha256"
 
+// sha256Regexp is the hex encoded SHA-256 checksum regexp.
+var sha256Regexp = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+func isSha256Valid(s string) bool {
+	return sha256Regexp.MatchString(s)
+}
+
+// metadataUserPrefix is the prefix of user-defined metadata headers.
+const metadataUserPrefix = "x-qs-meta-"
+
+// formatUserMetadata will convert user metadata into qingstor metadata headers.
+func formatUserMetadata(m map[string]string) *map[string]string {
+	metadata := make(map[string]string, len(m))
+	for k, v := range m {
+		metadata[metadataUserPrefix+strings.ToLower(k)] = v
+	}
+	return &metadata
+}
+
+// formatSystemMetadata will build object system metadata from response headers.
+//
+// Client encryption key will only be set while client-side encryption is enabled.
+func (s *Storage) formatSystemMetadata(storageClass, encryptionAlgorithm *string, metadata *map[string]string) (sm ObjectSystemMetadata) {
+	sm.StorageClass = service.StringValue(storageClass)
+	sm.EncryptionCustomerAlgorithm = service.StringValue(encryptionAlgorithm)
+	if metadata == nil {
+		return
+	}
+	for k, v := range *metadata {
+		if strings.ToLower(k) == metadataContentSha256 {
+			sm.ContentSha256 = v
+		}
+	}
+	if s.keyProvider != nil {
+		sm.ClientEncryptionKey, _ = getClientEncryptionKey(*metadata)
+	}
 	return
 }
+
+// isInternalMetadata will check whether the metadata is used by this service internally.
+func isInternalMetadata(k string) bool {
+	switch k {
+	case metadataLinkTargetHeader, metadataClientEncryptionKey, metadataClientEncryptionAlgorithm, metadataContentSha256:
+		return true
+	}
+	return false
+}
+
+// isMd5Etag will check whether etag is the md5 of content, etag of objects uploaded by multipart
+// is like "<md5>-<parts>" instead.
+func isMd5Etag(etag string) bool {
+	b, err := hex.DecodeString(etag)
+	return err == nil && len(b) == md5.Size
+}
+
+// parseContentRange will parse the Content-Range header like "bytes 0-99/1000", end is inclusive.
+func parseContentRange(v string) (start, end, total int64, ok bool) {
+	if _, err := fmt.Sscanf(v, "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		return 0, 0, 0, false
+	}
+	return start, end, total, true
+}
+
+// parseUserMetadata will convert qingstor metadata headers into user metadata.
+//
+// Metadata used by this service internally like link target will be ignored.
+func parseUserMetadata(m map[string]string) map[string]string {
+	metadata := make(map[string]string, len(m))
+	for k, v := range m {
+		k = strings.ToLower(k)
+		if isInternalMetadata(k) || !strings.HasPrefix(k, metadataUserPrefix) {
+			continue
+		}
+		metadata[strings.TrimPrefix(k, metadataUserPrefix)] = v
+	}
+	return metadata
+}
@@ -1,8 +1,15 @@
 package qingstor
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
@@ -14,7 +21,9 @@ import (
 	"github.com/beyondstorage/go-endpoint"
 	"github.com/beyondstorage/go-storage/v4/pairs"
 	"github.com/beyondstorage/go-storage/v4/pkg/credential"
+	"github.com/beyondstorage/go-storage/v4/pkg/randbytes"
 	"github.com/beyondstorage/go-storage/v4/services"
+	typ "github.com/beyondstorage/go-storage/v4/types"
 )
 
 func Test_New(t *testing.T) {
@@ -205,3 +214,323 @@ func Test_isObjectDirectory(t *testing.T) {
 		})
 	}
 }
+
+func Test_headerTransport(t *testing.T) {
+	disposition := "attachment; filename=" + uuid.New().String()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, disposition, r.Header.Get("Content-Disposition"))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &headerTransport{}}
+
+	ctx := withRequestHeader(context.Background(), "Content-Disposition", disposition)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	// Original request should not be modified.
+	assert.Empty(t, req.Header.Get("Content-Disposition"))
+}
+
+func TestParseStorageClass(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{StorageClassStandard, StorageClassStandard, false},
+		{"hot", StorageClassStandard, false},
+		{StorageClassStandardIA, StorageClassStandardIA, false},
+		{"standard-ia", StorageClassStandardIA, false},
+		{"warm", StorageClassStandardIA, false},
+		{"GLACIER", "", true},
+	}
+
+	for _, tt := range cases {
+		got, err := ParseStorageClass(tt.input)
+		if tt.wantErr {
+			assert.True(t, errors.Is(err, ErrStorageClassInvalid), tt.input)
+			continue
+		}
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+	}
+}
+
+func TestCalculatePartSize(t *testing.T) {
+	const mb = 1024 * 1024
+	cases := []struct {
+		size    int64
+		want    int64
+		wantErr bool
+	}{
+		{0, multipartSizeMinimum, false},
+		{100 * mb, multipartSizeMinimum, false},
+		{40000 * mb, multipartSizeMinimum, false},
+		{40000*mb + 1, 5 * mb, false},
+		{1024 * 1024 * mb, 105 * mb, false},
+		{multipartNumberMaximum * multipartSizeMaximum, multipartSizeMaximum, false},
+		{multipartNumberMaximum*multipartSizeMaximum + 1, 0, true},
+		{-1, 0, true},
+	}
+
+	for _, tt := range cases {
+		got, err := CalculatePartSize(tt.size)
+		if tt.wantErr {
+			assert.True(t, errors.Is(err, services.ErrRestrictionDissatisfied), tt.size)
+			continue
+		}
+		assert.NoError(t, err, tt.size)
+		assert.Equal(t, tt.want, got, tt.size)
+		assert.True(t, (tt.size+got-1)/got <= multipartNumberMaximum, tt.size)
+	}
+}
+
+func Test_decryptedSize(t *testing.T) {
+	for _, size := range []int64{0, 1, clientEncryptionChunkSize - 1, clientEncryptionChunkSize, clientEncryptionChunkSize + 1, 3*clientEncryptionChunkSize + 17} {
+		assert.Equal(t, size, decryptedSize(encryptedSize(size)))
+	}
+}
+
+func Test_decryptReader(t *testing.T) {
+	aead, err := newGCM(bytes.Repeat([]byte{'k'}, 32))
+	assert.NoError(t, err)
+
+	encrypt := func(content []byte, part int, last bool) []byte {
+		out, err := ioutil.ReadAll(newEncryptReader(bytes.NewReader(content), aead, part, last))
+		assert.NoError(t, err)
+		assert.Equal(t, encryptedSize(int64(len(content))), int64(len(out)))
+		return out
+	}
+	decrypt := func(stored ...[]byte) ([]byte, error) {
+		return ioutil.ReadAll(newDecryptReader(bytes.NewReader(bytes.Join(stored, nil)), aead))
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(randbytes.NewRand(), 3*clientEncryptionChunkSize+17))
+	assert.NoError(t, err)
+	first, second := content[:2*clientEncryptionChunkSize], content[2*clientEncryptionChunkSize:]
+
+	object := encrypt(content, 0, true)
+	plain, err := decrypt(object)
+	assert.NoError(t, err)
+	assert.Equal(t, content, plain)
+
+	plain, err = decrypt(encrypt(nil, 0, true))
+	assert.NoError(t, err)
+	assert.Empty(t, plain)
+
+	// Parts are encrypted separately and decrypted as a whole.
+	part0, part1 := encrypt(first, 0, false), encrypt(second, 1, false)
+	plain, err = decrypt(part0, part1)
+	assert.NoError(t, err)
+	assert.Equal(t, content, plain)
+	assert.Equal(t, int64(len(content)), decryptedSize(int64(len(part0)+len(part1))))
+
+	frame := clientEncryptionChunkSize + clientEncryptionOverhead
+	for name, stored := range map[string][][]byte{
+		"empty":              nil,
+		"truncated chunk":    {object[:len(object)-1]},
+		"dropped last chunk": {object[:frame]},
+		"reordered chunks":   {object[frame : 2*frame], object[:frame], object[2*frame:]},
+		"appended object":    {object, object},
+		"reordered parts":    {part1, part0},
+		"missing first part": {part1},
+		"truncated part":     {part0[:frame], part1},
+		"duplicated part":    {part0, part0, part1},
+	} {
+		_, err = decrypt(stored...)
+		assert.True(t, errors.Is(err, ErrContentCorrupted), name)
+	}
+}
+
+func Test_rateLimitReader(t *testing.T) {
+	limit := int64(100 * 1024)
+	r := newRateLimitReader(context.Background(), io.LimitReader(randbytes.NewRand(), limit*3/2), limit)
+
+	start := time.Now()
+	n, err := io.Copy(ioutil.Discard, r)
+	assert.NoError(t, err)
+	assert.Equal(t, limit*3/2, n)
+	// The first second of limit could be read in burst.
+	assert.True(t, time.Since(start) >= 400*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r = newRateLimitReader(ctx, io.LimitReader(randbytes.NewRand(), limit*2), limit)
+	_, err = io.Copy(ioutil.Discard, r)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func Test_rateLimitWriter(t *testing.T) {
+	limit := int64(100 * 1024)
+	w := &rateLimitWriter{ctx: context.Background(), w: ioutil.Discard, l: newRateLimiter(limit)}
+
+	start := time.Now()
+	n, err := w.Write(make([]byte, limit*3/2))
+	assert.NoError(t, err)
+	assert.Equal(t, int(limit*3/2), n)
+	assert.True(t, time.Since(start) >= 400*time.Millisecond)
+}
+
+func Test_parseContentRange(t *testing.T) {
+	start, end, total, ok := parseContentRange("bytes 0-99/1000")
+	assert.True(t, ok)
+	assert.Equal(t, []int64{0, 99, 1000}, []int64{start, end, total})
+
+	_, _, _, ok = parseContentRange("bytes */1000")
+	assert.False(t, ok)
+}
+
+func Test_formatImageAction(t *testing.T) {
+	cases := []struct {
+		actions []ImageAction
+		want    string
+	}{
+		{[]ImageAction{ImageInfo()}, "info"},
+		{[]ImageAction{ImageCrop(100, 50, 0), ImageRotate(90)}, "crop:w_100,h_50,g_0|rotate:a_90"},
+		{[]ImageAction{ImageResize(0, 200, 0)}, "resize:h_200,m_0"},
+		{[]ImageAction{ImageWatermark("qs", 150, 0.5, "#fff")}, "watermark:d_150,p_0.50,t_cXM=,c_I2ZmZg=="},
+	}
+
+	for _, tt := range cases {
+		assert.Equal(t, tt.want, formatImageAction(tt.actions))
+	}
+}
+
+// writerToRecorder records whether WriteTo has been called.
+type writerToRecorder struct {
+	r      *bytes.Reader
+	called bool
+}
+
+func (w *writerToRecorder) Read(p []byte) (int, error) {
+	return w.r.Read(p)
+}
+
+func (w *writerToRecorder) WriteTo(dst io.Writer) (int64, error) {
+	w.called = true
+	return w.r.WriteTo(dst)
+}
+
+func TestStorage_copyBuffer(t *testing.T) {
+	content, err := ioutil.ReadAll(io.LimitReader(randbytes.NewRand(), 100*1024))
+	assert.NoError(t, err)
+
+	for _, s := range []*Storage{{}, {copyBufferSize: 1024}} {
+		for _, size := range []int{0, 512, copyBufferSizeDefault} {
+			var buf bytes.Buffer
+			// Hide io.WriterTo of bytes.Reader so that the pooled buffer is used.
+			n, err := s.copyBuffer(&buf, io.LimitReader(bytes.NewReader(content), int64(len(content))), size)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(content)), n)
+			assert.Equal(t, content, buf.Bytes())
+		}
+	}
+
+	// Content is written by io.WriterTo of source while copy_buffer_size is not set.
+	var buf bytes.Buffer
+	wt := &writerToRecorder{r: bytes.NewReader(content)}
+	n, err := (&Storage{}).copyBuffer(struct{ io.Writer }{&buf}, wt, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.True(t, wt.called)
+
+	// Pooled buffer is used while copy_buffer_size is set, even if w implements io.ReaderFrom.
+	buf.Reset()
+	wt = &writerToRecorder{r: bytes.NewReader(content)}
+	n, err = (&Storage{}).copyBuffer(&buf, wt, 512)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.Bytes())
+	assert.False(t, wt.called)
+
+	assert.Equal(t, defaultCopyBufferPool, getCopyBufferPool(copyBufferSizeDefault))
+	assert.Equal(t, getCopyBufferPool(512), getCopyBufferPool(512))
+	// Sizes are rounded up to fixed classes, so that pools are bounded.
+	assert.Len(t, *getCopyBufferPool(512).Get().(*[]byte), copyBufferSizeMinimum)
+	assert.Len(t, *getCopyBufferPool(copyBufferSizeDefault+1).Get().(*[]byte), 2*copyBufferSizeDefault)
+	assert.Len(t, *getCopyBufferPool(copyBufferSizeMaximum*2).Get().(*[]byte), copyBufferSizeMaximum)
+}
+
+func Test_statCache(t *testing.T) {
+	c := newStatCache(time.Minute, 0, 2)
+	for _, key := range []string{"a", "b", "c"} {
+		c.add(key, c.begin(key), &service.HeadObjectOutput{})
+	}
+
+	// The least recently used one is evicted.
+	_, ok := c.get("a")
+	assert.False(t, ok)
+	_, ok = c.get("c")
+	assert.True(t, ok)
+
+	c.invalidate("c")
+	_, ok = c.get("c")
+	assert.False(t, ok)
+
+	// Result fetched before invalidated should not be cached.
+	generation := c.begin("d")
+	c.invalidate("d")
+	c.add("d", generation, &service.HeadObjectOutput{})
+	_, ok = c.get("d")
+	assert.False(t, ok)
+	assert.Empty(t, c.fetches)
+
+	nc := newStatCache(0, time.Minute, 2)
+	generation = nc.begin("e")
+	nc.invalidate("e")
+	nc.addNotExist("e", generation, services.ErrObjectNotExist)
+	_, ok = nc.get("e")
+	assert.False(t, ok)
+
+	// Nil cache caches nothing.
+	nc = nil
+	nc.add("a", nc.begin("a"), &service.HeadObjectOutput{})
+	_, ok = nc.get("a")
+	assert.False(t, ok)
+}
+
+func Test_isMd5Etag(t *testing.T) {
+	assert.True(t, isMd5Etag("d41d8cd98f00b204e9800998ecf8427e"))
+	assert.False(t, isMd5Etag("d41d8cd98f00b204e9800998ecf8427e-2"))
+	assert.False(t, isMd5Etag(""))
+}
+
+func TestMergeDefaultStoragePairs(t *testing.T) {
+	a := DefaultStoragePairs{
+		Write:        []typ.Pair{WithStorageClass(StorageClassStandardIA)},
+		CreateAppend: []typ.Pair{pairs.WithContentType("text/plain")},
+	}
+	b := DefaultStoragePairs{
+		Write:              []typ.Pair{WithStorageClass(StorageClassStandard), WithCacheControl("no-cache")},
+		QuerySignHTTPWrite: []typ.Pair{WithStorageClass(StorageClassStandard)},
+	}
+
+	m := MergeDefaultStoragePairs(a, b)
+	assert.Equal(t, a.CreateAppend, m.CreateAppend)
+	assert.Equal(t, b.QuerySignHTTPWrite, m.QuerySignHTTPWrite)
+	assert.Len(t, m.Write, 3)
+
+	// The former should take precedence.
+	var s Storage
+	opt, err := s.parsePairStorageWrite(m.Write)
+	assert.NoError(t, err)
+	assert.Equal(t, StorageClassStandardIA, opt.StorageClass)
+	assert.Equal(t, "no-cache", opt.CacheControl)
+
+	ps := mergeDefaultStoragePairs([]typ.Pair{
+		WithDefaultStoragePairs(a),
+		pairs.WithWorkDir("/test/"),
+		WithDefaultStoragePairs(b),
+	})
+	assert.Len(t, ps, 2)
+	sopt, err := parsePairStorageNew(append(ps, pairs.WithName("test")))
+	assert.NoError(t, err)
+	assert.Equal(t, m, sopt.DefaultStoragePairs)
+}
@@ -0,0 +1,454 @@
+package qingstor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	ps "github.com/beyondstorage/go-storage/v4/pairs"
+	"github.com/beyondstorage/go-storage/v4/pkg/iowrap"
+	"github.com/beyondstorage/go-storage/v4/services"
+	. "github.com/beyondstorage/go-storage/v4/types"
+)
+
+// streamPartSize is the part size used while writing content with unknown size.
+//
+// Content could be streamed is limited to about streamPartSize * multipartNumberMaximum (640GB).
+const streamPartSize = 64 * 1024 * 1024
+
+// Writer will return an io.WriteCloser which streams content into path.
+func (s *Storage) Writer(path string, pairs ...Pair) (w io.WriteCloser, err error) {
+	ctx := context.Background()
+	return s.WriterWithContext(ctx, path, pairs...)
+}
+
+// WriterWithContext will return an io.WriteCloser which streams content into path.
+//
+// Content smaller than 64MB will be uploaded via a single PUT, otherwise multipart upload
+// will be used. Pairs for Write are supported except object_mode, but verify_etag, content_md5,
+// if_none_match and auto_content_sha256 could not be honored by multipart upload, so Close will
+// fail without uploading if content turns out to be larger. The object is available only after
+// Close returns without error.
+func (s *Storage) WriterWithContext(ctx context.Context, path string, pairs ...Pair) (w io.WriteCloser, err error) {
+	defer func() {
+		err = s.formatError("writer", err, path)
+	}()
+
+	pairs = append(pairs, s.defaultPairs.Write...)
+	opt, err := s.parsePairStorageWrite(pairs)
+	if err != nil {
+		return
+	}
+	// Content may be written via multipart upload, reject pairs early instead of failing at Close.
+	if err = checkStorageClass(opt.HasStorageClass, opt.StorageClass); err != nil {
+		return
+	}
+	if opt.HasUploadConcurrency && opt.UploadConcurrency <= 0 {
+		err = services.PairUnsupportedError{Pair: WithUploadConcurrency(opt.UploadConcurrency)}
+		return
+	}
+	// Directory must be empty, so it could not be written as a stream.
+	if opt.HasObjectMode {
+		err = services.PairUnsupportedError{Pair: ps.WithObjectMode(opt.ObjectMode)}
+		return
+	}
+
+	pr, pw := io.Pipe()
+	sw := &streamWriter{
+		pw:   pw,
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(sw.done)
+
+		_, err := s.writeStream(ctx, path, pr, opt)
+		// Close reader so that Write will not be blocked if upload failed.
+		_ = pr.CloseWithError(err)
+		sw.err = s.formatError("write", err, path)
+	}()
+	return sw, nil
+}
+
+type streamWriter struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	err  error
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close will finish the upload, and returns the error while uploading.
+func (w *streamWriter) Close() error {
+	_ = w.pw.Close()
+	<-w.done
+	return w.err
+}
+
+// writeStream will write content with unknown size from r until EOF.
+//
+// Content fits in one part will be written via a single PUT, otherwise pairs that could not be
+// honored by multipart upload will be rejected after the first part read.
+func (s *Storage) writeStream(ctx context.Context, path string, r io.Reader, opt pairStorageWrite) (n int64, err error) {
+	defer s.statCache.invalidate(s.getAbsPath(path))
+
+	buf := &bytes.Buffer{}
+	size, err := io.CopyN(buf, r, streamPartSize)
+	if err == io.EOF {
+		// Content has been buffered, so it could be sniffed if the extension is unknown.
+		if opt.HasDetectContentType && opt.DetectContentType && !opt.HasContentType {
+			if v := detectContentType(path, buf.Bytes()); v != "" {
+				opt.HasContentType = true
+				opt.ContentType = v
+			}
+		}
+		return s.write(ctx, path, buf, size, opt)
+	}
+	if err != nil {
+		return
+	}
+	return s.writeMultipartStream(ctx, path, io.MultiReader(buf, r), -1, streamPartSize, 1, opt)
+}
+
+// writeMultipartStream will write content from r via multipart upload until EOF.
+//
+// If size is not negative, content shorter than size will be treated as io.ErrUnexpectedEOF.
+// At most concurrency parts will be buffered in memory and uploaded at the same time.
+// Pairs of write that supported by create_multipart and write_multipart will be used, and pairs
+// that could not be honored will be rejected.
+func (s *Storage) writeMultipartStream(ctx context.Context, path string, r io.Reader, size int64, partSize int64, concurrency int, opt pairStorageWrite) (n int64, err error) {
+	if err = checkMultipartWritePairs(opt); err != nil {
+		return
+	}
+
+	cmOpt, err := s.parsePairStorageCreateMultipart(filterPairs(opt.pairs, func(pairs []Pair) error {
+		_, err := s.parsePairStorageCreateMultipart(pairs)
+		return err
+	}))
+	if err != nil {
+		return
+	}
+	// Content will be verified with the checksum stored in metadata before completed.
+	var sha256Hash hash.Hash
+	if cmOpt.HasContentSha256 {
+		sha256Hash = sha256.New()
+		r = io.TeeReader(r, sha256Hash)
+	}
+	if opt.HasDetectContentType && opt.DetectContentType && !cmOpt.HasContentType {
+		// Content type should be set while initiating, so the head of the first part is read
+		// before it to be sniffed.
+		head := make([]byte, contentTypeSniffLen)
+		var headLen int
+		headLen, err = io.ReadFull(r, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return
+		}
+		head = head[:headLen]
+		r = io.MultiReader(bytes.NewReader(head), r)
+		if v := detectContentType(path, head); v != "" {
+			cmOpt.HasContentType = true
+			cmOpt.ContentType = v
+		}
+	}
+	wmOpt, err := s.parsePairStorageWriteMultipart(filterPairs(opt.pairs, func(pairs []Pair) error {
+		_, err := s.parsePairStorageWriteMultipart(pairs)
+		return err
+	}))
+	if err != nil {
+		return
+	}
+	// Pairs that have been applied on r by write should not be applied on every part again.
+	wmOpt.HasIoCallback = opt.HasIoCallback
+	wmOpt.HasWriteRateLimit = opt.HasWriteRateLimit
+
+	// Parts uploaded at the same time are capped for hosts with limited resources, the
+	// upload_concurrency of this call overrides the one of the storage.
+	limit := s.uploadConcurrency
+	if opt.HasUploadConcurrency {
+		limit = opt.UploadConcurrency
+	}
+	if limit > 0 && concurrency > limit {
+		concurrency = limit
+	}
+	retry := 0
+	if opt.HasMultipartRetry {
+		retry = opt.MultipartRetry
+	}
+
+	o, err := s.createMultipart(ctx, path, cmOpt)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			// Abort the multipart upload so that no parts will be left.
+			_ = s.delete(ctx, path, pairStorageDelete{HasMultipartID: true, MultipartID: o.MustGetMultipartID()})
+		}
+	}()
+
+	uctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		once     sync.Once
+		firstErr error
+		parts    []*Part
+		progress = MultipartProgress{Total: size}
+		sem      = make(chan struct{}, concurrency)
+	)
+	setErr := func(e error) {
+		once.Do(func() {
+			firstErr = e
+			cancel()
+		})
+	}
+
+	for index := multipartNumberMinimum; ; index++ {
+		// Every part needs its own buffer, because they could be uploaded concurrently.
+		buf := getPartBuffer()
+		partLen, readErr := io.CopyN(buf, r, partSize)
+		if readErr != nil && readErr != io.EOF {
+			putPartBuffer(buf)
+			setErr(readErr)
+			break
+		}
+		if partLen == 0 {
+			putPartBuffer(buf)
+			break
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-uctx.Done():
+		}
+		// The upload context will be canceled before the semaphore released if any part failed.
+		if uctx.Err() != nil {
+			putPartBuffer(buf)
+			break
+		}
+
+		wg.Add(1)
+		go func(index int, buf *bytes.Buffer, partLen int64) {
+			defer func() {
+				putPartBuffer(buf)
+				<-sem
+				wg.Done()
+			}()
+
+			part, err := s.writeMultipartWithRetry(uctx, o, buf.Bytes(), index, retry, wmOpt)
+			if err != nil {
+				setErr(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			parts = append(parts, part)
+			// Progress is reported with the lock held, so that calls are serialized.
+			if opt.HasMultipartProgress {
+				progress.Bytes += partLen
+				progress.Parts++
+				opt.MultipartProgress(progress)
+			}
+		}(index, buf, partLen)
+		n += partLen
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if size >= 0 && n != size {
+		setErr(io.ErrUnexpectedEOF)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return n, firstErr
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	if sha256Hash != nil && hex.EncodeToString(sha256Hash.Sum(nil)) != strings.ToLower(cmOpt.ContentSha256) {
+		err = ErrContentCorrupted
+		return
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Index < parts[j].Index
+	})
+	err = s.completeMultipartUpload(ctx, o, parts)
+	if err != nil {
+		return
+	}
+	return n, nil
+}
+
+// writeMultipartSized will write content with known size via multipart upload.
+func (s *Storage) writeMultipartSized(ctx context.Context, path string, r io.Reader, size int64, opt pairStorageWrite) (n int64, err error) {
+	partSize := int64(streamPartSize)
+	if opt.HasMultipartPartSize {
+		partSize = opt.MultipartPartSize
+		if partSize < multipartSizeMinimum || partSize > multipartSizeMaximum {
+			err = ErrPartSizeInvalid
+			return
+		}
+		// Encrypted parts should be aligned to chunks so that they could be decrypted continuously.
+		if s.keyProvider != nil && partSize%clientEncryptionChunkSize != 0 {
+			err = ErrPartSizeInvalid
+			return
+		}
+	}
+	// Part size will be enlarged if the part count limit is exceeded, the calculated size is
+	// aligned to encryption chunks too.
+	minPartSize, err := CalculatePartSize(size)
+	if err != nil {
+		return
+	}
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+
+	concurrency := 1
+	if opt.HasMultipartConcurrency {
+		concurrency = opt.MultipartConcurrency
+		if concurrency <= 0 {
+			err = fmt.Errorf("concurrency must be positive: %w", services.ErrRestrictionDissatisfied)
+			return
+		}
+	}
+
+	return s.writeMultipartStream(ctx, path, io.LimitReader(r, size), size, partSize, concurrency, opt)
+}
+
+// compressionGzip is the only compression algorithm supported now.
+const compressionGzip = "gzip"
+
+// isGzipEncoding will check whether the content encoding of object is gzip, x-gzip is treated
+// as gzip as RFC 7230 suggested.
+func isGzipEncoding(v string) bool {
+	v = strings.TrimSpace(v)
+	return strings.EqualFold(v, compressionGzip) || strings.EqualFold(v, "x-gzip")
+}
+
+// writeCompressed will compress content from r and write it as a stream, returns the size
+// of content before compressed.
+func (s *Storage) writeCompressed(ctx context.Context, path string, r io.Reader, size int64, opt pairStorageWrite) (n int64, err error) {
+	if opt.Compression != compressionGzip {
+		err = services.PairUnsupportedError{Pair: WithCompression(opt.Compression)}
+		return
+	}
+	// Checksum given by user is calculated from content before compressed.
+	if opt.HasContentSha256 {
+		err = services.PairUnsupportedError{Pair: WithContentSha256(opt.ContentSha256)}
+		return
+	}
+	if size >= 0 && r != nil {
+		r = io.LimitReader(r, size)
+	}
+	if r == nil {
+		r = bytes.NewReader(nil)
+	}
+
+	// Progress should be reported with the size before compressed.
+	if opt.HasIoCallback {
+		r = iowrap.CallbackReader(r, opt.IoCallback)
+		opt.HasIoCallback = false
+	}
+
+	opt.HasCompression = false
+	opt.HasContentEncoding = true
+	opt.ContentEncoding = compressionGzip
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		gw := gzip.NewWriter(pw)
+		var werr error
+		n, werr = s.copyBuffer(gw, r, opt.CopyBufferSize)
+		if werr == nil && size >= 0 && n != size {
+			// Content shorter than size should not be written.
+			werr = io.ErrUnexpectedEOF
+		}
+		if werr == nil {
+			werr = gw.Close()
+		}
+		_ = pw.CloseWithError(werr)
+	}()
+
+	_, err = s.writeStream(ctx, path, pr, opt)
+	// Close reader so that compressing will not be blocked if upload failed.
+	_ = pr.CloseWithError(err)
+	<-done
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// contentTypeSniffLen is the max length of content considered by http.DetectContentType.
+const contentTypeSniffLen = 512
+
+// detectContentType will detect content type from the extension of path, and head of the
+// content will be sniffed if the extension is unknown.
+func detectContentType(path string, head []byte) string {
+	if v := mime.TypeByExtension(filepath.Ext(path)); v != "" {
+		return v
+	}
+	if len(head) == 0 {
+		return ""
+	}
+	if len(head) > contentTypeSniffLen {
+		head = head[:contentTypeSniffLen]
+	}
+	return http.DetectContentType(head)
+}
+
+// checkMultipartWritePairs will return PairUnsupportedError for pairs of write which could not
+// be honored while writing via multipart upload.
+func checkMultipartWritePairs(opt pairStorageWrite) error {
+	switch {
+	case opt.HasVerifyEtag && opt.VerifyEtag:
+		// ETag of object uploaded via multipart upload is not the MD5 of content.
+		return services.PairUnsupportedError{Pair: WithVerifyEtag()}
+	case opt.HasContentMd5:
+		// Content MD5 of the whole content doesn't match any part, use auto_content_md5 instead.
+		return services.PairUnsupportedError{Pair: ps.WithContentMd5(opt.ContentMd5)}
+	case opt.HasIfNoneMatch:
+		// Complete multipart upload doesn't support conditional request.
+		return services.PairUnsupportedError{Pair: WithIfNoneMatch(opt.IfNoneMatch)}
+	case opt.HasObjectMode:
+		// Directory must be empty, so it could not be written as a stream.
+		return services.PairUnsupportedError{Pair: ps.WithObjectMode(opt.ObjectMode)}
+	case opt.HasAutoContentSha256 && opt.AutoContentSha256 && !opt.HasContentSha256:
+		// Metadata is set while initiating, before the checksum of content is known.
+		return services.PairUnsupportedError{Pair: WithAutoContentSha256()}
+	}
+	return nil
+}
+
+// filterPairs will return pairs which could be parsed by parse.
+func filterPairs(pairs []Pair, parse func([]Pair) error) []Pair {
+	result := make([]Pair, 0, len(pairs))
+	for _, v := range pairs {
+		if parse([]Pair{v}) == nil {
+			result = append(result, v)
+		}
+	}
+	return result
+}